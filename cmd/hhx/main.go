@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hhx/internal/commands"
 	"hhx/internal/config"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 )
 
 func main() {
@@ -41,8 +45,22 @@ func main() {
 		// Continue without config and create it when needed
 	}
 
+	// Cancel the running command on Ctrl-C so it can save what it has done;
+	// a second Ctrl-C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// Execute root command
-	if err := commands.Execute(cfg); err != nil {
+	if err := commands.Execute(ctx, cfg); err != nil {
+		var exitErr *commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		_, err := fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		if err != nil {
 			fmt.Println("Error writing to stderr:", err)
@@ -50,4 +68,9 @@ func main() {
 		}
 		os.Exit(1)
 	}
+
+	// Exit as an interrupted process would, so scripts can tell
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
 }
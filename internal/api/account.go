@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
@@ -10,7 +11,7 @@ import (
 )
 
 // GetUserDetails fetches detailed information about the current user
-func (c *Client) GetUserDetails() (*models.UserDetailsWithSubscription, error) {
+func (c *Client) GetUserDetails(ctx context.Context) (*models.UserDetailsWithSubscription, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -18,15 +19,14 @@ func (c *Client) GetUserDetails() (*models.UserDetailsWithSubscription, error) {
 
 	url := fmt.Sprintf("%s/%s/account/me", c.BaseURL, API_VERSION)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -55,7 +55,7 @@ func (c *Client) GetUserDetails() (*models.UserDetailsWithSubscription, error) {
 }
 
 // UpdateAccount updates the user's account information
-func (c *Client) UpdateAccount(req *models.UserDetails) (*models.UserDetails, error) {
+func (c *Client) UpdateAccount(ctx context.Context, req *models.UserDetails) (*models.UserDetails, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -68,7 +68,7 @@ func (c *Client) UpdateAccount(req *models.UserDetails) (*models.UserDetails, er
 		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -76,8 +76,7 @@ func (c *Client) UpdateAccount(req *models.UserDetails) (*models.UserDetails, er
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := c.do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -2,18 +2,17 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
 	"io"
 	"net/http"
-	"net/http/cookiejar"
 	"strings"
-	"time"
 )
 
 // Register creates a new user account and returns authentication information
-func (c *Client) Register(email string, password string, name string, phone string) (*models.Auth, error) {
+func (c *Client) Register(ctx context.Context, email string, password string, name string, phone string) (*models.Auth, error) {
 	url := fmt.Sprintf("%s/%s/auth/signup", c.BaseURL, API_VERSION)
 
 	requestBody := map[string]string{
@@ -29,18 +28,13 @@ func (c *Client) Register(email string, password string, name string, phone stri
 		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	client, err := createHTTPClientWithCookieJar()
-	if err != nil {
-		return nil, fmt.Errorf("error creating HTTP client: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -86,7 +80,7 @@ func (c *Client) Register(email string, password string, name string, phone stri
 }
 
 // Login authenticates the user with the server
-func (c *Client) Login(email, password string) (*models.Auth, error) {
+func (c *Client) Login(ctx context.Context, email, password string) (*models.Auth, error) {
 	reqBody, err := json.Marshal(map[string]string{
 		"email":    email,
 		"password": password,
@@ -95,17 +89,13 @@ func (c *Client) Login(email, password string) (*models.Auth, error) {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s/auth/signin", c.BaseURL, API_VERSION), bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s/auth/signin", c.BaseURL, API_VERSION), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client, err := createHTTPClientWithCookieJar()
-	if err != nil {
-		return nil, err
-	}
 
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +140,7 @@ func (c *Client) Login(email, password string) (*models.Auth, error) {
 }
 
 // Logout clears the authentication token and notifies the server
-func (c *Client) Logout() error {
+func (c *Client) Logout(ctx context.Context) error {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		// Continue
@@ -159,19 +149,14 @@ func (c *Client) Logout() error {
 
 	// Only proceed with server logout if we have a token
 	if token != "" {
-		req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s/auth/signout", c.BaseURL, API_VERSION), nil)
+		req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s/auth/signout", c.BaseURL, API_VERSION), nil)
 		if err != nil {
 			return fmt.Errorf("error creating logout request: %w", err)
 		}
 
 		req.Header.Set("Authorization", "Bearer "+token)
 
-		client, err := createHTTPClientWithCookieJar()
-		if err != nil {
-			return fmt.Errorf("error creating HTTP client: %w", err)
-		}
-
-		resp, err := client.Do(req)
+		resp, err := c.do(req)
 		if err != nil {
 			return fmt.Errorf("error calling signout endpoint: %w", err)
 		}
@@ -197,19 +182,6 @@ func (c *Client) Logout() error {
 	return nil
 }
 
-// createHTTPClientWithCookieJar creates an HTTP client with a cookie jar
-func createHTTPClientWithCookieJar() (*http.Client, error) {
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating cookie jar: %w", err)
-	}
-
-	return &http.Client{
-		Timeout: 30 * time.Second,
-		Jar:     jar,
-	}, nil
-}
-
 // extractUserInfo extracts user information from the response
 func extractUserInfo(responseMap map[string]interface{}) (string, string) {
 	userID, email := "", ""
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
@@ -18,11 +19,17 @@ type Client struct {
 	// Authentication token
 	AuthToken string
 
-	// HTTP client with a timeout
+	// HTTP client all requests go through
 	client *http.Client
 
+	// How requests that fail for transient reasons are retried
+	retry RetryPolicy
+
 	// Token store for managing authentication tokens
 	tokenStore *models.TokenStore
+
+	// Told about the bytes sent and received for files, if set
+	progress ProgressReporter
 }
 
 // NewClient creates a new API client
@@ -39,12 +46,35 @@ func NewClient(baseURL string, tokenStore *models.TokenStore) *Client {
 		BaseURL:    baseURL,
 		AuthToken:  token,
 		tokenStore: tokenStore,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:     newHTTPClient(),
+		retry:      DefaultRetryPolicy,
 	}
 }
 
+// pingTimeout bounds how long Ping waits for the server to answer
+const pingTimeout = 5 * time.Second
+
+// Ping checks that the server answers at its base URL. Any response counts,
+// whatever its status. The request isn't retried, so that an unreachable server
+// is reported quickly.
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	return nil
+}
+
 // UploadedFile contains information about an uploaded file
 type UploadedFile struct {
 	Path      string `json:"path"`
@@ -69,7 +99,7 @@ type CollectionInfo struct {
 }
 
 // CreateCollection creates a new collection
-func (c *Client) CreateCollection(collection *models.Collection) error {
+func (c *Client) CreateCollection(ctx context.Context, collection *models.Collection) error {
 	collectionInfo := CollectionInfo{
 		Name: collection.Name,
 		Type: string(collection.Type),
@@ -100,15 +130,16 @@ func (c *Client) CreateCollection(collection *models.Collection) error {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/collections", c.BaseURL), bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/collections", c.BaseURL), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
+	setIdempotencyKey(req)
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return err
 	}
@@ -128,15 +159,15 @@ func (c *Client) CreateCollection(collection *models.Collection) error {
 }
 
 // GetStatus gets the status of files from the server
-func (c *Client) GetStatus() ([]*models.File, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/files/status", c.BaseURL), nil)
+func (c *Client) GetStatus(ctx context.Context) ([]*models.File, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/files/status", c.BaseURL), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -161,15 +192,15 @@ func (c *Client) GetStatus() ([]*models.File, error) {
 }
 
 // ListCollections gets the list of available collections from the server
-func (c *Client) ListCollections() ([]*models.Collection, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/collections", c.BaseURL), nil)
+func (c *Client) ListCollections(ctx context.Context) ([]*models.Collection, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/collections", c.BaseURL), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
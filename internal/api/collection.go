@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+)
+
+// createProjectCollectionRequest creates a collection in a project
+type createProjectCollectionRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// ProjectCollectionExists checks if a project has a collection with the given name
+func (c *Client) ProjectCollectionExists(ctx context.Context, projectID, collectionName string) (bool, error) {
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return false, fmt.Errorf("error getting token: %w", err)
+	}
+
+	return c.projectCollectionExists(ctx, projectID, collectionName, token)
+}
+
+// projectCollectionExists lists a project's collections to find one by name
+func (c *Client) projectCollectionExists(ctx context.Context, projectID, collectionName, token string) (bool, error) {
+	collectionsURL := fmt.Sprintf("%s/%s/projects/%s/collections", c.BaseURL, API_VERSION, projectID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", collectionsURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request to check collections: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, fmt.Errorf("error checking collections: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("failed to check collections with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var collectionsResponse models.CollectionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&collectionsResponse); err != nil {
+		return false, fmt.Errorf("error decoding collections response: %w", err)
+	}
+
+	for _, c := range collectionsResponse.Collections {
+		if c.Name == collectionName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateProjectCollection creates a collection in a project on the server
+func (c *Client) CreateProjectCollection(ctx context.Context, projectID, name string, collectionType models.CollectionType, path string) error {
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return fmt.Errorf("error getting token: %w", err)
+	}
+
+	jsonData, err := json.Marshal(createProjectCollectionRequest{
+		Name: name,
+		Type: string(collectionType),
+		Path: path,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/collections", c.BaseURL, API_VERSION, projectID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("creating collection failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
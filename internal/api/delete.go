@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+)
+
+// DeleteResponse represents the response from a delete operation
+type DeleteResponse struct {
+	DeletedFiles []string      `json:"deleted_files"`
+	Errors       []UploadError `json:"errors"`
+}
+
+// DeleteFilesFromProjectCollection removes files from a specific project and collection
+func (c *Client) DeleteFilesFromProjectCollection(ctx context.Context, paths []string, projectNameOrID string, collection *models.Collection) (*DeleteResponse, error) {
+	if err := validatePushInputs(projectNameOrID, collection); err != nil {
+		return nil, err
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.verifyCollectionExists(ctx, projectID, collection.Name, token); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/collections/%s/files", c.BaseURL, API_VERSION, projectID, collection.Name)
+
+	jsonData, err := json.Marshal(map[string][]string{
+		"paths": paths,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var deleteResponse DeleteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&deleteResponse); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return &deleteResponse, nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"io"
+	"sync"
+)
+
+// ProgressReporter is told how file transfers progress. Files are identified by
+// the path they were pushed under or the destination they are downloaded to.
+// Methods may be called from several goroutines at once.
+type ProgressReporter interface {
+	// Transferred reports that n more bytes of a file were sent or received
+	Transferred(path string, n int64)
+
+	// Retried reports that n bytes of a file reported as transferred are
+	// being sent again, after the request carrying them failed
+	Retried(path string, n int64)
+
+	// Skipped reports a file whose contents didn't need to be transferred
+	Skipped(path string)
+}
+
+// SetProgress makes the client report the bytes it sends and receives for
+// files; nil stops reporting
+func (c *Client) SetProgress(progress ProgressReporter) {
+	c.progress = progress
+}
+
+// countingReader reports the bytes read through it as transferred
+type countingReader struct {
+	r        io.Reader
+	path     string
+	progress ProgressReporter
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.progress.Transferred(r.path, int64(n))
+	}
+	return n, err
+}
+
+// countReads wraps a reader of a file's contents so reading it reports progress
+// to progress, which may be nil
+func countReads(r io.Reader, path string, progress ProgressReporter) io.Reader {
+	if progress == nil {
+		return r
+	}
+	return &countingReader{r: r, path: path, progress: progress}
+}
+
+// attemptProgress passes on the progress of one attempt at sending a request
+// body, keeping count of the bytes reported for each file
+type attemptProgress struct {
+	progress ProgressReporter
+
+	mu    sync.Mutex
+	sent  map[string]int64
+	ended bool
+}
+
+func (a *attemptProgress) Transferred(path string, n int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A failed attempt may still be reading when the next one starts
+	if a.ended {
+		return
+	}
+	a.sent[path] += n
+	a.progress.Transferred(path, n)
+}
+
+func (a *attemptProgress) Retried(path string, n int64) {
+	a.progress.Retried(path, n)
+}
+
+func (a *attemptProgress) Skipped(path string) {
+	a.progress.Skipped(path)
+}
+
+// retry takes back the bytes reported for the attempt, which the next attempt
+// sends again
+func (a *attemptProgress) retry() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ended = true
+	for path, n := range a.sent {
+		a.progress.Retried(path, n)
+	}
+}
+
+// attempts returns a function to call each time a request body is made, which
+// returns the reporter for the reads of that attempt. Making the body again for
+// a retry takes back what the previous attempt reported, so progress isn't
+// counted twice. The reporter is nil if the client doesn't report progress.
+func (c *Client) attempts() func() ProgressReporter {
+	var current *attemptProgress
+	return func() ProgressReporter {
+		if c.progress == nil {
+			return nil
+		}
+		if current != nil {
+			current.retry()
+		}
+		current = &attemptProgress{progress: c.progress, sent: make(map[string]int64)}
+		return current
+	}
+}
+
+// reportTransferred reports bytes of a file transferred without a reader
+func (c *Client) reportTransferred(path string, n int64) {
+	if c.progress != nil && n > 0 {
+		c.progress.Transferred(path, n)
+	}
+}
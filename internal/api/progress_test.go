@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"hhx/internal/models"
+	"sync"
+	"testing"
+)
+
+// testProgress adds up the bytes reported for each file
+type testProgress struct {
+	mu   sync.Mutex
+	done map[string]int64
+}
+
+func (p *testProgress) Transferred(path string, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[path] += n
+}
+
+func (p *testProgress) Retried(path string, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done[path] -= n
+}
+
+func (p *testProgress) Skipped(path string) {}
+
+func TestProgressCountsRetriedBytesOnce(t *testing.T) {
+	const big = ChunkedUploadThreshold
+	tests := []struct {
+		name string
+		size int64
+	}{
+		{"form", 1 << 20},
+		{"upload part", big},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			client := newTestClient(t, server)
+			repoRoot := t.TempDir()
+
+			progress := &testProgress{done: make(map[string]int64)}
+			client.SetProgress(progress)
+			server.unavailable = 1
+			file := writeTestFile(t, repoRoot, "file.bin", tt.size, "hash-file")
+
+			resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Errors) > 0 || len(resp.UploadedFiles) != 1 {
+				t.Fatalf("uploaded %v with errors %v", resp.UploadedFiles, resp.Errors)
+			}
+			if server.unavailable != 0 {
+				t.Fatal("the push wasn't retried")
+			}
+
+			if done := progress.done[file.Path]; done != tt.size {
+				t.Errorf("progress reported %d bytes of %d", done, tt.size)
+			}
+		})
+	}
+}
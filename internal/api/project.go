@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
@@ -10,7 +11,7 @@ import (
 )
 
 // CreateProject creates a new project
-func (c *Client) CreateProject(name string, description string) (*models.Project, error) {
+func (c *Client) CreateProject(ctx context.Context, name string, description string) (*models.Project, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -28,16 +29,16 @@ func (c *Client) CreateProject(name string, description string) (*models.Project
 		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -66,7 +67,7 @@ func (c *Client) CreateProject(name string, description string) (*models.Project
 }
 
 // GetProject retrieves a project by ID
-func (c *Client) GetProject(projectID string) (*models.Project, error) {
+func (c *Client) GetProject(ctx context.Context, projectID string) (*models.Project, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -74,14 +75,13 @@ func (c *Client) GetProject(projectID string) (*models.Project, error) {
 
 	url := fmt.Sprintf("%s/%s/projects/%s", c.BaseURL, API_VERSION, projectID)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -109,7 +109,7 @@ func (c *Client) GetProject(projectID string) (*models.Project, error) {
 }
 
 // GetProjectByName retrieves a project by name
-func (c *Client) GetProjectByName(projectName string) (*models.Project, error) {
+func (c *Client) GetProjectByName(ctx context.Context, projectName string) (*models.Project, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -117,14 +117,13 @@ func (c *Client) GetProjectByName(projectName string) (*models.Project, error) {
 
 	url := fmt.Sprintf("%s/%s/projects/name/%s", c.BaseURL, API_VERSION, projectName)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -152,7 +151,7 @@ func (c *Client) GetProjectByName(projectName string) (*models.Project, error) {
 }
 
 // ListProjects retrieves all projects for the current user
-func (c *Client) ListProjects() ([]models.Project, error) {
+func (c *Client) ListProjects(ctx context.Context) ([]models.Project, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -160,14 +159,13 @@ func (c *Client) ListProjects() ([]models.Project, error) {
 
 	url := fmt.Sprintf("%s/%s/projects", c.BaseURL, API_VERSION)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -195,7 +193,7 @@ func (c *Client) ListProjects() ([]models.Project, error) {
 }
 
 // UpdateProject updates a project
-func (c *Client) UpdateProject(projectID string, name string, description string) (*models.Project, error) {
+func (c *Client) UpdateProject(ctx context.Context, projectID string, name string, description string) (*models.Project, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -218,15 +216,14 @@ func (c *Client) UpdateProject(projectID string, name string, description string
 		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -255,7 +252,7 @@ func (c *Client) UpdateProject(projectID string, name string, description string
 }
 
 // DeleteProject deletes a project
-func (c *Client) DeleteProject(projectID string) error {
+func (c *Client) DeleteProject(ctx context.Context, projectID string) error {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return fmt.Errorf("error getting token: %w", err)
@@ -263,14 +260,13 @@ func (c *Client) DeleteProject(projectID string) error {
 
 	url := fmt.Sprintf("%s/%s/projects/%s", c.BaseURL, API_VERSION, projectID)
 
-	req, err := http.NewRequest("DELETE", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RemoteFile contains information about a file stored in a remote collection
+type RemoteFile struct {
+	Path      string `json:"path"`
+	RemoteURL string `json:"remote_url"`
+	Size      int64  `json:"size"`
+	Hash      string `json:"hash"`
+}
+
+// ListProjectCollectionFiles lists the files stored in a specific project and collection
+func (c *Client) ListProjectCollectionFiles(ctx context.Context, projectNameOrID string, collection *models.Collection) ([]RemoteFile, error) {
+	if err := validatePushInputs(projectNameOrID, collection); err != nil {
+		return nil, err
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.verifyCollectionExists(ctx, projectID, collection.Name, token); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/collections/%s/files", c.BaseURL, API_VERSION, projectID, collection.Name)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list files with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var response struct {
+		Files []RemoteFile `json:"files"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return response.Files, nil
+}
+
+// DownloadFile downloads a remote file to destPath and verifies its SHA-256 hash.
+// The content is written to a temporary file next to destPath and only moved
+// into place once the hash matches, so a failed download never leaves a
+// partially written file behind.
+func (c *Client) DownloadFile(ctx context.Context, remoteURL, destPath, expectedHash string) (int64, error) {
+	if remoteURL == "" {
+		return 0, fmt.Errorf("no remote URL for %s", destPath)
+	}
+
+	// Remote URLs may be relative to the API server
+	if strings.HasPrefix(remoteURL, "/") {
+		remoteURL = c.BaseURL + remoteURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", remoteURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".hhx-download-*")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), countReads(resp.Body, destPath, c.progress))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("error writing %s: %w", destPath, err)
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+	if expectedHash != "" && hash != expectedHash {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("hash mismatch for %s: expected %s, got %s", destPath, expectedHash, hash)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, fmt.Errorf("error moving %s into place: %w", destPath, err)
+	}
+
+	return size, nil
+}
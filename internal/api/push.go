@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
@@ -16,16 +17,29 @@ import (
 
 // PushResponse represents the response from a push operation
 type PushResponse struct {
-	UploadedFiles []struct {
-		Path      string `json:"path"`
-		RemoteURL string `json:"remote_url"`
-		Size      int64  `json:"size"`
-	} `json:"uploaded_files"`
-	Errors []UploadError `json:"errors"`
+	UploadedFiles []UploadedFile `json:"uploaded_files"`
+	Errors        []UploadError  `json:"errors"`
+
+	// Files sent as references to content the server already had, and their
+	// total size. These are filled in by the client, not the server.
+	Referenced int   `json:"-"`
+	SavedBytes int64 `json:"-"`
+}
+
+// existingHashesRequest asks which file contents the server already stores
+type existingHashesRequest struct {
+	Hashes []string `json:"hashes"`
+}
+
+// existingHashesResponse lists the requested hashes the server already stores
+type existingHashesResponse struct {
+	Existing []string `json:"existing"`
 }
 
-// PushFilesToProjectCollection uploads files to a specific project and collection
-func (c *Client) PushFilesToProjectCollection(repoRoot string, files []*models.File, projectNameOrID string, collection *models.Collection) (*PushResponse, error) {
+// PushFilesToProjectCollection uploads files to a specific project and collection.
+// If ctx is cancelled part way through, the files uploaded until then are
+// returned, and the rest are left out of the response.
+func (c *Client) PushFilesToProjectCollection(ctx context.Context, repoRoot string, files []*models.File, projectNameOrID string, collection *models.Collection) (*PushResponse, error) {
 	if err := validatePushInputs(projectNameOrID, collection); err != nil {
 		return nil, err
 	}
@@ -35,21 +49,257 @@ func (c *Client) PushFilesToProjectCollection(repoRoot string, files []*models.F
 		return nil, fmt.Errorf("error getting token: %w", err)
 	}
 
-	projectID, err := c.resolveProjectID(projectNameOrID, token)
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := c.verifyCollectionExists(projectID, collection.Name, token); err != nil {
+	if err := c.verifyCollectionExists(ctx, projectID, collection.Name, token); err != nil {
+		return nil, err
+	}
+
+	// Contents the server already stores are sent as references instead of bytes
+	existing, err := c.findExistingHashes(ctx, projectID, files, token)
+	if err != nil {
 		return nil, err
 	}
+	references, _ := planReferences(files, existing)
+
+	// Large files are uploaded in resumable parts, the rest in a single form.
+	// Parts go first, since the form may reference their contents.
+	var formFiles, chunkedFiles []*models.File
+	for _, file := range files {
+		if !references[file.Path] && file.Size >= ChunkedUploadThreshold {
+			chunkedFiles = append(chunkedFiles, file)
+		} else {
+			formFiles = append(formFiles, file)
+		}
+	}
+
+	pushResponse := &PushResponse{}
+	uploads := models.NewUploadStore(repoRoot)
+	failed := make(map[string]string)
+	for _, file := range chunkedFiles {
+		uploaded, err := c.uploadChunked(ctx, uploads, repoRoot, projectID, collection, file, token)
+		if err != nil && ctx.Err() != nil {
+			// Interrupted: report only the files already uploaded
+			return interruptedPush(ctx, pushResponse)
+		}
+		if err != nil {
+			pushResponse.Errors = append(pushResponse.Errors, UploadError{Path: file.Path, Error: err.Error()})
+			failed[file.Hash] = file.Path
+			continue
+		}
+		pushResponse.UploadedFiles = append(pushResponse.UploadedFiles, *uploaded)
+	}
+
+	// Repeats of contents whose upload failed would reference contents the
+	// server never received
+	if len(failed) > 0 {
+		var sent []*models.File
+		for _, file := range formFiles {
+			if first, ok := failed[file.Hash]; ok && references[file.Path] && !existing[file.Hash] {
+				pushResponse.Errors = append(pushResponse.Errors, UploadError{
+					Path:  file.Path,
+					Error: fmt.Sprintf("not uploaded: uploading %s with the same contents failed", first),
+				})
+				continue
+			}
+			sent = append(sent, file)
+		}
+		formFiles = sent
+	}
+
+	if len(formFiles) > 0 {
+		formResponse, err := c.pushForm(ctx, repoRoot, projectID, collection, formFiles, references, token)
+		if err != nil && ctx.Err() != nil {
+			return interruptedPush(ctx, pushResponse)
+		}
+		if err != nil {
+			if len(chunkedFiles) == 0 {
+				return nil, err
+			}
+			for _, file := range formFiles {
+				pushResponse.Errors = append(pushResponse.Errors, UploadError{Path: file.Path, Error: err.Error()})
+			}
+		} else {
+			pushResponse.UploadedFiles = append(pushResponse.UploadedFiles, formResponse.UploadedFiles...)
+			pushResponse.Errors = append(pushResponse.Errors, formResponse.Errors...)
+		}
+	}
+
+	// Count only the references the server accepted
+	sizes := make(map[string]int64, len(files))
+	for _, file := range files {
+		sizes[file.Path] = file.Size
+	}
+	for _, uploaded := range pushResponse.UploadedFiles {
+		if references[uploaded.Path] {
+			pushResponse.Referenced++
+			pushResponse.SavedBytes += sizes[uploaded.Path]
+		}
+	}
+	return pushResponse, nil
+}
+
+// interruptedPush returns what a push cancelled part way through uploaded, or
+// the cancellation error if nothing was uploaded. Files missing from the
+// response were not uploaded.
+func interruptedPush(ctx context.Context, pushResponse *PushResponse) (*PushResponse, error) {
+	if len(pushResponse.UploadedFiles) == 0 {
+		return nil, ctx.Err()
+	}
+	return pushResponse, nil
+}
+
+// pushForm uploads files in a single multipart form
+func (c *Client) pushForm(ctx context.Context, repoRoot, projectID string, collection *models.Collection, files []*models.File, references map[string]bool, token string) (*PushResponse, error) {
+	newBody, contentType := c.createMultipartRequest(repoRoot, files, collection, references)
+	return c.sendPushRequest(ctx, projectID, collection.Name, newBody, contentType, token)
+}
+
+// findExistingHashes asks the server which of the files' hashes it already
+// stores. Servers without deduplication support are treated as storing none.
+func (c *Client) findExistingHashes(ctx context.Context, projectID string, files []*models.File, token string) (map[string]bool, error) {
+	seen := make(map[string]bool, len(files))
+	var hashes []string
+	for _, file := range files {
+		if file.Hash != "" && !seen[file.Hash] {
+			seen[file.Hash] = true
+			hashes = append(hashes, file.Hash)
+		}
+	}
+
+	existing := make(map[string]bool)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+
+	requestBody, err := json.Marshal(existingHashesRequest{Hashes: hashes})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling hashes: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/files/exists", c.BaseURL, API_VERSION, projectID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing files: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented {
+		return existing, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("checking existing files failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var existingResponse existingHashesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&existingResponse); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	for _, hash := range existingResponse.Existing {
+		if seen[hash] {
+			existing[hash] = true
+		}
+	}
+	return existing, nil
+}
+
+// planReferences picks the files whose contents don't need to be uploaded:
+// those the server already stores, and repeats of a hash uploaded by an earlier
+// file. It returns their paths and total size.
+func planReferences(files []*models.File, existing map[string]bool) (map[string]bool, int64) {
+	references := make(map[string]bool)
+	uploaded := make(map[string]bool, len(files))
+	var savedBytes int64
+	for _, file := range files {
+		if file.Hash == "" {
+			continue
+		}
+		if existing[file.Hash] || uploaded[file.Hash] {
+			references[file.Path] = true
+			savedBytes += file.Size
+			continue
+		}
+		uploaded[file.Hash] = true
+	}
+	return references, savedBytes
+}
 
-	requestBody, contentType, err := c.createMultipartRequest(repoRoot, files, collection)
+// PushPlan is what pushing files to collections would do
+type PushPlan struct {
+	// Whether each collection exists on the server; a push to one that doesn't
+	// would fail before uploading anything
+	CollectionExists map[string]bool
+
+	// Paths of the files that would be sent as references instead of their
+	// contents, and their total size
+	References map[string]bool
+	SavedBytes int64
+}
+
+// PlanPush works out what pushing files to collections would do, without
+// uploading anything. The project and collections are checked the same way
+// as by PushFilesToProjectCollection, and the server is asked which contents
+// it already stores. A push uploads each content once: files repeating
+// contents uploaded earlier in it, in any batch or collection, are sent as
+// references.
+func (c *Client) PlanPush(ctx context.Context, projectNameOrID string, collections []*models.Collection, filesByCollection map[string][]*models.File) (*PushPlan, error) {
+	for _, collection := range collections {
+		if err := validatePushInputs(projectNameOrID, collection); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.sendPushRequest(projectID, collection.Name, requestBody, contentType, token)
+	plan := &PushPlan{
+		CollectionExists: make(map[string]bool, len(collections)),
+		References:       make(map[string]bool),
+	}
+
+	// Files pushed to a missing collection are never uploaded
+	var files []*models.File
+	for _, collection := range collections {
+		exists, err := c.projectCollectionExists(ctx, projectID, collection.Name, token)
+		if err != nil {
+			return nil, fmt.Errorf("checking collection '%s': %w", collection.Name, err)
+		}
+		plan.CollectionExists[collection.Name] = exists
+		if exists {
+			files = append(files, filesByCollection[collection.Name]...)
+		}
+	}
+	if len(files) == 0 {
+		return plan, nil
+	}
+
+	existing, err := c.findExistingHashes(ctx, projectID, files, token)
+	if err != nil {
+		return nil, err
+	}
+	plan.References, plan.SavedBytes = planReferences(files, existing)
+	return plan, nil
 }
 
 // validatePushInputs validates the inputs for the push operation
@@ -69,22 +319,48 @@ func validatePushInputs(projectNameOrID string, collection *models.Collection) e
 	return nil
 }
 
+// ResolveProjectID returns the ID of a project given its name or ID, so callers
+// making many requests to the same project look it up only once
+func (c *Client) ResolveProjectID(ctx context.Context, projectNameOrID string) (string, error) {
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return "", fmt.Errorf("error getting token: %w", err)
+	}
+
+	return c.resolveProjectID(ctx, projectNameOrID, token)
+}
+
 // resolveProjectID resolves a project name to its ID
-func (c *Client) resolveProjectID(projectNameOrID string, token string) (string, error) {
+func (c *Client) resolveProjectID(ctx context.Context, projectNameOrID string, token string) (string, error) {
 	if util.IsUUID(projectNameOrID) {
 		return projectNameOrID, nil
 	}
 
 	// Not a UUID, try to get the project by name
 	fmt.Printf("Project '%s' doesn't look like a UUID, looking up project ID...\n", projectNameOrID)
-	projects, err := c.ListProjects()
+	projectID, err := c.FindProjectID(ctx, projectNameOrID)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Found project ID: %s\n", projectID)
+	return projectID, nil
+}
+
+// FindProjectID resolves a project name or ID to its ID like ResolveProjectID,
+// without printing anything
+func (c *Client) FindProjectID(ctx context.Context, projectNameOrID string) (string, error) {
+	if util.IsUUID(projectNameOrID) {
+		return projectNameOrID, nil
+	}
+
+	projects, err := c.ListProjects(ctx)
 	if err != nil {
 		return "", fmt.Errorf("error listing projects: %w", err)
 	}
 
 	for _, p := range projects {
 		if p.Name == projectNameOrID {
-			fmt.Printf("Found project ID: %s\n", p.ID)
 			return p.ID, nil
 		}
 	}
@@ -93,65 +369,73 @@ func (c *Client) resolveProjectID(projectNameOrID string, token string) (string,
 }
 
 // verifyCollectionExists checks if a collection exists on the server
-func (c *Client) verifyCollectionExists(projectID, collectionName, token string) error {
-	collectionsURL := fmt.Sprintf("%s/%s/projects/%s/collections", c.BaseURL, API_VERSION, projectID)
-
-	req, err := http.NewRequest("GET", collectionsURL, nil)
+func (c *Client) verifyCollectionExists(ctx context.Context, projectID, collectionName, token string) error {
+	exists, err := c.projectCollectionExists(ctx, projectID, collectionName, token)
 	if err != nil {
-		return fmt.Errorf("error creating request to check collections: %w", err)
+		return err
 	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error checking collections: %w", err)
+	if !exists {
+		return fmt.Errorf("collection '%s' does not exist on the remote server. Please create it first using API calls", collectionName)
 	}
-	defer safelyCloseResponseBody(resp.Body)
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to check collections with status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
+// createMultipartRequest streams a multipart request with files and metadata.
+// Files in references are sent as metadata only. The body is written by a
+// goroutine as it is read, so files are never held in memory; an error while
+// writing it is returned by the reader. Each call of the returned function
+// starts a new copy of the body with the same boundary, so the request can be
+// sent again if it has to be retried.
+func (c *Client) createMultipartRequest(repoRoot string, files []*models.File, collection *models.Collection, references map[string]bool) (func() (io.ReadCloser, error), string) {
+	form := multipart.NewWriter(io.Discard)
+	boundary := form.Boundary()
+
+	attempt := c.attempts()
+	newBody := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
 
-	var collectionsResponse models.CollectionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&collectionsResponse); err != nil {
-		return fmt.Errorf("error decoding collections response: %w", err)
-	}
+		progress := attempt()
+		go func() {
+			pw.CloseWithError(writeMultipartRequest(writer, repoRoot, files, collection, references, progress))
+		}()
 
-	// Check if the collection exists in the remote collections
-	for _, c := range collectionsResponse.Collections {
-		if c.Name == collectionName {
-			return nil // Collection exists
-		}
+		return pr, nil
 	}
 
-	return fmt.Errorf("collection '%s' does not exist on the remote server. Please create it first using API calls", collectionName)
+	return newBody, form.FormDataContentType()
 }
 
-// createMultipartRequest creates a multipart request with files and metadata
-func (c *Client) createMultipartRequest(repoRoot string, files []*models.File, collection *models.Collection) (*bytes.Buffer, string, error) {
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
-
+// writeMultipartRequest writes the parts of a push request and closes the
+// writer, reporting the files it reads to progress if it isn't nil
+func writeMultipartRequest(writer *multipart.Writer, repoRoot string, files []*models.File, collection *models.Collection, references map[string]bool, progress ProgressReporter) error {
 	if err := addMetadataToRequest(writer, collection); err != nil {
-		return nil, "", err
+		return err
 	}
 
 	// Add each file to the form
 	for _, file := range files {
-		if err := addFileToRequest(writer, repoRoot, file); err != nil {
-			return nil, "", err
+		if references[file.Path] {
+			if err := addFileReferenceToRequest(writer, file); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress.Skipped(file.Path)
+			}
+			continue
+		}
+		if err := addFileToRequest(writer, repoRoot, file, progress); err != nil {
+			return err
 		}
 	}
 
-	contentType := writer.FormDataContentType()
 	if err := writer.Close(); err != nil {
-		return nil, "", fmt.Errorf("error closing multipart writer: %w", err)
+		return fmt.Errorf("error closing multipart writer: %w", err)
 	}
-
-	return &requestBody, contentType, nil
+	return nil
 }
 
 // addMetadataToRequest adds collection metadata to the multipart request
@@ -179,7 +463,7 @@ func addMetadataToRequest(writer *multipart.Writer, collection *models.Collectio
 }
 
 // addFileToRequest adds a file and its metadata to the multipart request
-func addFileToRequest(writer *multipart.Writer, repoRoot string, file *models.File) error {
+func addFileToRequest(writer *multipart.Writer, repoRoot string, file *models.File, progress ProgressReporter) error {
 	fullPath := filepath.Join(repoRoot, file.Path)
 	f, err := os.Open(fullPath)
 	if err != nil {
@@ -198,15 +482,21 @@ func addFileToRequest(writer *multipart.Writer, repoRoot string, file *models.Fi
 		return fmt.Errorf("error creating form file: %w", err)
 	}
 
-	if _, err := io.Copy(fileField, f); err != nil {
+	if _, err := io.Copy(fileField, countReads(f, file.Path, progress)); err != nil {
 		return fmt.Errorf("error copying file data: %w", err)
 	}
 
-	return addFileMetadataToRequest(writer, file, stat, contentType)
+	return addFileMetadataToRequest(writer, file, stat.Size(), contentType, false)
+}
+
+// addFileReferenceToRequest adds only the metadata of a file whose contents the
+// server already has. The server finds the contents by the file's hash.
+func addFileReferenceToRequest(writer *multipart.Writer, file *models.File) error {
+	return addFileMetadataToRequest(writer, file, file.Size, getContentTypeFromFilename(file.Path), true)
 }
 
 // addFileMetadataToRequest adds file metadata to the multipart request
-func addFileMetadataToRequest(writer *multipart.Writer, file *models.File, stat os.FileInfo, contentType string) error {
+func addFileMetadataToRequest(writer *multipart.Writer, file *models.File, size int64, contentType string, reference bool) error {
 	fileMetaField, err := writer.CreateFormField(fmt.Sprintf("file_meta_%s", file.Path))
 	if err != nil {
 		return fmt.Errorf("error creating file metadata field: %w", err)
@@ -214,11 +504,14 @@ func addFileMetadataToRequest(writer *multipart.Writer, file *models.File, stat
 
 	fileMeta := map[string]interface{}{
 		"path":         file.Path,
-		"size":         stat.Size(),
+		"size":         size,
 		"hash":         file.Hash,
 		"remote_url":   file.RemoteURL,
 		"content_type": contentType,
 	}
+	if reference {
+		fileMeta["reference"] = true
+	}
 
 	fileMetaBytes, err := json.Marshal(fileMeta)
 	if err != nil {
@@ -232,20 +525,28 @@ func addFileMetadataToRequest(writer *multipart.Writer, file *models.File, stat
 	return nil
 }
 
-// sendPushRequest sends the push request to the server
-func (c *Client) sendPushRequest(projectID, collectionName string, requestBody *bytes.Buffer, contentType, token string) (*PushResponse, error) {
+// sendPushRequest sends the push request to the server, with a body made by
+// newBody
+func (c *Client) sendPushRequest(ctx context.Context, projectID, collectionName string, newBody func() (io.ReadCloser, error), contentType, token string) (*PushResponse, error) {
 	url := fmt.Sprintf("%s/%s/projects/%s/collections/%s/files", c.BaseURL, API_VERSION, projectID, collectionName)
 
-	req, err := http.NewRequest("POST", url, requestBody)
+	requestBody, err := newBody()
+	if err != nil {
+		return nil, fmt.Errorf("error creating request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, requestBody)
 	if err != nil {
+		requestBody.Close()
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
+	req.GetBody = newBody
 
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"hhx/internal/models"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCollection is the collection the stand-in server serves
+var testCollection = &models.Collection{Name: "default", Type: models.CollectionTypeBucket, Path: "default"}
+
+func TestPushSendsDuplicateContentOnce(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	server.existing["hash-on-server"] = true
+	const big = ChunkedUploadThreshold + 1
+	files := []*models.File{
+		writeTestFile(t, repoRoot, "a.txt", 100, "hash-a"),
+		writeTestFile(t, repoRoot, "a-copy.txt", 100, "hash-a"),
+		writeTestFile(t, repoRoot, "known.txt", 200, "hash-on-server"),
+		writeTestFile(t, repoRoot, "b.txt", 300, "hash-b"),
+		writeTestFile(t, repoRoot, "big.bin", big, "hash-big"),
+		writeTestFile(t, repoRoot, "big-copy.bin", big, "hash-big"),
+	}
+
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, files, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	if len(resp.UploadedFiles) != len(files) {
+		t.Fatalf("%d files uploaded, want %d", len(resp.UploadedFiles), len(files))
+	}
+
+	// Each distinct content is sent once, and none the server already had
+	want := map[string]int64{"a.txt": 100, "b.txt": 300, "big.bin": big}
+	if len(server.received) != len(want) {
+		t.Errorf("server received contents of %v, want %v", server.received, want)
+	}
+	for path, size := range want {
+		if server.received[path] != size {
+			t.Errorf("server received %d bytes of %s, want %d", server.received[path], path, size)
+		}
+	}
+
+	sort.Strings(server.references)
+	if got := strings.Join(server.references, " "); got != "a-copy.txt big-copy.bin known.txt" {
+		t.Errorf("references sent: %s", got)
+	}
+	if resp.Referenced != 3 || resp.SavedBytes != 100+200+big {
+		t.Errorf("Referenced = %d, SavedBytes = %d; want 3 and %d", resp.Referenced, resp.SavedBytes, 100+200+big)
+	}
+}
+
+func TestPushDoesNotReferenceFailedChunkedUpload(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	server.refuseUploads["big.bin"] = true
+	const big = ChunkedUploadThreshold + 1
+	files := []*models.File{
+		writeTestFile(t, repoRoot, "big.bin", big, "hash-big"),
+		writeTestFile(t, repoRoot, "big-copy.bin", big, "hash-big"),
+		writeTestFile(t, repoRoot, "small.txt", 10, "hash-small"),
+	}
+
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, files, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := make(map[string]bool)
+	for _, uploadErr := range resp.Errors {
+		failed[uploadErr.Path] = true
+	}
+	if !failed["big.bin"] || !failed["big-copy.bin"] || len(failed) != 2 {
+		t.Errorf("failed files: %v, want big.bin and big-copy.bin", resp.Errors)
+	}
+	if len(server.references) != 0 {
+		t.Errorf("references sent to content the server never received: %v", server.references)
+	}
+	if len(resp.UploadedFiles) != 1 || resp.UploadedFiles[0].Path != "small.txt" {
+		t.Errorf("uploaded files: %v, want only small.txt", resp.UploadedFiles)
+	}
+	if resp.Referenced != 0 || resp.SavedBytes != 0 {
+		t.Errorf("Referenced = %d, SavedBytes = %d; want 0", resp.Referenced, resp.SavedBytes)
+	}
+}
+
+func TestPushFormStreamsLargeFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("pushes a multi-GB file")
+	}
+
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	const size = 2 << 30
+	file := writeTestFile(t, repoRoot, "huge.bin", size, "hash-huge")
+
+	// Sample the heap while the push runs
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	var peak uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			peak = max(peak, stats.HeapAlloc)
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	// A form, as opposed to a chunked upload, carries the whole file in one request
+	resp, err := client.pushForm(context.Background(), repoRoot, testProjectID, testCollection, []*models.File{file}, nil, "test-token")
+	close(done)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.UploadedFiles) != 1 || server.received["huge.bin"] != size {
+		t.Fatalf("server received %d of %d bytes", server.received["huge.bin"], int64(size))
+	}
+	if growth := int64(peak) - int64(before.HeapAlloc); growth > 64<<20 {
+		t.Errorf("heap grew by %d MB while pushing a %d MB file", growth>>20, int64(size)>>20)
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail for transient reasons are retried
+type RetryPolicy struct {
+	// Attempts made in total, including the first; 1 disables retries
+	MaxAttempts int
+
+	// Delay before the first retry, doubled for each retry after it
+	BaseDelay time.Duration
+
+	// Longest wait between attempts, including waits the server asks for
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used for any part of a policy that isn't set
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// responseHeaderTimeout bounds how long the server may take to start answering
+// once a request has been sent. Requests have no overall timeout, since
+// uploading or downloading a large file takes as long as it takes.
+const responseHeaderTimeout = 2 * time.Minute
+
+// newHTTPClient creates the HTTP client all of a Client's requests go through
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+
+	// A jar without a public suffix list never fails to be created
+	jar, _ := cookiejar.New(nil)
+
+	return &http.Client{
+		Transport: transport,
+		Jar:       jar,
+	}
+}
+
+// SetRetryPolicy sets how the client retries failed requests. Fields left at
+// zero take their value from DefaultRetryPolicy.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultRetryPolicy.BaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	c.retry = policy
+}
+
+// do sends a request, retrying it according to the client's retry policy.
+//
+// Network errors and 502 and 504 responses are retried only for requests that
+// are safe to repeat: GET, HEAD, PUT and DELETE requests, and requests that
+// carry an Idempotency-Key. 429 and 503 responses mean the server didn't handle
+// the request, so they are retried for every request, after the delay the
+// server asks for in Retry-After if it gives one.
+//
+// A request with a body is only retried if it has GetBody, which
+// http.NewRequest sets for in-memory bodies. Nothing is retried once the
+// request's context is cancelled.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	policy := c.retry
+	if policy.MaxAttempts == 0 {
+		policy = DefaultRetryPolicy
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if attempt >= policy.MaxAttempts || req.Context().Err() != nil || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		retry := req.Clone(req.Context())
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			retry.Body = body
+		}
+
+		delay := policy.backoff(attempt)
+		if wait, ok := retryAfter(resp); ok {
+			delay = min(wait, policy.MaxDelay)
+		}
+
+		if resp != nil {
+			// Reading what's left of the body lets the connection be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = retry
+	}
+}
+
+// shouldRetry decides whether a failed attempt at a request is worth repeating
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return isIdempotent(req)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotent(req)
+	default:
+		return false
+	}
+}
+
+// isIdempotent reports whether sending a request twice has the same effect as
+// sending it once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// backoff returns the delay before a retry: exponential in the number of
+// attempts so far, capped at MaxDelay, with jitter so that clients that failed
+// together don't retry together
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if shift := attempt - 1; shift < 30 {
+		delay = min(p.BaseDelay<<shift, p.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(mathrand.Int63n(int64(delay-half)+1))
+}
+
+// retryAfter returns the wait a 429 or 503 response asks for, given either in
+// seconds or as a date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// setIdempotencyKey gives a request a random key the server can use to
+// recognise a retry of a request it already handled. Retries of the request
+// send the same key.
+func setIdempotencyKey(req *http.Request) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return
+	}
+	req.Header.Set("Idempotency-Key", hex.EncodeToString(key))
+}
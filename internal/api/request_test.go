@@ -0,0 +1,249 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// attemptServer answers each request with the next of a list of statuses,
+// then with 200, and records the requests it received
+type attemptServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	keys     []string
+	bodies   []string
+}
+
+// newAttemptServer starts a server answering with statuses in turn, adding
+// header to its answers, stopped when the test ends
+func newAttemptServer(t *testing.T, header http.Header, statuses ...int) *attemptServer {
+	s := &attemptServer{statuses: statuses, header: header}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.keys = append(s.keys, r.Header.Get("Idempotency-Key"))
+		s.bodies = append(s.bodies, string(body))
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		s.mu.Unlock()
+
+		if status != http.StatusOK {
+			for name, values := range s.header {
+				w.Header()[name] = values
+			}
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// attempts returns the number of requests the server received
+func (s *attemptServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+// sent returns the idempotency key and body of each request received
+func (s *attemptServer) sent() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.keys...), append([]string(nil), s.bodies...)
+}
+
+// newRetryClient creates a client with the given retry delays, and a context
+// that fails the test's requests if retries wait longer than they should
+func newRetryClient(t *testing.T, baseDelay, maxDelay time.Duration) (*Client, context.Context) {
+	client := NewClient("", nil)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 4, BaseDelay: baseDelay, MaxDelay: maxDelay})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return client, ctx
+}
+
+func TestDoWaitsAsLongAsRetryAfterSays(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			server := newAttemptServer(t, http.Header{"Retry-After": {"0"}}, status)
+
+			// Without Retry-After the backoff would outlast the test
+			client, ctx := newRetryClient(t, time.Hour, time.Hour)
+
+			// Even a request that isn't safe to repeat: the server didn't handle it
+			req, err := http.NewRequestWithContext(ctx, "POST", server.URL, bytes.NewReader([]byte("body")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK || server.attempts() != 2 {
+				t.Errorf("got %d after %d attempts, want 200 after 2", resp.StatusCode, server.attempts())
+			}
+			if _, bodies := server.sent(); len(bodies) > 1 && bodies[1] != "body" {
+				t.Errorf("retry sent body %q, want %q", bodies[1], "body")
+			}
+		})
+	}
+}
+
+func TestDoLimitsRetryAfterToMaxDelay(t *testing.T) {
+	server := newAttemptServer(t, http.Header{"Retry-After": {"3600"}}, http.StatusServiceUnavailable)
+	client, ctx := newRetryClient(t, time.Millisecond, 10*time.Millisecond)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatalf("waited for the server's hour instead of the policy's limit: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || server.attempts() != 2 {
+		t.Errorf("got %d after %d attempts, want 200 after 2", resp.StatusCode, server.attempts())
+	}
+}
+
+func TestDoKeepsIdempotencyKeyAcrossRetries(t *testing.T) {
+	server := newAttemptServer(t, nil, http.StatusBadGateway, http.StatusGatewayTimeout)
+	client, ctx := newRetryClient(t, time.Millisecond, time.Millisecond)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", server.URL, bytes.NewReader([]byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setIdempotencyKey(req)
+
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || server.attempts() != 3 {
+		t.Fatalf("got %d after %d attempts, want 200 after 3", resp.StatusCode, server.attempts())
+	}
+	key := req.Header.Get("Idempotency-Key")
+	keys, bodies := server.sent()
+	for i := range keys {
+		if key == "" || keys[i] != key {
+			t.Errorf("attempt %d sent key %q, want %q", i+1, keys[i], key)
+		}
+		if bodies[i] != "body" {
+			t.Errorf("attempt %d sent body %q, want %q", i+1, bodies[i], "body")
+		}
+	}
+}
+
+func TestDoDoesNotRepeatUnsafeRequests(t *testing.T) {
+	server := newAttemptServer(t, nil, http.StatusBadGateway)
+	client, ctx := newRetryClient(t, time.Millisecond, time.Millisecond)
+
+	// Without an idempotency key the server may have handled the request
+	req, err := http.NewRequestWithContext(ctx, "POST", server.URL, bytes.NewReader([]byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway || server.attempts() != 1 {
+		t.Errorf("got %d after %d attempts, want 502 after 1", resp.StatusCode, server.attempts())
+	}
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	server := newAttemptServer(t, nil, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	client, ctx := newRetryClient(t, time.Millisecond, time.Millisecond)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway || server.attempts() != 4 {
+		t.Errorf("got %d after %d attempts, want 502 after 4", resp.StatusCode, server.attempts())
+	}
+}
+
+func TestBackoffIsJitteredAndCapped(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{100, time.Second},
+	}
+	for _, tt := range tests {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			delay := policy.backoff(tt.attempt)
+			if delay < tt.delay/2 || delay > tt.delay {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, delay, tt.delay/2, tt.delay)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) always waits %v", tt.attempt, policy.backoff(tt.attempt))
+		}
+	}
+}
+
+func TestDoStopsWaitingWhenCancelled(t *testing.T) {
+	server := newAttemptServer(t, http.Header{"Retry-After": {"3600"}}, http.StatusServiceUnavailable)
+	client, _ := newRetryClient(t, time.Hour, time.Hour)
+
+	// Ctrl-C while waiting to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	resp, err := client.do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request succeeded after being cancelled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("do returned %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second || server.attempts() != 1 {
+		t.Errorf("gave up after %v and %d attempts, want right after the first", elapsed, server.attempts())
+	}
+}
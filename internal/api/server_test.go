@@ -0,0 +1,321 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testProjectID is the project the stand-in server serves
+const testProjectID = "123e4567-e89b-12d3-a456-426614174000"
+
+// testServer is a stand-in for the hhx server that accepts pushes to one
+// project and records what it received
+type testServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	// Hashes the server already stores
+	existing map[string]bool
+
+	// Paths whose chunked uploads are refused
+	refuseUploads map[string]bool
+
+	// Bytes received for each path, in forms and in upload parts
+	received map[string]int64
+
+	// Paths sent as references in forms
+	references []string
+
+	// Number of forms received
+	forms int
+
+	// Path of each upload session
+	uploads map[string]string
+
+	// Part size the server picks for uploads, zero for the one requested
+	partSize int64
+
+	// Size of the largest upload part received
+	largestPart int64
+
+	// Number of an upload part refused once, zero for none
+	failPart int
+
+	// Numbers of the upload parts received, in order
+	partNumbers []int
+
+	// Number of forms and upload parts still to be read and then answered
+	// with 503 Service Unavailable
+	unavailable int
+}
+
+// newTestServer starts a stand-in server, stopped when the test ends
+func newTestServer(t testing.TB) *testServer {
+	s := &testServer{
+		existing:      make(map[string]bool),
+		refuseUploads: make(map[string]bool),
+		received:      make(map[string]int64),
+		uploads:       make(map[string]string),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestClient creates a client logged in to the stand-in server
+func newTestClient(t testing.TB, s *testServer) *Client {
+	t.Helper()
+
+	tokenStore := models.NewTokenStore(t.TempDir())
+	if err := tokenStore.SaveToken("test-token"); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(s.URL, tokenStore)
+}
+
+// writeTestFile creates a file of the given size in repoRoot, sparse where
+// the file system allows, and returns it as staged with the given hash
+func writeTestFile(t testing.TB, repoRoot, path string, size int64, hash string) *models.File {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(repoRoot, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+
+	return &models.File{Path: path, Size: size, Hash: hash, Status: models.StatusStaged}
+}
+
+func (s *testServer) handle(w http.ResponseWriter, r *http.Request) {
+	prefix := fmt.Sprintf("/%s/projects/%s/", API_VERSION, testProjectID)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "collections":
+		writeJSON(w, map[string]interface{}{
+			"collections": []map[string]string{{"name": "default"}},
+		})
+
+	case r.Method == "POST" && len(parts) == 2 && parts[0] == "files" && parts[1] == "exists":
+		s.handleExists(w, r)
+
+	case r.Method == "POST" && len(parts) == 3 && parts[2] == "files":
+		if !s.available(w, r) {
+			return
+		}
+		s.handleForm(w, r)
+
+	case r.Method == "POST" && len(parts) == 3 && parts[2] == "uploads":
+		s.handleCreateUpload(w, r)
+
+	case r.Method == "GET" && len(parts) == 4 && parts[2] == "uploads":
+		s.handleGetUpload(w, r, parts[3])
+
+	case r.Method == "PUT" && len(parts) == 6 && parts[4] == "parts":
+		if !s.available(w, r) {
+			return
+		}
+		s.handlePart(w, r, parts[3], parts[5])
+
+	case r.Method == "POST" && len(parts) == 5 && parts[4] == "complete":
+		s.handleComplete(w, parts[3])
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// available reads and refuses the request while the server is to be
+// unavailable, and reports whether it can be handled
+func (s *testServer) available(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	unavailable := s.unavailable > 0
+	if unavailable {
+		s.unavailable--
+	}
+	s.mu.Unlock()
+
+	if !unavailable {
+		return true
+	}
+	_, _ = io.Copy(io.Discard, r.Body)
+	w.Header().Set("Retry-After", "0")
+	http.Error(w, "try again", http.StatusServiceUnavailable)
+	return false
+}
+
+func (s *testServer) handleExists(w http.ResponseWriter, r *http.Request) {
+	var request existingHashesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	response := existingHashesResponse{Existing: []string{}}
+	for _, hash := range request.Hashes {
+		if s.existing[hash] {
+			response.Existing = append(response.Existing, hash)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, response)
+}
+
+// handleForm reads a pushed form part by part, without keeping file contents
+func (s *testServer) handleForm(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := PushResponse{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case part.FormName() == "files":
+			n, err := io.Copy(io.Discard, part)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			s.mu.Lock()
+			s.received[part.FileName()] += n
+			s.mu.Unlock()
+
+		case strings.HasPrefix(part.FormName(), "file_meta_"):
+			var meta struct {
+				Path      string `json:"path"`
+				Size      int64  `json:"size"`
+				Hash      string `json:"hash"`
+				Reference bool   `json:"reference"`
+			}
+			if err := json.NewDecoder(part).Decode(&meta); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			s.mu.Lock()
+			if meta.Reference {
+				s.references = append(s.references, meta.Path)
+			}
+			s.mu.Unlock()
+			response.UploadedFiles = append(response.UploadedFiles, UploadedFile{
+				Path:      meta.Path,
+				RemoteURL: "/files/" + meta.Path,
+				Size:      meta.Size,
+				Hash:      meta.Hash,
+			})
+		}
+	}
+
+	s.mu.Lock()
+	s.forms++
+	s.mu.Unlock()
+	writeJSON(w, response)
+}
+
+func (s *testServer) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	var request createUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refuseUploads[request.Path] {
+		http.Error(w, "upload refused", http.StatusBadRequest)
+		return
+	}
+
+	partSize := request.PartSize
+	if s.partSize != 0 {
+		partSize = s.partSize
+	}
+
+	uploadID := fmt.Sprintf("upload-%d", len(s.uploads)+1)
+	s.uploads[uploadID] = request.Path
+	writeJSON(w, createUploadResponse{UploadID: uploadID, PartSize: partSize})
+}
+
+func (s *testServer) handleGetUpload(w http.ResponseWriter, r *http.Request, uploadID string) {
+	s.mu.Lock()
+	path, ok := s.uploads[uploadID]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, map[string]string{"upload_id": uploadID, "path": path})
+}
+
+func (s *testServer) handlePart(w http.ResponseWriter, r *http.Request, uploadID, partNumber string) {
+	number, err := strconv.Atoi(partNumber)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := io.Copy(io.Discard, r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if number == s.failPart {
+		s.failPart = 0
+		s.mu.Unlock()
+		http.Error(w, "part refused", http.StatusBadRequest)
+		return
+	}
+	s.partNumbers = append(s.partNumbers, number)
+	s.received[s.uploads[uploadID]] += n
+	s.largestPart = max(s.largestPart, n)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *testServer) handleComplete(w http.ResponseWriter, uploadID string) {
+	s.mu.Lock()
+	path := s.uploads[uploadID]
+	size := s.received[path]
+	s.mu.Unlock()
+
+	writeJSON(w, UploadedFile{Path: path, RemoteURL: "/files/" + path, Size: size})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
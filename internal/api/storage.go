@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"hhx/internal/models"
@@ -12,7 +13,7 @@ import (
 )
 
 // ListBuckets retrieves all storage buckets
-func (c *Client) ListBuckets(projectID string) ([]models.Bucket, error) {
+func (c *Client) ListBuckets(ctx context.Context, projectID string) ([]models.Bucket, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -20,15 +21,14 @@ func (c *Client) ListBuckets(projectID string) ([]models.Bucket, error) {
 
 	listUrl := fmt.Sprintf("%s/%s/projects/%s/storage/buckets", c.BaseURL, API_VERSION, projectID)
 
-	req, err := http.NewRequest("GET", listUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", listUrl, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -56,7 +56,7 @@ func (c *Client) ListBuckets(projectID string) ([]models.Bucket, error) {
 }
 
 // CreateBucket creates a new storage bucket
-func (c *Client) CreateBucket(projectID string, name string, public bool, fileSizeLimit int64, allowedMimeTypes string) (*models.Bucket, error) {
+func (c *Client) CreateBucket(ctx context.Context, projectID string, name string, public bool, fileSizeLimit int64, allowedMimeTypes string) (*models.Bucket, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -84,16 +84,16 @@ func (c *Client) CreateBucket(projectID string, name string, public bool, fileSi
 		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", createUrl, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", createUrl, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -121,7 +121,7 @@ func (c *Client) CreateBucket(projectID string, name string, public bool, fileSi
 }
 
 // GetBucket retrieves details about a specific bucket
-func (c *Client) GetBucket(projectID string, name string) (*models.Bucket, error) {
+func (c *Client) GetBucket(ctx context.Context, projectID string, name string) (*models.Bucket, error) {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return nil, fmt.Errorf("error getting token: %w", err)
@@ -130,15 +130,14 @@ func (c *Client) GetBucket(projectID string, name string) (*models.Bucket, error
 	encodedName := url.PathEscape(name)
 	bucketsUrl := fmt.Sprintf("%s/%s/projects/%s/storage/buckets/%s", c.BaseURL, API_VERSION, projectID, encodedName)
 
-	req, err := http.NewRequest("GET", bucketsUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", bucketsUrl, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
@@ -166,7 +165,7 @@ func (c *Client) GetBucket(projectID string, name string) (*models.Bucket, error
 }
 
 // UpdateBucket updates a bucket's settings
-func (c *Client) UpdateBucket(projectID string, name string, updates interface{}) error {
+func (c *Client) UpdateBucket(ctx context.Context, projectID string, name string, updates interface{}) error {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return fmt.Errorf("error getting token: %w", err)
@@ -180,7 +179,7 @@ func (c *Client) UpdateBucket(projectID string, name string, updates interface{}
 		return fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("PATCH", updateUrl, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "PATCH", updateUrl, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
@@ -188,8 +187,7 @@ func (c *Client) UpdateBucket(projectID string, name string, updates interface{}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
@@ -209,7 +207,7 @@ func (c *Client) UpdateBucket(projectID string, name string, updates interface{}
 }
 
 // DeleteBucket deletes a storage bucket
-func (c *Client) DeleteBucket(projectID string, name string) error {
+func (c *Client) DeleteBucket(ctx context.Context, projectID string, name string) error {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return fmt.Errorf("error getting token: %w", err)
@@ -218,15 +216,14 @@ func (c *Client) DeleteBucket(projectID string, name string) error {
 	encodedName := url.PathEscape(name)
 	deleteUrl := fmt.Sprintf("%s/%s/projects/%s/storage/buckets/%s", c.BaseURL, API_VERSION, projectID, encodedName)
 
-	req, err := http.NewRequest("DELETE", deleteUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", deleteUrl, nil)
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
@@ -246,7 +243,7 @@ func (c *Client) DeleteBucket(projectID string, name string) error {
 }
 
 // EmptyBucket removes all files from a bucket
-func (c *Client) EmptyBucket(projectID string, name string) error {
+func (c *Client) EmptyBucket(ctx context.Context, projectID string, name string) error {
 	token, err := c.tokenStore.GetToken()
 	if err != nil {
 		return fmt.Errorf("error getting token: %w", err)
@@ -255,15 +252,14 @@ func (c *Client) EmptyBucket(projectID string, name string) error {
 	encodedName := url.PathEscape(name)
 	emptyUrl := fmt.Sprintf("%s/%s/projects/%s/storage/buckets/%s/empty", c.BaseURL, API_VERSION, projectID, encodedName)
 
-	req, err := http.NewRequest("POST", emptyUrl, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", emptyUrl, nil)
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("error making request: %w", err)
 	}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+)
+
+// CreateProjectTag stores a tag on the server for a project
+func (c *Client) CreateProjectTag(ctx context.Context, projectNameOrID string, tag *models.Tag) error {
+	if projectNameOrID == "" {
+		return fmt.Errorf("project name or ID is required")
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/tags", c.BaseURL, API_VERSION, projectID)
+
+	jsonData, err := json.Marshal(tag)
+	if err != nil {
+		return fmt.Errorf("error marshalling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode == http.StatusConflict {
+		return models.ErrTagExists
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("tag creation failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// GetProjectTag retrieves a tag and its files from the server
+func (c *Client) GetProjectTag(ctx context.Context, projectNameOrID string, name string) (*models.Tag, error) {
+	if projectNameOrID == "" {
+		return nil, fmt.Errorf("project name or ID is required")
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/tags/%s", c.BaseURL, API_VERSION, projectID, name)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, models.ErrTagNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get tag with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var tag models.Tag
+	if err := json.NewDecoder(resp.Body).Decode(&tag); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return &tag, nil
+}
+
+// ListProjectTags lists the tags stored on the server for a project. The files
+// of each tag are not included.
+func (c *Client) ListProjectTags(ctx context.Context, projectNameOrID string) ([]*models.Tag, error) {
+	if projectNameOrID == "" {
+		return nil, fmt.Errorf("project name or ID is required")
+	}
+
+	token, err := c.tokenStore.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("error getting token: %w", err)
+	}
+
+	projectID, err := c.resolveProjectID(ctx, projectNameOrID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/projects/%s/tags", c.BaseURL, API_VERSION, projectID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to list tags with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var response struct {
+		Tags []*models.Tag `json:"tags"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	return response.Tags, nil
+}
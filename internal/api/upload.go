@@ -0,0 +1,351 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ChunkedUploadThreshold is the size from which files are uploaded in parts
+// instead of as part of a multipart form
+const ChunkedUploadThreshold = 64 << 20
+
+// UploadPartSize is the size of the parts a chunked upload is split into
+const UploadPartSize = 16 << 20
+
+// MaxUploadPartSize is the largest part size accepted from the server or a
+// recorded session, since a whole part is held in memory while it is sent
+const MaxUploadPartSize = 256 << 20
+
+// createUploadRequest starts a chunked upload session
+type createUploadRequest struct {
+	Path           string                `json:"path"`
+	Size           int64                 `json:"size"`
+	Hash           string                `json:"hash"`
+	ContentType    string                `json:"content_type"`
+	PartSize       int64                 `json:"part_size"`
+	CollectionType models.CollectionType `json:"collection_type"`
+	CollectionPath string                `json:"collection_path"`
+}
+
+// createUploadResponse identifies a new upload session. The server may choose
+// a different part size than the one requested.
+type createUploadResponse struct {
+	UploadID string `json:"upload_id"`
+	PartSize int64  `json:"part_size"`
+}
+
+// completedPart identifies an uploaded part when completing an upload
+type completedPart struct {
+	PartNumber int    `json:"part_number"`
+	SHA256     string `json:"sha256"`
+}
+
+// completeUploadRequest lists the parts that make up the file
+type completeUploadRequest struct {
+	Parts []completedPart `json:"parts"`
+}
+
+// uploadChunked uploads a file in parts, resuming the upload recorded in the
+// store if there is one. The session is saved after every acknowledged part and
+// removed once the upload is complete.
+func (c *Client) uploadChunked(ctx context.Context, uploads *models.UploadStore, repoRoot, projectID string, collection *models.Collection, file *models.File, token string) (*UploadedFile, error) {
+	f, err := os.Open(filepath.Join(repoRoot, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", file.Path, err)
+	}
+	defer safelyCloseFile(f)
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error getting file stats for %s: %w", file.Path, err)
+	}
+	if stat.Size() != file.Size {
+		return nil, fmt.Errorf("%s changed since it was staged; stage it again", file.Path)
+	}
+
+	session, err := c.resumableSession(ctx, uploads, projectID, collection, file, stat.ModTime(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		session, err = c.createUpload(ctx, projectID, collection, file, token)
+		if err != nil {
+			return nil, err
+		}
+		session.ModTime = stat.ModTime()
+		if err := uploads.Save(session); err != nil {
+			return nil, fmt.Errorf("error saving upload session: %w", err)
+		}
+	} else if c.progress != nil {
+		c.reportTransferred(file.Path, session.UploadedBytes())
+	} else {
+		fmt.Printf("Resuming upload of %s from part %d of %d\n", file.Path, len(session.Parts)+1, session.PartCount())
+	}
+
+	buf := make([]byte, session.PartSize)
+	for number := 1; number <= session.PartCount(); number++ {
+		if session.Acknowledged(number) {
+			continue
+		}
+
+		offset := int64(number-1) * session.PartSize
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading %s: %w", file.Path, err)
+		}
+
+		sum := sha256.Sum256(buf[:n])
+		checksum := hex.EncodeToString(sum[:])
+		if err := c.uploadPart(ctx, projectID, collection.Name, session.UploadID, file.Path, number, buf[:n], checksum, token); err != nil {
+			return nil, err
+		}
+
+		session.Parts = append(session.Parts, models.UploadedPart{Number: number, Size: int64(n), SHA256: checksum})
+		if err := uploads.Save(session); err != nil {
+			return nil, fmt.Errorf("error saving upload session: %w", err)
+		}
+	}
+
+	uploaded, err := c.completeUpload(ctx, projectID, collection.Name, session, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uploads.Remove(session); err != nil {
+		fmt.Printf("Warning: failed to remove upload session for %s: %v\n", file.Path, err)
+	}
+	return uploaded, nil
+}
+
+// resumableSession returns the recorded session for a file if it can still be
+// resumed. Sessions for a different version of the file, including one modified
+// since the upload started, or that the server no longer knows, are discarded.
+func (c *Client) resumableSession(ctx context.Context, uploads *models.UploadStore, projectID string, collection *models.Collection, file *models.File, modTime time.Time, token string) (*models.UploadSession, error) {
+	session, err := uploads.Load(projectID, collection.Name, file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload session: %w", err)
+	}
+	if session == nil {
+		return nil, nil
+	}
+
+	unchanged := session.Hash == file.Hash && session.Size == file.Size && session.ModTime.Equal(modTime)
+	if unchanged && session.PartSize > 0 && session.PartSize <= MaxUploadPartSize {
+		exists, err := c.uploadExists(ctx, projectID, collection.Name, session.UploadID, token)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return session, nil
+		}
+	} else {
+		c.abortUpload(ctx, projectID, collection.Name, session.UploadID, token)
+	}
+
+	if err := uploads.Remove(session); err != nil {
+		return nil, fmt.Errorf("error removing upload session: %w", err)
+	}
+	return nil, nil
+}
+
+// uploadURL builds the URL of a collection's upload sessions, or of a path
+// under one of them
+func (c *Client) uploadURL(projectID, collectionName string, elems ...string) string {
+	url := fmt.Sprintf("%s/%s/projects/%s/collections/%s/uploads", c.BaseURL, API_VERSION, projectID, collectionName)
+	for _, elem := range elems {
+		url += "/" + elem
+	}
+	return url
+}
+
+// createUpload starts a chunked upload session on the server
+func (c *Client) createUpload(ctx context.Context, projectID string, collection *models.Collection, file *models.File, token string) (*models.UploadSession, error) {
+	requestBody, err := json.Marshal(createUploadRequest{
+		Path:           file.Path,
+		Size:           file.Size,
+		Hash:           file.Hash,
+		ContentType:    getContentTypeFromFilename(file.Path),
+		PartSize:       UploadPartSize,
+		CollectionType: collection.Type,
+		CollectionPath: collection.Path,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling upload request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.uploadURL(projectID, collection.Name), bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error starting upload of %s: %w", file.Path, err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("starting upload of %s failed with status %d: %s", file.Path, resp.StatusCode, string(bodyBytes))
+	}
+
+	var createResponse createUploadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&createResponse); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	partSize := createResponse.PartSize
+	if partSize <= 0 || partSize > MaxUploadPartSize {
+		partSize = UploadPartSize
+	}
+
+	return &models.UploadSession{
+		UploadID:   createResponse.UploadID,
+		ProjectID:  projectID,
+		Collection: collection.Name,
+		Path:       file.Path,
+		Hash:       file.Hash,
+		Size:       file.Size,
+		PartSize:   partSize,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// uploadExists reports whether the server still has an upload session
+func (c *Client) uploadExists(ctx context.Context, projectID, collectionName, uploadID, token string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.uploadURL(projectID, collectionName, uploadID), nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return false, fmt.Errorf("error checking upload session: %w", err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusGone:
+		return false, nil
+	default:
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("checking upload session failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+}
+
+// uploadPart sends one part of a chunked upload with its SHA-256 checksum,
+// which the server verifies before acknowledging the part
+func (c *Client) uploadPart(ctx context.Context, projectID, collectionName, uploadID, path string, number int, data []byte, checksum, token string) error {
+	url := c.uploadURL(projectID, collectionName, uploadID, "parts", fmt.Sprint(number))
+	attempt := c.attempts()
+	newBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(countReads(bytes.NewReader(data), path, attempt())), nil
+	}
+	body, _ := newBody()
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.ContentLength = int64(len(data))
+	req.GetBody = newBody
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Part-SHA256", checksum)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error uploading part %d: %w", number, err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("uploading part %d failed with status %d: %s", number, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// completeUpload asks the server to assemble the uploaded parts into the file
+func (c *Client) completeUpload(ctx context.Context, projectID, collectionName string, session *models.UploadSession, token string) (*UploadedFile, error) {
+	parts := make([]completedPart, 0, len(session.Parts))
+	for number := 1; number <= session.PartCount(); number++ {
+		for _, part := range session.Parts {
+			if part.Number == number {
+				parts = append(parts, completedPart{PartNumber: part.Number, SHA256: part.SHA256})
+				break
+			}
+		}
+	}
+
+	requestBody, err := json.Marshal(completeUploadRequest{Parts: parts})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling completion request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.uploadURL(projectID, collectionName, session.UploadID, "complete"), bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	setIdempotencyKey(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error completing upload of %s: %w", session.Path, err)
+	}
+	defer safelyCloseResponseBody(resp.Body)
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("completing upload of %s failed with status %d: %s", session.Path, resp.StatusCode, string(bodyBytes))
+	}
+
+	var uploaded UploadedFile
+	if err := json.NewDecoder(resp.Body).Decode(&uploaded); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if uploaded.Path == "" {
+		uploaded.Path = session.Path
+	}
+	return &uploaded, nil
+}
+
+// abortUpload tells the server to discard an upload session. Failures are
+// ignored, since the server expires abandoned sessions on its own.
+func (c *Client) abortUpload(ctx context.Context, projectID, collectionName, uploadID, token string) {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.uploadURL(projectID, collectionName, uploadID), nil)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return
+	}
+	safelyCloseResponseBody(resp.Body)
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadIgnoresOversizedPartSizeFromServer(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	server.partSize = 1 << 40
+	const big = ChunkedUploadThreshold + 1
+	file := writeTestFile(t, repoRoot, "big.bin", big, "hash-big")
+
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	if server.received["big.bin"] != big {
+		t.Errorf("server received %d of %d bytes", server.received["big.bin"], int64(big))
+	}
+	if server.largestPart != UploadPartSize {
+		t.Errorf("largest part sent was %d bytes, want %d", server.largestPart, UploadPartSize)
+	}
+}
+
+func TestUploadDiscardsSessionWithOversizedPartSize(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	const big = ChunkedUploadThreshold + 1
+	file := writeTestFile(t, repoRoot, "big.bin", big, "hash-big")
+	info, err := os.Stat(filepath.Join(repoRoot, file.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A session file the server still knows, but whose part size was tampered with
+	server.uploads["recorded"] = "big.bin"
+	err = models.NewUploadStore(repoRoot).Save(&models.UploadSession{
+		UploadID:   "recorded",
+		ProjectID:  testProjectID,
+		Collection: testCollection.Name,
+		Path:       file.Path,
+		Hash:       file.Hash,
+		Size:       file.Size,
+		ModTime:    info.ModTime(),
+		PartSize:   1 << 40,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	if len(server.uploads) != 2 {
+		t.Errorf("%d upload sessions on the server, want the recorded one and a new one", len(server.uploads))
+	}
+	if server.largestPart != UploadPartSize {
+		t.Errorf("largest part sent was %d bytes, want %d", server.largestPart, UploadPartSize)
+	}
+}
+
+// pushAfterFailedPart pushes a file of four parts while the server refuses its
+// third part, and returns the file and the part numbers the server received
+func pushAfterFailedPart(t *testing.T, server *testServer, client *Client, repoRoot string) *models.File {
+	t.Helper()
+
+	server.partSize = ChunkedUploadThreshold / 4
+	server.failPart = 3
+	file := writeTestFile(t, repoRoot, "big.bin", ChunkedUploadThreshold, "hash-big")
+
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Path != file.Path {
+		t.Fatalf("errors: %v, want big.bin to fail", resp.Errors)
+	}
+	if got := fmt.Sprint(server.partNumbers); got != "[1 2]" {
+		t.Fatalf("parts received before the failure: %s, want [1 2]", got)
+	}
+	return file
+}
+
+func TestUploadResumesAfterFailedPart(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	file := pushAfterFailedPart(t, server, client, repoRoot)
+
+	server.partNumbers = nil
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 || len(resp.UploadedFiles) != 1 {
+		t.Fatalf("uploaded %v with errors %v", resp.UploadedFiles, resp.Errors)
+	}
+
+	// Only the parts after the last acknowledged one are sent again
+	if got := fmt.Sprint(server.partNumbers); got != "[3 4]" {
+		t.Errorf("parts sent when resuming: %s, want [3 4]", got)
+	}
+	if len(server.uploads) != 1 {
+		t.Errorf("%d upload sessions started, want 1", len(server.uploads))
+	}
+	if server.received[file.Path] != ChunkedUploadThreshold {
+		t.Errorf("server received %d of %d bytes", server.received[file.Path], int64(ChunkedUploadThreshold))
+	}
+	if entries, _ := os.ReadDir(filepath.Join(repoRoot, ".hhx", "uploads")); len(entries) != 0 {
+		t.Errorf("%d upload sessions left recorded after the upload completed", len(entries))
+	}
+}
+
+func TestUploadStartsOverWhenFileModified(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+
+	file := pushAfterFailedPart(t, server, client, repoRoot)
+
+	// Rewritten in place with the same size
+	modified := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(repoRoot, file.Path), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	server.partNumbers = nil
+	resp, err := client.PushFilesToProjectCollection(context.Background(), repoRoot, []*models.File{file}, testProjectID, testCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+
+	if got := fmt.Sprint(server.partNumbers); got != "[1 2 3 4]" {
+		t.Errorf("parts sent after the file was modified: %s, want [1 2 3 4]", got)
+	}
+	if len(server.uploads) != 2 {
+		t.Errorf("%d upload sessions started, want a new one for the modified file", len(server.uploads))
+	}
+}
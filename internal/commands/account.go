@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/charmbracelet/x/term"
 	"github.com/spf13/cobra"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
 	"os"
@@ -92,8 +91,8 @@ var accountCreateCmd = &cobra.Command{
 			phone = scanner.Text()
 		}
 
-		client := api.NewClient(serverURL, tokenStore)
-		authResult, err := client.Register(email, password, name, phone)
+		client := newClient(serverURL, tokenStore)
+		authResult, err := client.Register(cmd.Context(), email, password, name, phone)
 		if err != nil {
 			fmt.Println("Account creation failed:", err)
 			return nil
@@ -160,8 +159,8 @@ var accountLoginCmd = &cobra.Command{
 		fmt.Println() // Add a newline after password input
 
 		password := string(passwordBytes)
-		client := api.NewClient(serverURL, tokenStore)
-		authResult, err := client.Login(email, password)
+		client := newClient(serverURL, tokenStore)
+		authResult, err := client.Login(cmd.Context(), email, password)
 		if err != nil {
 			fmt.Println("Login failed:", err)
 			return nil
@@ -199,8 +198,8 @@ var accountLogoutCmd = &cobra.Command{
 		}
 
 		tokenStore := models.NewTokenStore(globalConfigDir)
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		if err := client.Logout(); err != nil {
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		if err := client.Logout(cmd.Context()); err != nil {
 			fmt.Println("Error during logout:", err)
 			return nil
 		}
@@ -294,8 +293,8 @@ var accountDetailsCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		userDetails, err := client.GetUserDetails()
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		userDetails, err := client.GetUserDetails(cmd.Context())
 		if err != nil {
 			fmt.Println("Error fetching account details:", err)
 			return nil
@@ -348,9 +347,9 @@ var accountUpdateCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
+		client := newClient(globalConfig.ServerURL, tokenStore)
 
-		userDetails, err := client.GetUserDetails()
+		userDetails, err := client.GetUserDetails(cmd.Context())
 		if err != nil {
 			fmt.Println("Error fetching account details:", err)
 			return nil
@@ -457,7 +456,7 @@ var accountUpdateCmd = &cobra.Command{
 			}
 		}
 
-		result, err := client.UpdateAccount(updateRequest)
+		result, err := client.UpdateAccount(cmd.Context(), updateRequest)
 		if err != nil {
 			fmt.Println("Error updating account:", err)
 			return nil
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"hhx/internal/util"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local object cache",
+	Long: `Manage the cache of file contents kept by their SHA-256 hash.
+
+Push and pull add the files they transfer to the cache, so diff, restore and
+pulling a version that was seen before work without downloading anything.
+The cache lives in each repository's .hhx/objects by default, or in ~/.hhx/cache
+shared by every repository:
+
+  hhx config set --object-cache shared
+  hhx config set --cache-max-size 20GB
+
+Once the cache grows over its maximum size, the least recently used files are
+evicted.`,
+}
+
+// cacheStatusCmd represents the cache status command
+var cacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the size of the object cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		objects, err := cacheObjectStore()
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		usage, err := objects.Usage()
+		if err != nil {
+			fmt.Println("error reading object cache:", err)
+			return nil
+		}
+
+		mode := objectCacheMode()
+		fmt.Printf("Location:  %s\n", objects.Dir())
+		if mode == config.ObjectCacheOff {
+			fmt.Println("Mode:      off (push and pull don't add files)")
+		} else {
+			fmt.Printf("Mode:      %s\n", mode)
+		}
+		fmt.Printf("Objects:   %d\n", usage.Objects)
+		if objects.MaxSize() > 0 {
+			fmt.Printf("Size:      %s of %s (%.0f%%)\n", util.FormatSize(usage.Size), util.FormatSize(objects.MaxSize()),
+				float64(usage.Size)/float64(objects.MaxSize())*100)
+		} else {
+			fmt.Printf("Size:      %s (no limit)\n", util.FormatSize(usage.Size))
+		}
+
+		return nil
+	},
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict files from the object cache",
+	Long: `Evict the least recently used files until the cache fits in its maximum size.
+
+Use --max-size to prune to a different size, or --all to empty the cache. Files
+evicted from the cache are downloaded again when they are next needed.`,
+	Example: `  hhx cache prune                 # Trim the cache to the configured maximum size
+  hhx cache prune --max-size 5GB  # Trim the cache to 5 GB
+  hhx cache prune --all           # Empty the cache`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		maxSizeFlag, _ := cmd.Flags().GetString("max-size")
+
+		objects, err := cacheObjectStore()
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		var maxSize int64
+		switch {
+		case all:
+		case maxSizeFlag != "":
+			maxSize, err = util.ParseSize(maxSizeFlag)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return nil
+			}
+		case objects.MaxSize() > 0:
+			maxSize = objects.MaxSize()
+		default:
+			fmt.Println("Error: no maximum cache size is set. Use --max-size or --all, or set one with 'hhx config set --cache-max-size'")
+			return nil
+		}
+
+		result, err := objects.Prune(maxSize)
+		if err != nil {
+			fmt.Println("error pruning object cache:", err)
+			return nil
+		}
+
+		fmt.Printf("Evicted %d objects (%s) from %s\n", result.Removed, util.FormatSize(result.Freed), objects.Dir())
+		if result.TempFilesFreed > 0 {
+			fmt.Printf("Removed %s of leftover temporary files\n", util.FormatSize(result.TempFilesFreed))
+		}
+		fmt.Printf("%d objects (%s) remain\n", result.Remaining, util.FormatSize(result.RemainingSize))
+		return nil
+	},
+}
+
+// cacheObjectStore opens the object cache the cache commands work on. The
+// repository's own cache needs a repository; the shared cache doesn't.
+func cacheObjectStore() (*models.ObjectStore, error) {
+	if objectCacheMode() == config.ObjectCacheShared {
+		return openObjectStore(""), nil
+	}
+
+	repoRoot, err := findRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	return openObjectStore(repoRoot), nil
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatusCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+
+	cachePruneCmd.Flags().String("max-size", "", "Size to trim the cache to, e.g. 5GB (defaults to the configured maximum)")
+	cachePruneCmd.Flags().Bool("all", false, "Evict every object")
+}
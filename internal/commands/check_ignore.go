@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var checkIgnoreCmd = &cobra.Command{
+	Use:   "check-ignore <path>...",
+	Short: "Explain why a path is ignored",
+	Long: `Show the .hhxignore rule that decides whether each path is ignored.
+
+For every path the matching rule is printed as <source>:<line>:<pattern>. Rules
+starting with '!' re-include a path that an earlier rule ignored.`,
+	Example: `  hhx check-ignore venv/bin/python
+  hhx check-ignore wandb/ outputs/run-1.log`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Println("error getting current directory:", err)
+			return nil
+		}
+
+		matcher := models.NewIgnoreMatcher(repoRoot)
+
+		for _, arg := range args {
+			path := arg
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(cwd, path)
+			}
+
+			relPath, err := filepath.Rel(repoRoot, path)
+			if err != nil || !filepath.IsLocal(relPath) {
+				fmt.Printf("error: '%s' is outside the repository\n", arg)
+				continue
+			}
+
+			// Paths that don't exist are treated as directories if written with a trailing slash
+			isDir := strings.HasSuffix(arg, "/")
+			if info, err := os.Stat(path); err == nil {
+				isDir = info.IsDir()
+			}
+
+			rule := matcher.Match(relPath, isDir)
+			switch {
+			case rule == nil:
+				fmt.Printf("%s: not ignored\n", arg)
+			case rule.Negate:
+				fmt.Printf("%s:%d:%s\t%s (not ignored)\n", rule.Source, rule.Line, rule.Pattern, arg)
+			default:
+				fmt.Printf("%s:%d:%s\t%s\n", rule.Source, rule.Line, rule.Pattern, arg)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkIgnoreCmd)
+}
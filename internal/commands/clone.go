@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"hhx/internal/ui"
+	"hhx/internal/util"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <project> [directory]",
+	Short: "Create a repository from an existing remote project",
+	Long: `Create a new hhx repository linked to an existing remote project.
+
+The remote collections are imported into the index and their files are downloaded,
+so the new working tree matches the remote project.`,
+	Example: `  hhx clone myproject                  # Clone into ./myproject
+  hhx clone myproject data             # Clone into ./data
+  hhx clone myproject --remote=prod    # Name the remote 'prod' instead of 'origin'`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		projectName := args[0]
+		remoteName, _ := cmd.Flags().GetString("remote")
+		if remoteName == "" {
+			remoteName = "origin"
+		}
+
+		dir := projectName
+		if len(args) == 2 {
+			dir = args[1]
+		}
+
+		repoRoot, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Println("Error resolving directory:", err)
+			return nil
+		}
+
+		// Refuse to clone into a non-empty directory
+		if entries, err := os.ReadDir(repoRoot); err == nil && len(entries) > 0 {
+			fmt.Printf("Error: destination '%s' already exists and is not empty\n", dir)
+			return nil
+		}
+
+		// Load global config and authenticate
+		globalConfigDir, err := config.GetGlobalConfigDir()
+		if err != nil {
+			fmt.Println("Error getting global config directory:", err)
+			return nil
+		}
+
+		globalConfig, err := config.LoadGlobalConfig()
+		if err != nil {
+			fmt.Println("Error loading global config:", err)
+			return nil
+		}
+
+		tokenStore := models.NewTokenStore(globalConfigDir)
+		token, err := tokenStore.GetToken()
+		if err != nil || token == "" {
+			fmt.Println("You are not logged in. Please log in first with 'hhx account login'.")
+			return nil
+		}
+
+		client := newClient(globalConfig.ServerURL, tokenStore)
+
+		// Look up the project
+		var project *models.Project
+		if util.IsUUID(projectName) {
+			project, err = client.GetProject(ctx, projectName)
+		} else {
+			project, err = client.GetProjectByName(ctx, projectName)
+		}
+		if err != nil {
+			fmt.Printf("Error: couldn't find project '%s' on the server: %v\n", projectName, err)
+			return nil
+		}
+
+		// Import the remote collections
+		remoteCollections, err := client.ListCollections(ctx)
+		if err != nil {
+			fmt.Println("Error listing remote collections:", err)
+			return nil
+		}
+
+		hhxDir := filepath.Join(repoRoot, ".hhx")
+		if err := os.MkdirAll(hhxDir, 0755); err != nil {
+			fmt.Println("Error creating .hhx directory:", err)
+			return nil
+		}
+
+		indexPath := filepath.Join(hhxDir, "index")
+		index := models.NewIndex(repoRoot)
+
+		for _, collection := range remoteCollections {
+			if err := index.AddCollection(collection); err != nil {
+				color.Yellow("Warning: skipping collection '%s': %v\n", collection.Name, err)
+			}
+		}
+
+		collections := index.GetCollections()
+		if len(collections) == 0 {
+			fmt.Println("Remote project has no collections, creating collection 'default'")
+			defaultCollection := &models.Collection{
+				Name: "default",
+				Type: models.CollectionTypeBucket,
+				Path: "default",
+			}
+			if err := index.AddCollection(defaultCollection); err != nil {
+				fmt.Println("Error creating default collection:", err)
+				return nil
+			}
+		}
+
+		repoConfig := &config.RepoConfig{
+			Remotes: map[string]string{
+				remoteName: globalConfig.ServerURL,
+			},
+			CurrentRemote: remoteName,
+			IndexPath:     indexPath,
+			ProjectID:     project.ID,
+			ProjectName:   project.Name,
+		}
+
+		if err := repoConfig.Save(filepath.Join(hhxDir, "config.json")); err != nil {
+			fmt.Println("Error creating repository config:", err)
+			return nil
+		}
+
+		if err := index.Save(indexPath); err != nil {
+			fmt.Println("Error creating index file:", err)
+			return nil
+		}
+
+		fmt.Printf("Cloning project '%s' (ID: %s) into '%s'...\n", project.Name, project.ID, dir)
+		startTime := time.Now()
+
+		// Download the contents of every collection, reusing and filling the object cache
+		objects := syncObjectStore(repoRoot)
+		transfer := ui.NewTransfer()
+		client.SetProgress(transfer)
+		view := ui.StartProgress(transfer, "Cloning")
+		total := &pullResult{}
+		for _, collection := range collections {
+			if ctx.Err() != nil {
+				break
+			}
+			view.Printf("Collection '%s':\n", collection.Name)
+
+			remoteFiles, err := client.ListProjectCollectionFiles(ctx, project.ID, collection)
+			if err != nil {
+				view.Println(color.RedString("  error listing files: %v", err))
+				total.Failed++
+				continue
+			}
+
+			result := pullFiles(ctx, client, index, objects, view, repoRoot, collection.Name, remoteFiles, false)
+			total.Downloaded += result.Downloaded
+			total.Bytes += result.Bytes
+			total.UpToDate += result.UpToDate
+			total.Skipped += result.Skipped
+			total.Failed += result.Failed
+		}
+		view.Stop()
+
+		if err := index.Save(indexPath); err != nil {
+			fmt.Println("Error saving index:", err)
+			return nil
+		}
+
+		duration := time.Since(startTime).Round(time.Millisecond)
+		fmt.Printf("\nCloned %d collections, %d files (%s) in %s\n",
+			len(collections),
+			total.Downloaded,
+			util.FormatSize(total.Bytes),
+			duration,
+		)
+		if total.Failed > 0 {
+			color.Red("%d files or collections could not be downloaded; run 'hhx pull' to retry\n", total.Failed)
+		}
+		printPullInterrupted(ctx)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().String("remote", "origin", "Name of the remote")
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// loginTestHome gives the test a home directory whose global config points
+// at the stand-in server, logged in
+func loginTestHome(t *testing.T, s *testServer) *config.Config {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	cfg := &config.Config{ServerURL: s.URL}
+	if err := config.SaveGlobalConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	configDir, err := config.GetGlobalConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.NewTokenStore(configDir).SaveToken("test-token"); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// runCommand runs hhx with the given arguments in dir, as main does, and
+// resets the flags it set once the test ends
+func runCommand(t *testing.T, cfg *config.Config, dir string, args ...string) error {
+	t.Helper()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(cwd)
+		globalConfig = nil
+		resetFlags(rootCmd)
+	})
+
+	rootCmd.SetArgs(args)
+	return Execute(context.Background(), cfg)
+}
+
+// captureStdout returns what f prints to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+
+	f()
+	w.Close()
+	return <-output
+}
+
+// resetFlags sets the flags of cmd and its subcommands back to their defaults
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = slice.Replace(nil)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestCloneImportsCollectionsAndFiles(t *testing.T) {
+	server := newTestServer(t, "data", "models")
+	cfg := loginTestHome(t, server)
+	server.list("data", server.testRemoteFile("data/train.csv", "a,b", "a,b"))
+	server.list("models", server.testRemoteFile("models/net.bin", "weights", "weights"))
+
+	dir := filepath.Join(t.TempDir(), "clone")
+	if err := runCommand(t, cfg, filepath.Dir(dir), "clone", testProjectName, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var repoConfig config.RepoConfig
+	if err := json.Unmarshal([]byte(readTestFile(t, dir, ".hhx/config.json")), &repoConfig); err != nil {
+		t.Fatal(err)
+	}
+	if repoConfig.ProjectID != testProjectID || repoConfig.Remotes["origin"] != server.URL {
+		t.Errorf("repository config: %+v, want project %s at origin %s", repoConfig, testProjectID, server.URL)
+	}
+
+	index, err := models.LoadIndex(filepath.Join(dir, ".hhx", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collections := index.GetCollections(); len(collections) != 2 {
+		t.Errorf("%d collections imported, want 2", len(collections))
+	}
+	for path, content := range map[string]string{"data/train.csv": "a,b", "models/net.bin": "weights"} {
+		if got := readTestFile(t, dir, path); got != content {
+			t.Errorf("%s contains %q, want %q", path, got, content)
+		}
+		if _, ok := index.GetSyncedFile(path); !ok {
+			t.Errorf("%s not recorded as synced", path)
+		}
+	}
+}
+
+func TestCloneRefusesNonEmptyDirectory(t *testing.T) {
+	server := newTestServer(t, "data")
+	cfg := loginTestHome(t, server)
+	server.list("data", server.testRemoteFile("data/train.csv", "a,b", "a,b"))
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "notes.txt", "mine")
+	if err := runCommand(t, cfg, dir, "clone", testProjectName, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".hhx")); !os.IsNotExist(err) {
+		t.Errorf("clone created a repository in a non-empty directory: %v", err)
+	}
+}
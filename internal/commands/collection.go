@@ -236,6 +236,14 @@ var collectionCreateCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -294,6 +302,14 @@ var collectionRemoveCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -347,6 +363,14 @@ var collectionSetDefaultCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -1,15 +1,10 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
-	"io"
-	"net/http"
 	"time"
 )
 
@@ -43,6 +38,14 @@ var collectionLinkCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -81,13 +84,16 @@ var collectionLinkCmd = &cobra.Command{
 			}
 		}
 
-		client := api.NewClient(remoteURL, tokenStore)
+		client := newClient(remoteURL, tokenStore)
 
 		// Check if the project exists and get its ID
-		projectID := repoConfig.ProjectID
+		projectID := repoConfig.RemoteProjects[repoConfig.CurrentRemote]
+		if projectID == "" {
+			projectID = repoConfig.ProjectID
+		}
 		if projectID == "" && repoConfig.ProjectName != "" {
 			// Try to find project ID from name
-			projects, err := client.ListProjects()
+			projects, err := client.ListProjects(cmd.Context())
 			if err != nil {
 				fmt.Println("Error listing projects:", err)
 				return nil
@@ -123,102 +129,21 @@ var collectionLinkCmd = &cobra.Command{
 		// Check if the remote collection exists
 		fmt.Printf("Checking if collection '%s' exists on the remote server...\n", remoteBucketName)
 
-		// Call the API to check collections
-		collectionsURL := fmt.Sprintf("%s/%s/projects/%s/collections", remoteURL, api.API_VERSION, projectID)
-
-		token, err := tokenStore.GetToken()
-		if err != nil {
-			fmt.Println("Error getting token:", err)
-			return nil
-		}
-
-		req, err := http.NewRequest("GET", collectionsURL, nil)
-		if err != nil {
-			fmt.Println("Error creating request:", err)
-			return nil
-		}
-
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		httpClient := &http.Client{}
-		resp, err := httpClient.Do(req)
+		collectionExists, err := client.ProjectCollectionExists(cmd.Context(), projectID, remoteBucketName)
 		if err != nil {
 			fmt.Println("Error checking remote collections:", err)
 			return nil
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			fmt.Printf("Failed to check collections with status %d: %s\n", resp.StatusCode, string(bodyBytes))
-			return nil
-		}
-
-		var collectionsResponse struct {
-			Collections []struct {
-				Name string `json:"name"`
-				Type string `json:"type"`
-			} `json:"collections"`
-		}
-
-		if err := json.NewDecoder(resp.Body).Decode(&collectionsResponse); err != nil {
-			fmt.Println("Error parsing collections response:", err)
-			return nil
-		}
-
-		// Check if our collection exists in the remote collections
-		collectionExists := false
-		for _, c := range collectionsResponse.Collections {
-			if c.Name == remoteBucketName {
-				collectionExists = true
-				break
-			}
-		}
 
 		if !collectionExists {
 			if createIfMissing {
 				fmt.Printf("Collection '%s' doesn't exist on the server. Creating it...\n", remoteBucketName)
 
 				// Create the collection on the server
-				createURL := fmt.Sprintf("%s/%s/projects/%s/collections", remoteURL, api.API_VERSION, projectID)
-
-				createRequest := struct {
-					Name string `json:"name"`
-					Type string `json:"type"`
-					Path string `json:"path"`
-				}{
-					Name: remoteBucketName,
-					Type: string(collection.Type),
-					Path: collection.Path,
-				}
-
-				jsonData, err := json.Marshal(createRequest)
-				if err != nil {
-					fmt.Println("Error preparing collection creation request:", err)
-					return nil
-				}
-
-				req, err := http.NewRequest("POST", createURL, bytes.NewBuffer(jsonData))
-				if err != nil {
-					fmt.Println("Error creating request:", err)
-					return nil
-				}
-
-				req.Header.Set("Authorization", "Bearer "+token)
-				req.Header.Set("Content-Type", "application/json")
-
-				resp, err := httpClient.Do(req)
-				if err != nil {
+				if err := client.CreateProjectCollection(cmd.Context(), projectID, remoteBucketName, collection.Type, collection.Path); err != nil {
 					fmt.Println("Error creating remote collection:", err)
 					return nil
 				}
-				defer resp.Body.Close()
-
-				if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-					bodyBytes, _ := io.ReadAll(resp.Body)
-					fmt.Printf("Failed to create collection with status %d: %s\n", resp.StatusCode, string(bodyBytes))
-					return nil
-				}
 
 				fmt.Printf("Remote collection '%s' created successfully\n", remoteBucketName)
 				collectionExists = true
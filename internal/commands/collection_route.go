@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+// collectionRouteCmd represents the collection route command
+var collectionRouteCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Route files to collections by path",
+	Long: `Manage rules that send files to collections based on their path.
+
+When pushing, a file tagged with 'hhx stage --collection' goes to that collection.
+Otherwise the first route whose pattern matches the file's path decides the
+collection, and files matching no route go to the default collection.`,
+}
+
+// collectionRouteAddCmd represents the collection route add command
+var collectionRouteAddCmd = &cobra.Command{
+	Use:   "add [pattern] [collection]",
+	Short: "Add a routing rule",
+	Long:  `Send files matching a glob pattern, relative to the repository root, to a collection.`,
+	Example: `  hhx collection route add 'checkpoints/**' models
+  hhx collection route add 'logs/**' metrics
+  hhx collection route add '*.parquet' tables`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pattern, collectionName := args[0], args[1]
+
+		// Find repository root
+		_, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		if err := index.AddRoute(pattern, collectionName); err != nil {
+			if err == models.ErrCollectionNotFound {
+				fmt.Println("Error: collection not found:", collectionName)
+			} else {
+				fmt.Println("error adding route:", err)
+			}
+			return nil
+		}
+
+		// Save index
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		fmt.Printf("Files matching '%s' will be pushed to collection '%s'.\n", pattern, collectionName)
+		return nil
+	},
+}
+
+// collectionRouteListCmd represents the collection route list command
+var collectionRouteListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List routing rules",
+	Long:  `List routing rules in the order they are checked.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		_, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		routes := index.GetRoutes()
+		if len(routes) == 0 {
+			fmt.Println("No routes defined. Add one with 'hhx collection route add'.")
+			return nil
+		}
+
+		fmt.Println("Routes:")
+		for _, route := range routes {
+			fmt.Printf("  %s -> %s\n", route.Pattern, route.Collection)
+		}
+
+		if index.DefaultCollection != "" {
+			fmt.Printf("\nOther files go to the default collection '%s'.\n", index.DefaultCollection)
+		}
+
+		return nil
+	},
+}
+
+// collectionRouteRemoveCmd represents the collection route remove command
+var collectionRouteRemoveCmd = &cobra.Command{
+	Use:   "remove [pattern]",
+	Short: "Remove a routing rule",
+	Long:  `Remove the routing rule with the given pattern.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pattern := args[0]
+
+		// Find repository root
+		_, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		if err := index.RemoveRoute(pattern); err != nil {
+			fmt.Println("error removing route:", err)
+			return nil
+		}
+
+		// Save index
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		fmt.Printf("Route '%s' removed.\n", pattern)
+		return nil
+	},
+}
+
+func init() {
+	collectionCmd.AddCommand(collectionRouteCmd)
+	collectionRouteCmd.AddCommand(collectionRouteAddCmd)
+	collectionRouteCmd.AddCommand(collectionRouteListCmd)
+	collectionRouteCmd.AddCommand(collectionRouteRemoveCmd)
+}
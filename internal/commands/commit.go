@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"hhx/internal/util"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Record a snapshot of the synced files",
+	Long: `Record an immutable snapshot of every file synced with the remote server.
+
+A snapshot lists the path, hash, size, remote URL and collection of each file, along
+with the author, time, message and the previous snapshot. Snapshots are stored in
+.hhx/snapshots and are never modified, so they can be used to tell exactly which
+files a run used. Staged changes that have not been pushed are not included.`,
+	Example: `  hhx commit -m "Training data for run 42"
+  hhx commit --allow-empty -m "Checkpoint before cleanup"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		message, _ := cmd.Flags().GetString("message")
+		allowEmpty, _ := cmd.Flags().GetBool("allow-empty")
+
+		if message == "" {
+			fmt.Println("Error: a message is required. Use -m to specify one")
+			return nil
+		}
+
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		store := models.NewSnapshotStore(repoRoot)
+		head, err := store.Head()
+		if err != nil {
+			fmt.Println("error reading snapshot head:", err)
+			return nil
+		}
+
+		snapshot := &models.Snapshot{
+			Parent:  head,
+			Author:  snapshotAuthor(),
+			Time:    time.Now().UTC(),
+			Message: message,
+			Files:   index.Manifest(),
+		}
+
+		// Refuse to record a snapshot identical to the previous one
+		if head != "" && !allowEmpty {
+			parent, err := store.Load(head)
+			if err != nil {
+				fmt.Println("error loading snapshot", models.ShortID(head), ":", err)
+				return nil
+			}
+			if snapshot.SameFiles(parent) {
+				fmt.Println("Error:", models.ErrNothingToCommit, "(no files changed since the last snapshot, use --allow-empty to commit anyway)")
+				return nil
+			}
+		}
+
+		if err := store.Save(snapshot); err != nil {
+			fmt.Println("error saving snapshot:", err)
+			return nil
+		}
+
+		var totalSize int64
+		for _, entry := range snapshot.Files {
+			totalSize += entry.Size
+		}
+
+		fmt.Printf("[%s] %s\n", snapshot.ShortID(), firstLine(snapshot.Message))
+		fmt.Printf(" %d files, %s\n", len(snapshot.Files), util.FormatSize(totalSize))
+
+		if pending := len(index.GetStagedFiles()) + len(index.GetStagedDeletions()); pending > 0 {
+			fmt.Printf("\nNote: %d staged changes have not been pushed and are not part of this snapshot.\n", pending)
+		}
+
+		return nil
+	},
+}
+
+// snapshotAuthor returns the name recorded as the author of new snapshots
+func snapshotAuthor() string {
+	if globalConfig != nil && globalConfig.Email != "" {
+		return globalConfig.Email
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
+
+// loadSnapshotStore returns the snapshot store of the current repository
+func loadSnapshotStore() (*models.SnapshotStore, error) {
+	repoRoot, err := findRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	return models.NewSnapshotStore(repoRoot), nil
+}
+
+// describeSnapshotError turns snapshot lookup errors into a message for users
+func describeSnapshotError(id string, err error) string {
+	switch {
+	case errors.Is(err, models.ErrSnapshotNotFound):
+		return fmt.Sprintf("Error: snapshot not found: %s", id)
+	case errors.Is(err, models.ErrAmbiguousSnapshot):
+		return fmt.Sprintf("Error: snapshot ID '%s' is ambiguous, use more characters", id)
+	default:
+		return fmt.Sprintf("error loading snapshot %s: %v", id, err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+
+	commitCmd.Flags().StringP("message", "m", "", "Message describing the snapshot")
+	commitCmd.Flags().Bool("allow-empty", false, "Record a snapshot even if no files changed since the last one")
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newCommandRepo creates a repository with the given index for commands to run
+// in, linked to the stand-in server's project if there is one, and returns
+// its root
+func newCommandRepo(t *testing.T, s *testServer, index func(repoRoot string) *models.Index) string {
+	t.Helper()
+
+	repoRoot := t.TempDir()
+	hhxDir := filepath.Join(repoRoot, ".hhx")
+	if err := os.Mkdir(hhxDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repoConfig := &config.RepoConfig{
+		Remotes:       map[string]string{},
+		CurrentRemote: "origin",
+		IndexPath:     filepath.Join(hhxDir, "index"),
+	}
+	if s != nil {
+		repoConfig.Remotes["origin"] = s.URL
+		repoConfig.ProjectID = testProjectID
+	}
+	if err := repoConfig.Save(filepath.Join(hhxDir, "config.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := index(repoRoot).Save(repoConfig.IndexPath); err != nil {
+		t.Fatal(err)
+	}
+	return repoRoot
+}
+
+// loadCommandIndex loads the index of a repository made by newCommandRepo
+func loadCommandIndex(t *testing.T, repoRoot string) *models.Index {
+	t.Helper()
+
+	index, err := models.LoadIndex(filepath.Join(repoRoot, ".hhx", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestCommitRecordsSyncedFiles(t *testing.T) {
+	repoRoot := newCommandRepo(t, nil, func(repoRoot string) *models.Index {
+		index := models.NewIndex(repoRoot)
+		index.RecordSynced(&models.File{Path: "a.txt", Hash: hashOf("a"), Size: 1, RemoteURL: "/files/a.txt"})
+		return index
+	})
+	store := models.NewSnapshotStore(repoRoot)
+
+	if err := runCommand(t, &config.Config{Email: "tester@example.com"}, repoRoot, "commit", "-m", "Training data"); err != nil {
+		t.Fatal(err)
+	}
+	log, err := store.Log()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 {
+		t.Fatalf("%d snapshots recorded, want 1", len(log))
+	}
+	snapshot := log[0]
+	if snapshot.Author != "tester@example.com" || snapshot.Message != "Training data" {
+		t.Errorf("snapshot by %q with message %q", snapshot.Author, snapshot.Message)
+	}
+	if len(snapshot.Files) != 1 || snapshot.Files[0].Path != "a.txt" || snapshot.Files[0].RemoteURL != "/files/a.txt" {
+		t.Errorf("snapshot files: %+v, want a.txt", snapshot.Files)
+	}
+
+	// Nothing changed since
+	if err := runCommand(t, nil, repoRoot, "commit", "-m", "Again"); err != nil {
+		t.Fatal(err)
+	}
+	if log, _ := store.Log(); len(log) != 1 {
+		t.Errorf("%d snapshots after committing unchanged files, want 1", len(log))
+	}
+
+	resetFlags(rootCmd)
+	if err := runCommand(t, nil, repoRoot, "commit", "--allow-empty", "-m", "Again"); err != nil {
+		t.Fatal(err)
+	}
+	if log, _ := store.Log(); len(log) != 2 || log[0].Parent != snapshot.ID {
+		t.Errorf("--allow-empty didn't record a snapshot on top of %s", snapshot.ShortID())
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	"hhx/internal/config"
+	"hhx/internal/util"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 var (
 	// Variables to hold flag values
-	serverURL string
+	serverURL    string
+	hashJobs     int
+	objectCache  string
+	cacheMaxSize string
+
+	retryAttempts  int
+	retryBaseDelay string
+	retryMaxDelay  string
 )
 
 var configCmd = &cobra.Command{
@@ -40,6 +49,24 @@ var configGetCmd = &cobra.Command{
 			if cfg.Email != "" {
 				fmt.Printf("Email: %s\n", cfg.Email)
 			}
+			if cfg.HashJobs > 0 {
+				fmt.Printf("Hash Jobs: %d\n", cfg.HashJobs)
+			}
+			if cfg.ObjectCache != "" {
+				fmt.Printf("Object Cache: %s\n", cfg.ObjectCache)
+			}
+			if cfg.CacheMaxSize > 0 {
+				fmt.Printf("Cache Max Size: %s\n", util.FormatSize(cfg.CacheMaxSize))
+			}
+			if cfg.RetryAttempts > 0 {
+				fmt.Printf("Retry Attempts: %d\n", cfg.RetryAttempts)
+			}
+			if cfg.RetryBaseDelay != "" {
+				fmt.Printf("Retry Base Delay: %s\n", cfg.RetryBaseDelay)
+			}
+			if cfg.RetryMaxDelay != "" {
+				fmt.Printf("Retry Max Delay: %s\n", cfg.RetryMaxDelay)
+			}
 			return nil
 		}
 
@@ -51,6 +78,22 @@ var configGetCmd = &cobra.Command{
 			fmt.Println(cfg.DefaultRepoPath)
 		case "email":
 			fmt.Println(cfg.Email)
+		case "hash-jobs":
+			fmt.Println(cfg.HashJobs)
+		case "object-cache":
+			if cfg.ObjectCache == "" {
+				fmt.Println(config.ObjectCacheRepo)
+			} else {
+				fmt.Println(cfg.ObjectCache)
+			}
+		case "cache-max-size":
+			fmt.Println(cfg.CacheMaxSize)
+		case "retry-attempts":
+			fmt.Println(retryPolicy(cfg).MaxAttempts)
+		case "retry-base-delay":
+			fmt.Println(retryPolicy(cfg).BaseDelay)
+		case "retry-max-delay":
+			fmt.Println(retryPolicy(cfg).MaxDelay)
 		default:
 			return fmt.Errorf("unknown configuration key: %s", args[0])
 		}
@@ -79,6 +122,69 @@ var configSetCmd = &cobra.Command{
 			configUpdated = true
 		}
 
+		if cmd.Flags().Changed("hash-jobs") {
+			if hashJobs < 0 {
+				return fmt.Errorf("hash jobs must not be negative")
+			}
+			oldJobs := cfg.HashJobs
+			cfg.HashJobs = hashJobs
+			fmt.Printf("Hash jobs updated: %d -> %d\n", oldJobs, hashJobs)
+			configUpdated = true
+		}
+
+		if cmd.Flags().Changed("object-cache") {
+			switch objectCache {
+			case config.ObjectCacheRepo, config.ObjectCacheShared, config.ObjectCacheOff:
+			default:
+				return fmt.Errorf("object cache must be one of %s, %s or %s",
+					config.ObjectCacheRepo, config.ObjectCacheShared, config.ObjectCacheOff)
+			}
+			fmt.Printf("Object cache updated: %s\n", objectCache)
+			cfg.ObjectCache = objectCache
+			configUpdated = true
+		}
+
+		if cmd.Flags().Changed("cache-max-size") {
+			size, err := util.ParseSize(cacheMaxSize)
+			if err != nil {
+				return err
+			}
+			cfg.CacheMaxSize = size
+			if size == 0 {
+				fmt.Println("Cache max size updated: no limit")
+			} else {
+				fmt.Printf("Cache max size updated: %s\n", util.FormatSize(size))
+			}
+			configUpdated = true
+		}
+
+		if cmd.Flags().Changed("retry-attempts") {
+			if retryAttempts < 0 {
+				return fmt.Errorf("retry attempts must not be negative")
+			}
+			cfg.RetryAttempts = retryAttempts
+			fmt.Printf("Retry attempts updated: %d\n", retryAttempts)
+			configUpdated = true
+		}
+
+		if cmd.Flags().Changed("retry-base-delay") {
+			if err := validateRetryDelay(retryBaseDelay); err != nil {
+				return err
+			}
+			cfg.RetryBaseDelay = retryBaseDelay
+			fmt.Printf("Retry base delay updated: %s\n", retryBaseDelay)
+			configUpdated = true
+		}
+
+		if cmd.Flags().Changed("retry-max-delay") {
+			if err := validateRetryDelay(retryMaxDelay); err != nil {
+				return err
+			}
+			cfg.RetryMaxDelay = retryMaxDelay
+			fmt.Printf("Retry max delay updated: %s\n", retryMaxDelay)
+			configUpdated = true
+		}
+
 		// Save configuration if it was updated
 		if configUpdated {
 			if err := config.SaveGlobalConfig(cfg); err != nil {
@@ -184,6 +290,18 @@ var configPathsCmd = &cobra.Command{
 	},
 }
 
+// validateRetryDelay checks a retry delay setting is a positive duration
+func validateRetryDelay(value string) error {
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid delay %q: use a duration such as 500ms or 30s", value)
+	}
+	if delay <= 0 {
+		return fmt.Errorf("retry delays must be positive")
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(configGetCmd)
@@ -192,6 +310,12 @@ func init() {
 	configCmd.AddCommand(configPathsCmd)
 
 	configSetCmd.Flags().StringVar(&serverURL, "server-url", "", "Set API server URL")
+	configSetCmd.Flags().IntVar(&hashJobs, "hash-jobs", 0, "Set the number of files hashed in parallel (0 for one per CPU)")
+	configSetCmd.Flags().StringVar(&objectCache, "object-cache", "", "Set where file contents are cached: repo, shared (~/.hhx/cache) or off")
+	configSetCmd.Flags().StringVar(&cacheMaxSize, "cache-max-size", "", "Set the maximum size of the object cache, e.g. 20GB (0 for no limit)")
+	configSetCmd.Flags().IntVar(&retryAttempts, "retry-attempts", 0, "Set how many times each API request is attempted (1 disables retries, 0 for the default)")
+	configSetCmd.Flags().StringVar(&retryBaseDelay, "retry-base-delay", "", "Set the delay before the first retry of a failed request, e.g. 500ms")
+	configSetCmd.Flags().StringVar(&retryMaxDelay, "retry-max-delay", "", "Set the longest delay between retries, e.g. 30s")
 
 	configInitCmd.Flags().StringVar(&serverURL, "server-url", "", "Set API server URL")
 }
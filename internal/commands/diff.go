@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/diff"
+	"hhx/internal/models"
+	"hhx/internal/util"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [file/directory]",
+	Short: "Show changes since the last sync",
+	Long: `Compare working files against the version last synced with the remote server.
+
+Text files are shown as a unified diff, and binary files and files over 16 MB as a
+size and hash summary.
+CSV and JSONL files in a table collection are compared row by row, matching rows on
+the primary key columns of the collection's schema.
+
+The synced version is read from the local object cache in .hhx/objects when it is
+there, and otherwise downloaded from the server and cached.`,
+	Example: `  hhx diff                # Show all changes
+  hhx diff data/          # Show changes in a directory
+  hhx diff -U 10 train.py # Show 10 lines of context`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		contextLines, _ := cmd.Flags().GetInt("unified")
+
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		pathspecs, err := resolvePathspecs(repoRoot, args)
+		if err != nil {
+			fmt.Println("error:", err)
+			return nil
+		}
+
+		fetcher := newObjectFetcher(repoRoot, repoConfig)
+
+		for _, entry := range index.Tracked() {
+			if !matchesPathspecs(entry.Path, pathspecs) {
+				continue
+			}
+
+			fullPath := filepath.Join(repoRoot, filepath.FromSlash(entry.Path))
+
+			// Hash the working version, skipping files that haven't changed
+			var working *workingFile
+			info, err := os.Stat(fullPath)
+			if err != nil && !os.IsNotExist(err) {
+				fmt.Println("error reading", entry.Path, ":", err)
+				continue
+			}
+			if err == nil {
+				if tracked, ok := index.GetTrackedFile(entry.Path); ok && tracked.Stat.Matches(info) {
+					continue
+				}
+
+				working, err = readWorkingFile(fullPath)
+				if err != nil {
+					fmt.Println("error reading", entry.Path, ":", err)
+					continue
+				}
+				if working.hash == entry.Hash {
+					continue
+				}
+			}
+
+			// Binary and large files are summarized from the index, without
+			// reading either version in full
+			tooLarge := entry.Size > maxTextDiffSize || working != nil && working.size > maxTextDiffSize
+			if tooLarge || working != nil && diff.IsBinary(working.head) {
+				color.New(color.Bold).Printf("diff --hhx a/%s b/%s\n", entry.Path, entry.Path)
+				printDiff(summarizeChange(entry, working, tooLarge))
+				continue
+			}
+
+			// Read the synced version from the cache, downloading it if needed
+			objectPath, err := fetcher.fetch(cmd.Context(), entry.RemoteURL, entry.Hash)
+			if err != nil {
+				fmt.Printf("error fetching the synced version of %s: %v\n", entry.Path, err)
+				continue
+			}
+
+			oldContent, err := os.ReadFile(objectPath)
+			if err != nil {
+				fmt.Printf("error reading the synced version of %s: %v\n", entry.Path, err)
+				continue
+			}
+
+			var newContent []byte
+			if working != nil {
+				newContent, err = os.ReadFile(fullPath)
+				if err != nil {
+					fmt.Println("error reading", entry.Path, ":", err)
+					continue
+				}
+			}
+
+			oldName := "a/" + entry.Path
+			newName := "b/" + entry.Path
+			if working == nil {
+				newName = "/dev/null"
+			}
+
+			color.New(color.Bold).Printf("diff --hhx a/%s b/%s\n", entry.Path, entry.Path)
+			if diff.IsBinary(oldContent) {
+				printDiff(summarizeChange(entry, working, false))
+				continue
+			}
+			printDiff(diffContents(index, entry, oldName, newName, oldContent, newContent, working == nil, contextLines))
+		}
+
+		return nil
+	},
+}
+
+// maxTextDiffSize is the largest file compared line by line or row by row;
+// larger files are summarized by their size and hash
+const maxTextDiffSize = 16 << 20
+
+// workingFile is what diff needs of the working version of a file
+type workingFile struct {
+	size int64
+	hash string
+
+	// Start of the file, enough to tell whether it is binary
+	head []byte
+}
+
+// readWorkingFile hashes a working file and keeps its first block, without
+// reading the whole file into memory
+func readWorkingFile(path string) (*workingFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, diff.BinarySniffLength+utf8.UTFMax)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	h := sha256.New()
+	h.Write(head)
+	rest, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workingFile{size: int64(n) + rest, hash: hex.EncodeToString(h.Sum(nil)), head: head}, nil
+}
+
+// summarizeChange describes a change to a binary or large file by the size and
+// hash recorded for the synced version and those of the working version, which
+// is nil if the file was deleted
+func summarizeChange(entry *models.ManifestEntry, working *workingFile, tooLarge bool) string {
+	kind := "Binary file"
+	if tooLarge {
+		kind = "Large file"
+	}
+
+	if working == nil {
+		return fmt.Sprintf("%s deleted\n- size: %s\n- hash: %s\n",
+			kind, util.FormatSize(entry.Size), entry.Hash)
+	}
+	return fmt.Sprintf("%ss differ\n- size: %s\n+ size: %s\n- hash: %s\n+ hash: %s\n",
+		kind, util.FormatSize(entry.Size), util.FormatSize(working.size), entry.Hash, working.hash)
+}
+
+// diffContents compares two text versions of a file, choosing a row-level or
+// text diff depending on the file and its collection
+func diffContents(index *models.Index, entry *models.ManifestEntry, oldName, newName string, oldContent, newContent []byte, deleted bool, contextLines int) string {
+	if keys := tableKeys(index, entry); len(keys) > 0 && !deleted {
+		ext := strings.ToLower(filepath.Ext(entry.Path))
+		read := diff.ReadCSV
+		if ext == ".jsonl" || ext == ".ndjson" {
+			read = diff.ReadJSONL
+		}
+
+		oldTable, oldErr := read(bytes.NewReader(oldContent))
+		newTable, newErr := read(bytes.NewReader(newContent))
+		if oldErr == nil && newErr == nil {
+			tableDiff, err := diff.CompareTables(oldTable, newTable, keys)
+			if err == nil {
+				var sb strings.Builder
+				_ = diff.WriteTableDiff(&sb, oldName, newName, tableDiff)
+				return sb.String()
+			}
+			color.Yellow("Falling back to a text diff for %s: %v\n", entry.Path, err)
+		}
+	}
+
+	return diff.Unified(oldName, newName, string(oldContent), string(newContent), contextLines)
+}
+
+// tableKeys returns the primary key columns to compare a file on, or nil if it
+// isn't a CSV or JSONL file in a table collection with a primary key
+func tableKeys(index *models.Index, entry *models.ManifestEntry) []string {
+	switch strings.ToLower(filepath.Ext(entry.Path)) {
+	case ".csv", ".jsonl", ".ndjson":
+	default:
+		return nil
+	}
+
+	name := entry.Collection
+	if name == "" {
+		name = index.DefaultCollection
+	}
+	collection, err := index.GetCollection(name)
+	if err != nil || collection.Type != models.CollectionTypeTable || collection.Schema == nil {
+		return nil
+	}
+
+	var keys []string
+	for _, column := range collection.Schema.Columns {
+		if column.PrimaryKey {
+			keys = append(keys, column.Name)
+		}
+	}
+	return keys
+}
+
+// printDiff prints diff output, coloring lines by the change they describe
+func printDiff(text string) {
+	for _, line := range diff.SplitLines(text) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color.New(color.Bold).Print(line)
+		case strings.HasPrefix(line, "@@"):
+			color.New(color.FgCyan).Print(line)
+		case strings.HasPrefix(line, "+"):
+			color.New(color.FgGreen).Print(line)
+		case strings.HasPrefix(line, "-"):
+			color.New(color.FgRed).Print(line)
+		case strings.HasPrefix(line, "~"):
+			color.New(color.FgYellow).Print(line)
+		default:
+			fmt.Print(line)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().IntP("unified", "U", 3, "Number of context lines in text diffs")
+}
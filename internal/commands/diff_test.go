@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"hhx/internal/diff"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWorkingFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		binary bool
+	}{
+		{"empty", nil, false},
+		{"short text", []byte("hello\n"), false},
+		{"long text", bytes.Repeat([]byte("line of text\n"), 10000), false},
+		{"binary", append([]byte{0}, bytes.Repeat([]byte("x"), 100000)...), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			working, err := readWorkingFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sum := sha256.Sum256(tt.data)
+			if working.hash != hex.EncodeToString(sum[:]) || working.size != int64(len(tt.data)) {
+				t.Errorf("got size %d and hash %s, want %d and %x", working.size, working.hash, len(tt.data), sum)
+			}
+			if len(working.head) > diff.BinarySniffLength+4 {
+				t.Errorf("kept %d bytes of the file", len(working.head))
+			}
+			if diff.IsBinary(working.head) != tt.binary {
+				t.Errorf("IsBinary = %v, want %v", !tt.binary, tt.binary)
+			}
+		})
+	}
+}
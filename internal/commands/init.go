@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
 	"os"
@@ -66,8 +65,8 @@ var initCmd = &cobra.Command{
 			}
 
 			// Verify the project exists on the server
-			client := api.NewClient(globalConfig.ServerURL, tokenStore)
-			project, err := client.GetProject(projectName)
+			client := newClient(globalConfig.ServerURL, tokenStore)
+			project, err := client.GetProject(cmd.Context(), projectName)
 			if err != nil {
 				fmt.Printf("Error: couldn't find project '%s' on the server. Please check the project name or create it first with 'hhx project create'.\n", projectName)
 				return nil
@@ -83,7 +82,7 @@ var initCmd = &cobra.Command{
 		}
 
 		// Create index file
-		indexPath := filepath.Join(hhxDir, "index.json")
+		indexPath := filepath.Join(hhxDir, "index")
 		index := models.NewIndex(cwd)
 
 		// Create a default collection if no specific collection is provided
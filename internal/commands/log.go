@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/util"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "List snapshots",
+	Long:  `List the snapshots recorded with 'hhx commit', newest first.`,
+	Example: `  hhx log              # List all snapshots
+  hhx log -n 5         # List the five most recent snapshots
+  hhx log --oneline    # List one snapshot per line`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxCount, _ := cmd.Flags().GetInt("max-count")
+		oneline, _ := cmd.Flags().GetBool("oneline")
+
+		store, err := loadSnapshotStore()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		snapshots, err := store.Log()
+		if err != nil {
+			fmt.Println("error reading snapshots:", err)
+			if len(snapshots) == 0 {
+				return nil
+			}
+		}
+
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots yet. Record one with 'hhx commit -m <message>'.")
+			return nil
+		}
+
+		if maxCount > 0 && len(snapshots) > maxCount {
+			snapshots = snapshots[:maxCount]
+		}
+
+		for i, snapshot := range snapshots {
+			if oneline {
+				color.New(color.FgYellow).Print(snapshot.ShortID())
+				fmt.Printf(" %s\n", firstLine(snapshot.Message))
+				continue
+			}
+
+			if i > 0 {
+				fmt.Println()
+			}
+
+			var totalSize int64
+			for _, entry := range snapshot.Files {
+				totalSize += entry.Size
+			}
+
+			color.Yellow("snapshot %s\n", snapshot.ID)
+			fmt.Printf("Author: %s\n", snapshot.Author)
+			fmt.Printf("Date:   %s\n", snapshot.Time.Local().Format(time.RFC1123Z))
+			fmt.Printf("Files:  %d (%s)\n", len(snapshot.Files), util.FormatSize(totalSize))
+			fmt.Println()
+			for _, line := range strings.Split(snapshot.Message, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+
+		return nil
+	},
+}
+
+// firstLine returns the first line of a message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return line
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().IntP("max-count", "n", 0, "Limit the number of snapshots shown")
+	logCmd.Flags().Bool("oneline", false, "Show each snapshot on a single line")
+}
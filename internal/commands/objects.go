@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"hhx/internal/api"
+	"hhx/internal/config"
+	"hhx/internal/models"
+)
+
+// objectCacheMode returns the object cache mode set in the global config
+func objectCacheMode() string {
+	if globalConfig == nil || globalConfig.ObjectCache == "" {
+		return config.ObjectCacheRepo
+	}
+	return globalConfig.ObjectCache
+}
+
+// openObjectStore opens the object cache chosen in the global config: the one
+// shared by all repositories, or the repository's own .hhx/objects
+func openObjectStore(repoRoot string) *models.ObjectStore {
+	objects := models.NewObjectStore(repoRoot)
+	if objectCacheMode() == config.ObjectCacheShared {
+		if dir, err := config.GetSharedCacheDir(); err == nil {
+			objects = models.NewObjectStoreAt(dir)
+		}
+	}
+
+	if globalConfig != nil {
+		objects.SetMaxSize(globalConfig.CacheMaxSize)
+	}
+	return objects
+}
+
+// syncObjectStore returns the object cache that push and pull fill with the
+// files they transfer, or nil if caching is turned off
+func syncObjectStore(repoRoot string) *models.ObjectStore {
+	if objectCacheMode() == config.ObjectCacheOff {
+		return nil
+	}
+	return openObjectStore(repoRoot)
+}
+
+// objectFetcher makes synced file contents available in the local object cache,
+// downloading them from the remote server when they aren't cached yet
+type objectFetcher struct {
+	objects    *models.ObjectStore
+	repoConfig *config.RepoConfig
+	client     *api.Client
+}
+
+// newObjectFetcher creates a fetcher for the repository at repoRoot
+func newObjectFetcher(repoRoot string, repoConfig *config.RepoConfig) *objectFetcher {
+	return &objectFetcher{
+		objects:    openObjectStore(repoRoot),
+		repoConfig: repoConfig,
+	}
+}
+
+// fetch ensures the content of a synced file is cached and returns its path in the cache
+func (f *objectFetcher) fetch(ctx context.Context, remoteURL, hash string) (string, error) {
+	if path, ok := f.objects.Lookup(hash); ok {
+		return path, nil
+	}
+
+	// Only require a login once something has to be downloaded
+	if f.client == nil {
+		client, err := newRemoteClient(f.repoConfig, f.repoConfig.CurrentRemote)
+		if err != nil {
+			return "", err
+		}
+		f.client = client
+	}
+
+	path := f.objects.Path(hash)
+	if _, err := f.client.DownloadFile(ctx, remoteURL, path, hash); err != nil {
+		return "", err
+	}
+
+	// Evicting other objects to stay under the size cap is best effort
+	_ = f.objects.Track(hash)
+
+	return path, nil
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/spf13/cobra"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
 	"os"
@@ -70,8 +69,8 @@ var projectCreateCmd = &cobra.Command{
 		}
 
 		// Create the project
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		project, err := client.CreateProject(name, description)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		project, err := client.CreateProject(cmd.Context(), name, description)
 		if err != nil {
 			fmt.Println("Error creating project:", err)
 			return nil
@@ -112,8 +111,8 @@ var projectListCmd = &cobra.Command{
 		}
 
 		// List projects
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		projects, err := client.ListProjects()
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		projects, err := client.ListProjects(cmd.Context())
 		if err != nil {
 			fmt.Println("Error listing projects:", err)
 			return nil
@@ -166,8 +165,8 @@ var projectShowCmd = &cobra.Command{
 		}
 
 		// Get project details
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		project, err := client.GetProject(projectID)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		project, err := client.GetProject(cmd.Context(), projectID)
 		if err != nil {
 			fmt.Println("Error getting project:", err)
 			return nil
@@ -213,8 +212,8 @@ var projectUpdateCmd = &cobra.Command{
 		}
 
 		// Get current project details
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		project, err := client.GetProject(projectID)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		project, err := client.GetProject(cmd.Context(), projectID)
 		if err != nil {
 			fmt.Println("Error getting project:", err)
 			return nil
@@ -261,7 +260,7 @@ var projectUpdateCmd = &cobra.Command{
 		}
 
 		// Update the project
-		updatedProject, err := client.UpdateProject(projectID, name, description)
+		updatedProject, err := client.UpdateProject(cmd.Context(), projectID, name, description)
 		if err != nil {
 			fmt.Println("Error updating project:", err)
 			return nil
@@ -305,8 +304,8 @@ var projectDeleteCmd = &cobra.Command{
 		}
 
 		// Get current project details
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		project, err := client.GetProject(projectID)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		project, err := client.GetProject(cmd.Context(), projectID)
 		if err != nil {
 			fmt.Println("Error getting project:", err)
 			return nil
@@ -325,7 +324,7 @@ var projectDeleteCmd = &cobra.Command{
 		}
 
 		// Delete the project
-		err = client.DeleteProject(projectID)
+		err = client.DeleteProject(cmd.Context(), projectID)
 		if err != nil {
 			fmt.Println("Error deleting project:", err)
 			return nil
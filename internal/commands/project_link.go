@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
 
@@ -12,8 +11,10 @@ import (
 var projectLinkCmd = &cobra.Command{
 	Use:   "link [project_name]",
 	Short: "Link repository to a project",
-	Long:  "Link the current repository to a project on the remote server",
-	Args:  cobra.ExactArgs(1),
+	Long: `Link the current repository to a project on the current remote. If the current
+remote is linked to a project of its own ('hhx remote add --project'), the remote is
+linked to the new project instead.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectName := args[0]
 
@@ -51,23 +52,39 @@ var projectLinkCmd = &cobra.Command{
 			return nil
 		}
 
+		// Look the project up on the current remote
+		serverURL := globalConfig.ServerURL
+		if url, ok := repoConfig.Remotes[repoConfig.CurrentRemote]; ok {
+			serverURL = url
+		}
+
 		// Verify the project exists using the name-based lookup
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		project, err := client.GetProjectByName(projectName)
+		client := newClient(serverURL, tokenStore)
+		project, err := client.GetProjectByName(cmd.Context(), projectName)
 		if err != nil {
 			fmt.Printf("Error: couldn't find project '%s'. Please check the name or create it first.\n", projectName)
 			return nil
 		}
 
-		repoConfig.ProjectName = project.Name
-		repoConfig.ProjectID = project.ID
+		// A remote linked to its own project keeps using it, so relink the remote
+		remote := repoConfig.CurrentRemote
+		if repoConfig.RemoteProjects[remote] != "" {
+			repoConfig.RemoteProjects[remote] = project.ID
+		} else {
+			repoConfig.ProjectName = project.Name
+			repoConfig.ProjectID = project.ID
+		}
 
 		if err := config.SaveRepoConfig(repoConfig); err != nil {
 			fmt.Println("Error saving repository config:", err)
 			return nil
 		}
 
-		fmt.Printf("Repository linked to project '%s' (ID: %s)\n", project.Name, project.ID)
+		if repoConfig.RemoteProjects[remote] != "" {
+			fmt.Printf("Remote '%s' linked to project '%s' (ID: %s)\n", remote, project.Name, project.ID)
+		} else {
+			fmt.Printf("Repository linked to project '%s' (ID: %s)\n", project.Name, project.ID)
+		}
 		return nil
 	},
 }
@@ -0,0 +1,494 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hhx/internal/api"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"hhx/internal/ui"
+	"hhx/internal/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var pullCmd = &cobra.Command{
+	Use:   "pull [remote]",
+	Short: "Download files from the remote server",
+	Long: `Download files from a remote collection into the working tree.
+
+Files that are missing locally or whose remote hash differs from the last synced
+version are downloaded, verified against their SHA-256 hash and recorded as synced.
+Local changes are never overwritten unless --force is given.
+
+With --tag, the working tree is restored to the exact files of a tag across all
+collections. Synced files that aren't part of the tag are removed from the working
+tree if they have no local changes; they stay on the server.`,
+	Example: `  hhx pull                            # Pull the default collection from the default remote
+  hhx pull origin                     # Pull the default collection from the specified remote
+  hhx pull --collection=my-models     # Pull a specific collection
+  hhx pull --force                    # Overwrite local changes with the remote version
+  hhx pull --tag v1.3                 # Restore the files of a tag`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		remote := ""
+		if len(args) > 1 {
+			fmt.Println("Error: unexpected argument:", args[1])
+			err := cmd.Usage()
+			if err != nil {
+				fmt.Println("error displaying usage:", err)
+			}
+			return nil
+		}
+		if len(args) == 1 {
+			remote = args[0]
+		}
+
+		collectionName, _ := cmd.Flags().GetString("collection")
+		projectName, _ := cmd.Flags().GetString("project")
+		force, _ := cmd.Flags().GetBool("force")
+		tagName, _ := cmd.Flags().GetString("tag")
+
+		if tagName != "" && collectionName != "" {
+			fmt.Println("Error: --tag and --collection can't be used together, a tag covers all collections")
+			return nil
+		}
+
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		if remote == "" {
+			remote = repoConfig.CurrentRemote
+		}
+
+		remoteURL, ok := repoConfig.Remotes[remote]
+		if !ok {
+			fmt.Println("Error: unknown remote:", remote)
+			return nil
+		}
+
+		// Determine which project to use
+		activeProject := repoConfig.ProjectFor(remote)
+		if projectName != "" {
+			activeProject = projectName
+		}
+
+		if activeProject == "" {
+			fmt.Println("Error: no project specified or linked. Use --project to specify a project or link a project with 'hhx project link'")
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		// A tag lists the collection of each of its files, so it doesn't need one
+		var collection *models.Collection
+		if collectionName != "" {
+			collection, err = index.GetCollection(collectionName)
+			if err != nil {
+				fmt.Println("Error: collection not found:", collectionName)
+				return nil
+			}
+		} else if tagName == "" {
+			collection, err = index.GetDefaultCollection()
+			if err != nil {
+				fmt.Println("Error: no default collection set. Use --collection to specify or set a default with 'hhx collection set-default'")
+				return nil
+			}
+		}
+
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Println("error getting home directory:", err)
+			return nil
+		}
+		configDir := filepath.Join(homeDir, ".hhx")
+		tokenStore := models.NewTokenStore(configDir)
+		client := newClient(remoteURL, tokenStore)
+		if client.AuthToken == "" {
+			fmt.Println("Error: not logged in. Please run 'hhx login' first")
+			return nil
+		}
+
+		// Reuse and fill the local object cache
+		objects := syncObjectStore(repoRoot)
+
+		if tagName != "" {
+			tag, err := resolveTag(ctx, client, repoRoot, activeProject, tagName)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return nil
+			}
+
+			fmt.Printf("Pulling tag '%s' of project '%s' from '%s'...\n", tag.Name, activeProject, remote)
+			startTime := time.Now()
+
+			transfer := ui.NewTransfer()
+			client.SetProgress(transfer)
+			view := ui.StartProgress(transfer, "Pulling")
+			result := pullTag(ctx, client, index, objects, view, repoRoot, tag, force)
+			view.Stop()
+
+			if err := index.Save(repoConfig.IndexPath); err != nil {
+				fmt.Println("error saving index:", err)
+				return nil
+			}
+
+			duration := time.Since(startTime).Round(time.Millisecond)
+			fmt.Printf("\nDownloaded %d files (%s) and removed %d files for tag '%s' in %s\n",
+				result.Downloaded,
+				util.FormatSize(result.Bytes),
+				result.Removed,
+				tag.Name,
+				duration,
+			)
+			printPullResult(result)
+			printPullInterrupted(ctx)
+			return nil
+		}
+
+		fmt.Printf("Pulling project '%s', collection '%s' from '%s'...\n", activeProject, collection.Name, remote)
+		startTime := time.Now()
+
+		remoteFiles, err := client.ListProjectCollectionFiles(ctx, activeProject, collection)
+		if err != nil {
+			fmt.Println("pull failed:", err)
+			return nil
+		}
+
+		transfer := ui.NewTransfer()
+		client.SetProgress(transfer)
+		view := ui.StartProgress(transfer, "Pulling")
+		result := pullFiles(ctx, client, index, objects, view, repoRoot, collection.Name, remoteFiles, force)
+		view.Stop()
+
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		// Print summary
+		duration := time.Since(startTime).Round(time.Millisecond)
+		fmt.Printf("\nDownloaded %d files (%s) from project '%s', collection '%s' in %s\n",
+			result.Downloaded,
+			util.FormatSize(result.Bytes),
+			activeProject,
+			collection.Name,
+			duration,
+		)
+		printPullResult(result)
+		printPullInterrupted(ctx)
+
+		return nil
+	},
+}
+
+// printPullResult prints the files a pull didn't download
+func printPullResult(result *pullResult) {
+	if result.UpToDate > 0 {
+		fmt.Printf("%d files already up to date\n", result.UpToDate)
+	}
+	if result.Skipped > 0 {
+		fmt.Printf("%d files skipped because of local changes (use --force to overwrite)\n", result.Skipped)
+	}
+	if result.Failed > 0 {
+		fmt.Printf("%d files failed to download\n", result.Failed)
+	}
+}
+
+// printPullInterrupted tells the user a pull was cut short, if it was
+func printPullInterrupted(ctx context.Context) {
+	if ctx.Err() != nil {
+		color.Yellow("\nPull interrupted. The files downloaded so far are recorded as synced; run 'hhx pull' again to get the rest.")
+	}
+}
+
+// pullResult summarises the outcome of downloading a set of remote files
+type pullResult struct {
+	Downloaded int
+	Bytes      int64
+	UpToDate   int
+	Skipped    int
+	Failed     int
+	Removed    int
+}
+
+// pullFiles downloads the remote files that are missing locally or whose hash
+// differs from the synced version, and records them as synced in the index.
+// Files found in the object cache are copied from it instead of downloaded,
+// and downloaded files are added to it; objects may be nil to skip the cache.
+// Once ctx is cancelled no more files are downloaded.
+func pullFiles(ctx context.Context, client *api.Client, index *models.Index, objects *models.ObjectStore, view *ui.ProgressView, repoRoot, collectionName string, remoteFiles []api.RemoteFile, force bool) *pullResult {
+	result := &pullResult{}
+
+	sort.Slice(remoteFiles, func(i, j int) bool {
+		return remoteFiles[i].Path < remoteFiles[j].Path
+	})
+
+	for _, remoteFile := range remoteFiles {
+		view.AddFile(filepath.Join(repoRoot, filepath.FromSlash(remoteFile.Path)), remoteFile.Path, remoteFile.Size)
+	}
+
+	for _, remoteFile := range remoteFiles {
+		if ctx.Err() != nil {
+			break
+		}
+
+		fullPath := filepath.Join(repoRoot, filepath.FromSlash(remoteFile.Path))
+		pullFile(ctx, client, index, objects, view, repoRoot, fullPath, collectionName, remoteFile, force, result)
+		view.Finish(fullPath)
+	}
+
+	return result
+}
+
+// pullFile downloads a single remote file to fullPath unless it is up to date
+// or has local changes, and adds the outcome to result
+func pullFile(ctx context.Context, client *api.Client, index *models.Index, objects *models.ObjectStore, view *ui.ProgressView, repoRoot, fullPath, collectionName string, remoteFile api.RemoteFile, force bool, result *pullResult) {
+	// Never write outside the repository
+	if !filepath.IsLocal(filepath.FromSlash(remoteFile.Path)) {
+		view.Println(color.RedString("  skipping %s: path escapes the repository", remoteFile.Path))
+		result.Failed++
+		return
+	}
+
+	synced, isSynced := index.GetSyncedFile(remoteFile.Path)
+
+	if _, err := os.Stat(fullPath); err == nil {
+		if isSynced && synced.Hash == remoteFile.Hash {
+			result.UpToDate++
+			return
+		}
+
+		local, err := models.NewFileFromPath(repoRoot, fullPath)
+		if err != nil {
+			view.Println(color.RedString("  %s: %v", remoteFile.Path, err))
+			result.Failed++
+			return
+		}
+
+		// The local file already matches the remote version
+		if local.Hash == remoteFile.Hash {
+			local.RemoteURL = remoteFile.RemoteURL
+			local.Collection = collectionName
+			index.RecordSynced(local)
+			result.UpToDate++
+			return
+		}
+
+		// Don't clobber untracked files or local modifications
+		locallyChanged := !isSynced || local.Hash != synced.Hash
+		if locallyChanged && !force {
+			view.Println(color.YellowString("  skipping %s: local changes would be overwritten", remoteFile.Path))
+			result.Skipped++
+			return
+		}
+	} else if !os.IsNotExist(err) {
+		view.Println(color.RedString("  %s: %v", remoteFile.Path, err))
+		result.Failed++
+		return
+	}
+
+	// Copy the file from the object cache if it is there, else download it
+	var size int64
+	cached := false
+	if objects != nil && objects.Has(remoteFile.Hash) {
+		if n, err := objects.Checkout(remoteFile.Hash, fullPath); err == nil {
+			size, cached = n, true
+		}
+	}
+	if !cached {
+		n, err := client.DownloadFile(ctx, remoteFile.RemoteURL, fullPath, remoteFile.Hash)
+		if err != nil && ctx.Err() != nil {
+			// Interrupted; the file is left as it was
+			return
+		}
+		if err != nil {
+			view.Println(color.RedString("  %s: %v", remoteFile.Path, err))
+			result.Failed++
+			return
+		}
+		size = n
+
+		// The cache is best effort, a file that can't be cached is still pulled
+		if objects != nil {
+			_ = objects.Put(remoteFile.Hash, fullPath)
+		}
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		view.Println(color.RedString("  %s: %v", remoteFile.Path, err))
+		result.Failed++
+		return
+	}
+
+	index.RecordSynced(&models.File{
+		Path:         remoteFile.Path,
+		Size:         size,
+		Hash:         remoteFile.Hash,
+		LastModified: info.ModTime(),
+		RemoteURL:    remoteFile.RemoteURL,
+		Collection:   collectionName,
+	})
+
+	if cached {
+		view.Printf("  copied %s from cache (%s)\n", remoteFile.Path, util.FormatSize(size))
+	} else {
+		view.Printf("  downloaded %s (%s)\n", remoteFile.Path, util.FormatSize(size))
+	}
+	result.Downloaded++
+	result.Bytes += size
+}
+
+// resolveTag loads a tag from the repository, fetching it from the server and
+// storing it locally if it isn't known yet
+func resolveTag(ctx context.Context, client *api.Client, repoRoot, project, name string) (*models.Tag, error) {
+	tags := models.NewTagStore(repoRoot)
+
+	tag, err := tags.Load(name)
+	if err == nil {
+		return tag, nil
+	}
+	if !errors.Is(err, models.ErrTagNotFound) {
+		return nil, err
+	}
+
+	tag, err = client.GetProjectTag(ctx, project, name)
+	if err != nil {
+		if errors.Is(err, models.ErrTagNotFound) {
+			return nil, fmt.Errorf("tag not found: %s", name)
+		}
+		return nil, err
+	}
+
+	// The server's copy is authoritative for the name it was requested under
+	tag.Name = name
+	if err := tags.Save(tag); err != nil {
+		return nil, fmt.Errorf("error saving tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// pullTag restores the working tree to the files of a tag. Synced files that
+// aren't part of the tag are removed locally unless they have local changes,
+// and forgotten by the index without staging their deletion. Staged deletions
+// are kept for the next push.
+func pullTag(ctx context.Context, client *api.Client, index *models.Index, objects *models.ObjectStore, view *ui.ProgressView, repoRoot string, tag *models.Tag, force bool) *pullResult {
+	result := &pullResult{}
+
+	// Download the tag's files, one collection at a time
+	byCollection := make(map[string][]api.RemoteFile)
+	inTag := make(map[string]bool)
+	for _, entry := range tag.Files {
+		inTag[entry.Path] = true
+		byCollection[entry.Collection] = append(byCollection[entry.Collection], api.RemoteFile{
+			Path:      entry.Path,
+			RemoteURL: entry.RemoteURL,
+			Size:      entry.Size,
+			Hash:      entry.Hash,
+		})
+	}
+
+	collectionNames := make([]string, 0, len(byCollection))
+	for name := range byCollection {
+		collectionNames = append(collectionNames, name)
+	}
+	sort.Strings(collectionNames)
+
+	for _, name := range collectionNames {
+		collectionResult := pullFiles(ctx, client, index, objects, view, repoRoot, name, byCollection[name], force)
+		result.Downloaded += collectionResult.Downloaded
+		result.Bytes += collectionResult.Bytes
+		result.UpToDate += collectionResult.UpToDate
+		result.Skipped += collectionResult.Skipped
+		result.Failed += collectionResult.Failed
+	}
+
+	// Only a complete pull may remove files
+	if ctx.Err() != nil {
+		return result
+	}
+
+	// Remove synced files the tag doesn't include
+	for _, entry := range index.Tracked() {
+		if inTag[entry.Path] {
+			continue
+		}
+
+		// A staged deletion still has to be pushed to remove the file from
+		// the server, so it stays staged
+		if file, ok := index.GetTrackedFile(entry.Path); ok && file.Status == models.StatusDeleted {
+			view.Println(color.YellowString("  keeping %s staged for deletion: not part of the tag", entry.Path))
+			continue
+		}
+
+		fullPath := filepath.Join(repoRoot, filepath.FromSlash(entry.Path))
+		if _, err := os.Stat(fullPath); err == nil {
+			local, err := models.NewFileFromPath(repoRoot, fullPath)
+			if err != nil {
+				view.Println(color.RedString("  %s: %v", entry.Path, err))
+				result.Failed++
+				continue
+			}
+
+			if local.Hash != entry.Hash && !force {
+				view.Println(color.YellowString("  keeping %s: not part of the tag but has local changes", entry.Path))
+				result.Skipped++
+				continue
+			}
+
+			if err := os.Remove(fullPath); err != nil {
+				view.Println(color.RedString("  %s: %v", entry.Path, err))
+				result.Failed++
+				continue
+			}
+		} else if !os.IsNotExist(err) {
+			view.Println(color.RedString("  %s: %v", entry.Path, err))
+			result.Failed++
+			continue
+		}
+
+		index.ForgetSynced(entry.Path)
+		view.Printf("  removed %s\n", entry.Path)
+		result.Removed++
+	}
+
+	return result
+}
+
+func init() {
+	rootCmd.AddCommand(pullCmd)
+
+	pullCmd.Flags().String("collection", "", "Collection to pull from (defaults to the default collection)")
+	pullCmd.Flags().String("project", "", "Project to pull from (overrides the linked project)")
+	pullCmd.Flags().Bool("force", false, "Overwrite local changes with the remote version")
+	pullCmd.Flags().String("tag", "", "Restore the exact files of a tag instead of pulling a collection")
+}
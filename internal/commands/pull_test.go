@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hhx/internal/api"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPullTagKeepsStagedDeletions(t *testing.T) {
+	repoRoot := t.TempDir()
+	index := models.NewIndex(repoRoot)
+
+	// A synced file still in the working tree, and one whose deletion is staged
+	if err := os.WriteFile(filepath.Join(repoRoot, "kept.txt"), []byte("kept"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("kept"))
+	index.RecordSynced(&models.File{Path: "kept.txt", Hash: hex.EncodeToString(sum[:]), Size: 4})
+	index.RecordSynced(&models.File{Path: "deleted.txt", Hash: "hash-deleted", Size: 7})
+	if err := index.StageDeletion(filepath.Join(repoRoot, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tag holding neither file needs nothing from the server
+	result := pullTag(context.Background(), nil, index, nil, nil, repoRoot, &models.Tag{Name: "empty"}, false)
+
+	if result.Removed != 1 || result.Failed != 0 {
+		t.Errorf("removed %d and failed %d files, want 1 and 0", result.Removed, result.Failed)
+	}
+	if _, err := os.Stat(filepath.Join(repoRoot, "kept.txt")); !os.IsNotExist(err) {
+		t.Errorf("kept.txt left in the working tree: %v", err)
+	}
+	if _, ok := index.GetSyncedFile("kept.txt"); ok {
+		t.Error("kept.txt is still synced after pulling a tag without it")
+	}
+	deletions := index.GetStagedDeletions()
+	if len(deletions) != 1 || deletions[0].Path != "deleted.txt" {
+		t.Errorf("staged deletions: %v, want deleted.txt", deletions)
+	}
+}
+
+// writeTestFile writes content to path in repoRoot
+func writeTestFile(t *testing.T, repoRoot, path, content string) {
+	t.Helper()
+
+	fullPath := filepath.Join(repoRoot, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile returns the contents of path in repoRoot
+func readTestFile(t *testing.T, repoRoot, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestPullFilesDownloadsAndRecordsFiles(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+	index := models.NewIndex(repoRoot)
+
+	remoteFiles := []api.RemoteFile{
+		server.testRemoteFile("models/b.bin", "weights", "weights"),
+		server.testRemoteFile("a.txt", "notes", "notes"),
+	}
+	result := pullFiles(context.Background(), client, index, nil, nil, repoRoot, "default", remoteFiles, false)
+
+	if result.Downloaded != 2 || result.Bytes != 12 || result.Failed != 0 {
+		t.Errorf("result: %+v, want 2 files and 12 bytes downloaded", result)
+	}
+	if got := readTestFile(t, repoRoot, "models/b.bin"); got != "weights" {
+		t.Errorf("models/b.bin contains %q", got)
+	}
+
+	synced, ok := index.GetSyncedFile("models/b.bin")
+	if !ok {
+		t.Fatal("models/b.bin not synced")
+	}
+	if synced.Hash != hashOf("weights") || synced.RemoteURL != "/files/models/b.bin" || synced.Collection != "default" {
+		t.Errorf("models/b.bin recorded as %+v", synced)
+	}
+}
+
+func TestPullFilesRejectsHashMismatch(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+	index := models.NewIndex(repoRoot)
+
+	// The server sends different contents than it lists
+	remoteFiles := []api.RemoteFile{server.testRemoteFile("a.txt", "corrupted", "notes")}
+	result := pullFiles(context.Background(), client, index, nil, nil, repoRoot, "default", remoteFiles, false)
+
+	if result.Failed != 1 || result.Downloaded != 0 {
+		t.Errorf("result: %+v, want 1 failed file", result)
+	}
+	entries, err := os.ReadDir(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("failed download left %s behind", entries[0].Name())
+	}
+	if _, ok := index.GetSyncedFile("a.txt"); ok {
+		t.Error("a.txt recorded as synced after a failed download")
+	}
+}
+
+func TestPullFilesKeepsLocalChanges(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	tests := []struct {
+		name   string
+		force  bool
+		want   string
+		result pullResult
+	}{
+		{name: "without force", force: false, want: "local edit", result: pullResult{Skipped: 1}},
+		{name: "with force", force: true, want: "remote edit", result: pullResult{Downloaded: 1, Bytes: 11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoRoot := t.TempDir()
+			index := models.NewIndex(repoRoot)
+
+			// Both sides changed the file since it was last synced
+			writeTestFile(t, repoRoot, "a.txt", "local edit")
+			index.RecordSynced(&models.File{Path: "a.txt", Hash: hashOf("original"), Size: 8})
+
+			remoteFiles := []api.RemoteFile{server.testRemoteFile("a.txt", "remote edit", "remote edit")}
+			result := pullFiles(context.Background(), client, index, nil, nil, repoRoot, "default", remoteFiles, tt.force)
+
+			if *result != tt.result {
+				t.Errorf("result: %+v, want %+v", *result, tt.result)
+			}
+			if got := readTestFile(t, repoRoot, "a.txt"); got != tt.want {
+				t.Errorf("a.txt contains %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullFilesLeavesUpToDateFiles(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+	index := models.NewIndex(repoRoot)
+
+	// One file synced at the remote version, one untracked that matches it
+	writeTestFile(t, repoRoot, "synced.txt", "same")
+	index.RecordSynced(&models.File{Path: "synced.txt", Hash: hashOf("same"), Size: 4})
+	writeTestFile(t, repoRoot, "untracked.txt", "same")
+
+	remoteFiles := []api.RemoteFile{
+		server.testRemoteFile("synced.txt", "same", "same"),
+		server.testRemoteFile("untracked.txt", "same", "same"),
+	}
+	// Downloads would fail, so none may be attempted
+	server.files = map[string]string{}
+	result := pullFiles(context.Background(), client, index, nil, nil, repoRoot, "default", remoteFiles, false)
+
+	if result.UpToDate != 2 || result.Downloaded != 0 || result.Failed != 0 {
+		t.Errorf("result: %+v, want 2 files up to date", result)
+	}
+	if synced, ok := index.GetSyncedFile("untracked.txt"); !ok || synced.RemoteURL != "/files/untracked.txt" {
+		t.Errorf("untracked.txt matching the remote not recorded as synced: %+v", synced)
+	}
+}
+
+func TestPullFilesUsesObjectCache(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	repoRoot := t.TempDir()
+	index := models.NewIndex(repoRoot)
+	objects := models.NewObjectStore(repoRoot)
+
+	// The first pull downloads and caches the file
+	remoteFiles := []api.RemoteFile{server.testRemoteFile("a.txt", "notes", "notes")}
+	if result := pullFiles(context.Background(), client, index, objects, nil, repoRoot, "default", remoteFiles, false); result.Downloaded != 1 {
+		t.Fatalf("result: %+v, want 1 file downloaded", result)
+	}
+	if !objects.Has(hashOf("notes")) {
+		t.Fatal("downloaded file not cached")
+	}
+
+	// Pulled again into another repository, it is copied from the cache
+	server.Close()
+	otherRoot := t.TempDir()
+	result := pullFiles(context.Background(), client, models.NewIndex(otherRoot), objects, nil, otherRoot, "default", remoteFiles, false)
+	if result.Downloaded != 1 || result.Failed != 0 {
+		t.Errorf("result: %+v, want 1 file copied from the cache", result)
+	}
+	if got := readTestFile(t, otherRoot, "a.txt"); got != "notes" {
+		t.Errorf("a.txt contains %q", got)
+	}
+}
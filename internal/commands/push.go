@@ -1,13 +1,17 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
+	"hhx/internal/ui"
 	"hhx/internal/util"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
@@ -17,15 +21,37 @@ import (
 var pushCmd = &cobra.Command{
 	Use:   "push [remote] [all]",
 	Short: "Upload files to the remote server",
-	Long:  `Upload staged files to the remote server.`,
+	Long: `Upload staged files to the remote server and remove files whose deletion is staged.
+
+Each file is pushed to the collection it was staged with ('hhx stage --collection'),
+or else to the collection of the first matching route ('hhx collection route'). Other
+files go to the collection given with --collection, or the default collection.
+
+Files are uploaded in batches limited by --batch-size and --batch-bytes, with
+--upload-jobs batches in flight at once. Each batch is recorded as synced as soon
+as it succeeds, so a failed batch doesn't undo the others. Each content is uploaded
+once per push: files repeating the contents of a file in another batch are sent as
+references once the other batches are done. Files that fail to upload are recorded
+with their error and shown by 'hhx status'; 'hhx push --retry-failed' pushes only
+those. Push exits with a non-zero status if any file failed.
+
+With --dry-run nothing is uploaded or deleted. The project and collections are
+checked as for a real push, and each file is listed with what would happen to it:
+new, modified, delete, or duplicate when the server already has its contents and
+it would be skipped. Add --json for a plan that tools can read.`,
 	Example: `  hhx push                            # Push staged files to default collection on default remote
   hhx push origin                     # Push staged files to default collection on specified remote
   hhx push --collection=my-models     # Push staged files to specific collection on default remote
   hhx push --project=proj-name        # Push to a specific project (overrides the linked project)
   hhx push all                        # Push all files to default collection on default remote
   hhx push origin all                 # Push all files to default collection on specified remote
-  hhx push --collection=my-models all # Push all files to specific collection on default remote`,
+  hhx push --collection=my-models all # Push all files to specific collection on default remote
+  hhx push --upload-jobs 8            # Upload 8 batches at a time
+  hhx push --retry-failed             # Push only the files whose last push failed
+  hhx push --dry-run                  # Show what would be pushed without pushing
+  hhx push --dry-run --json           # Print the plan as JSON`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		remote := ""
 		pushAll := false
 
@@ -46,6 +72,17 @@ var pushCmd = &cobra.Command{
 
 		collectionName, _ := cmd.Flags().GetString("collection")
 		projectName, _ := cmd.Flags().GetString("project")
+		retryFailed, _ := cmd.Flags().GetBool("retry-failed")
+		if retryFailed && pushAll {
+			fmt.Println("Error: --retry-failed can't be combined with 'all'")
+			return nil
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if asJSON && !dryRun {
+			fmt.Println("Error: --json can only be used with --dry-run")
+			return nil
+		}
 
 		repoRoot, err := findRepoRoot()
 		if err != nil {
@@ -70,7 +107,7 @@ var pushCmd = &cobra.Command{
 		}
 
 		// Determine which project to use
-		activeProject := repoConfig.ProjectName
+		activeProject := repoConfig.ProjectFor(remote)
 		if projectName != "" {
 			activeProject = projectName
 		}
@@ -80,53 +117,123 @@ var pushCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
 			fmt.Println("error loading index:", err)
 			return nil
 		}
 
-		var collection *models.Collection
-		if collectionName != "" {
-			collection, err = index.GetCollection(collectionName)
-			if err != nil {
-				fmt.Println("Error: collection not found:", collectionName)
-				return nil
-			}
-		} else {
-			collection, err = index.GetDefaultCollection()
-			if err != nil {
-				fmt.Println("Error: no default collection set. Use --collection to specify or set a default with 'hhx collection set-default'")
-				return nil
-			}
+		// Files that aren't tagged or routed to a collection go to the fallback collection
+		fallback := collectionName
+		if fallback == "" {
+			fallback = index.DefaultCollection
+		} else if _, err := index.GetCollection(fallback); err != nil {
+			fmt.Println("Error: collection not found:", fallback)
+			return nil
+		}
+
+		if rehash, _ := cmd.Flags().GetBool("rehash"); rehash {
+			index.DisableStatCache()
+		}
+		if globalConfig != nil {
+			index.SetHashJobs(globalConfig.HashJobs)
 		}
 
 		var filesToPush []*models.File
 
 		if pushAll {
 			// If pushing all, stage all unstaged files first
-			newFiles, modifiedFiles, _, err := index.ScanWorkingDirectory()
+			newFiles, modifiedFiles, deletedFiles, err := index.ScanWorkingDirectory()
 			if err != nil {
 				fmt.Println("error scanning working directory:", err)
 				return nil
 			}
 
 			// Stage all new and modified files
+			var paths []string
 			for _, file := range append(newFiles, modifiedFiles...) {
-				if err := index.StageFile(file.FullPath(repoRoot)); err != nil {
-					fmt.Println("error staging file", file.Path, ":", err)
+				paths = append(paths, file.FullPath(repoRoot))
+			}
+			if err := index.StageFiles(paths); err != nil {
+				fmt.Println("error staging files:", err)
+				return nil
+			}
+
+			// Stage all deleted files
+			for _, file := range deletedFiles {
+				if err := index.StageDeletion(file.FullPath(repoRoot)); err != nil {
+					fmt.Println("error staging deletion of", file.Path, ":", err)
 					return nil
 				}
 			}
 		}
 
-		// Get staged files
+		// Get staged files and deletions
 		filesToPush = index.GetStagedFiles()
-		if len(filesToPush) == 0 {
+		filesToDelete := index.GetStagedDeletions()
+		if retryFailed {
+			filesToPush, filesToDelete = nil, nil
+			for _, file := range index.GetFailedFiles() {
+				if file.Status == models.StatusDeleted {
+					filesToDelete = append(filesToDelete, file)
+				} else {
+					filesToPush = append(filesToPush, file)
+				}
+			}
+			if len(filesToPush) == 0 && len(filesToDelete) == 0 && !dryRun {
+				fmt.Println("No failed files to push.")
+				return nil
+			}
+		}
+		if len(filesToPush) == 0 && len(filesToDelete) == 0 && !dryRun {
 			fmt.Println("No files to push.")
 			return nil
 		}
 
+		// Group files by the collection they are pushed to
+		filesByCollection := make(map[string][]*models.File)
+		deletionsByCollection := make(map[string][]*models.File)
+		var collectionNames []string
+		for _, file := range append(filesToPush, filesToDelete...) {
+			name := index.ResolveCollection(file)
+			if name == "" {
+				name = fallback
+			}
+			if name == "" {
+				fmt.Println("Error: no default collection set. Use --collection to specify or set a default with 'hhx collection set-default'")
+				return nil
+			}
+
+			if len(filesByCollection[name]) == 0 && len(deletionsByCollection[name]) == 0 {
+				collectionNames = append(collectionNames, name)
+			}
+
+			if file.Status == models.StatusDeleted {
+				deletionsByCollection[name] = append(deletionsByCollection[name], file)
+			} else {
+				filesByCollection[name] = append(filesByCollection[name], file)
+			}
+		}
+		sort.Strings(collectionNames)
+
+		collections := make([]*models.Collection, 0, len(collectionNames))
+		for _, name := range collectionNames {
+			collection, err := index.GetCollection(name)
+			if err != nil {
+				fmt.Println("Error: collection not found:", name)
+				return nil
+			}
+			collections = append(collections, collection)
+		}
+
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
 			fmt.Println("error getting home directory:", err)
@@ -134,65 +241,433 @@ var pushCmd = &cobra.Command{
 		}
 		configDir := filepath.Join(homeDir, ".hhx")
 		tokenStore := models.NewTokenStore(configDir)
-		client := api.NewClient(remoteURL, tokenStore)
+		client := newClient(remoteURL, tokenStore)
 		if client.AuthToken == "" {
 			fmt.Println("Error: not logged in. Please run 'hhx login' first")
 			return nil
 		}
 
-		// Push files to the specific project and collection
-		fmt.Printf("Pushing %d files to project '%s', collection '%s' on '%s'...\n",
-			len(filesToPush), activeProject, collection.Name, remote)
-		startTime := time.Now()
+		jobs, _ := cmd.Flags().GetInt("upload-jobs")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		batchBytesFlag, _ := cmd.Flags().GetString("batch-bytes")
+		var batchBytes int64
+		if batchBytesFlag != "" {
+			batchBytes, err = util.ParseSize(batchBytesFlag)
+			if err != nil {
+				fmt.Println("Error: invalid --batch-bytes:", err)
+				return nil
+			}
+		}
 
-		// Add project and collection information to the push request
-		resp, err := client.PushFilesToProjectCollection(repoRoot, filesToPush, activeProject, collection)
+		// Look the project up once rather than for every batch. The plan printed
+		// as JSON must be the only output.
+		resolveProjectID := client.ResolveProjectID
+		if asJSON {
+			resolveProjectID = client.FindProjectID
+		}
+		projectID, err := resolveProjectID(ctx, activeProject)
 		if err != nil {
-			fmt.Println("push failed:", err)
+			fmt.Println("Error:", err)
 			return nil
 		}
 
-		// Process response
-		if len(resp.Errors) > 0 {
-			fmt.Println("Some files failed to upload:")
-			for _, uploadErr := range resp.Errors {
-				color.Red("  %s: %s\n", uploadErr.Path, uploadErr.Error)
+		var batches []*pushBatch
+		summaries := make(map[string]*pushSummary, len(collections))
+		for _, collection := range collections {
+			summaries[collection.Name] = &pushSummary{Collection: collection.Name}
+			batches = append(batches, splitBatches(collection, filesByCollection[collection.Name], batchSize, batchBytes)...)
+		}
+
+		if dryRun {
+			plan, err := planPush(ctx, client, remote, activeProject, projectID, collections, batches, deletionsByCollection)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return nil
 			}
+			printPushPlan(plan, asJSON)
+			return nil
 		}
 
-		// Update index with new remote URLs
-		for _, uploaded := range resp.UploadedFiles {
-			index.MarkSynced(uploaded.Path, uploaded.RemoteURL)
+		// Contents are uploaded once per push, by the first batch that has them
+		batches, repeats := deferRepeats(batches)
+
+		fmt.Printf("Pushing %d files in %d batches and %d deletions to %d collections in project '%s' on '%s'...\n",
+			len(filesToPush), len(batches)+len(repeats), len(filesToDelete), len(collections), activeProject, remote)
+		startTime := time.Now()
+
+		// Keep a copy of every pushed file in the object cache
+		objects := syncObjectStore(repoRoot)
+
+		// Show the progress of the uploads until they finish
+		transfer := ui.NewTransfer()
+		for _, file := range filesToPush {
+			transfer.AddFile(file.Path, file.Path, file.Size)
 		}
+		client.SetProgress(transfer)
+		view := ui.StartProgress(transfer, "Pushing")
+		defer view.Stop()
 
-		if err := index.Save(repoConfig.IndexPath); err != nil {
+		err = pushBatches(ctx, client, index, repoConfig.IndexPath, objects, view, repoRoot, projectID, batches, repeats, jobs, summaries)
+		view.Stop()
+		if err != nil {
 			fmt.Println("error saving index:", err)
 			return nil
 		}
 
+		for _, collection := range collections {
+			deletions := deletionsByCollection[collection.Name]
+			if len(deletions) == 0 || ctx.Err() != nil {
+				continue
+			}
+
+			deleteFromCollection(ctx, client, index, projectID, collection, deletions, summaries[collection.Name])
+			if err := index.Save(repoConfig.IndexPath); err != nil {
+				fmt.Println("error saving index:", err)
+				return nil
+			}
+		}
+
 		// Print summary
 		duration := time.Since(startTime).Round(time.Millisecond)
-		fileSize := int64(0)
-		for _, file := range resp.UploadedFiles {
-			fileSize += file.Size
+		total := &pushSummary{}
+		fmt.Println()
+		for _, collection := range collections {
+			summary := summaries[collection.Name]
+			fmt.Printf("  %-20s uploaded %d files (%s), deleted %d files",
+				summary.Collection, summary.Uploaded, util.FormatSize(summary.Bytes), summary.Deleted)
+			if summary.Referenced > 0 {
+				fmt.Printf(", %d already on the server", summary.Referenced)
+			}
+			if summary.Failed > 0 {
+				color.New(color.FgRed).Printf(", %d failed", summary.Failed)
+			}
+			fmt.Println()
+
+			total.Uploaded += summary.Uploaded
+			total.Bytes += summary.Bytes
+			total.Referenced += summary.Referenced
+			total.Saved += summary.Saved
+			total.Deleted += summary.Deleted
+			total.Failed += summary.Failed
 		}
 
-		fmt.Printf("\nUploaded %d files (%s) to project '%s', collection '%s' in %s\n",
-			len(resp.UploadedFiles),
-			util.FormatSize(fileSize),
+		fmt.Printf("\nUploaded %d files (%s) and deleted %d files in project '%s' in %s\n",
+			total.Uploaded,
+			util.FormatSize(total.Bytes),
+			total.Deleted,
 			activeProject,
-			collection.Name,
 			duration,
 		)
+		if total.Saved > 0 {
+			fmt.Printf("Skipped uploading %s for %d files the server already had\n",
+				util.FormatSize(total.Saved), total.Referenced)
+		}
+		if ctx.Err() != nil {
+			color.Yellow("\nPush interrupted. Files the server confirmed are marked as synced; run 'hhx push' again to push the rest.")
+		}
+		if total.Failed > 0 {
+			color.Red("\n%d files failed to push; see 'hhx status' and run 'hhx push --retry-failed' to try them again", total.Failed)
+			return exitStatus(cmd, 1)
+		}
 
 		return nil
 	},
 }
 
+// pushSummary counts what a push changed in a single collection
+type pushSummary struct {
+	Collection string
+	Uploaded   int
+	Bytes      int64
+	Referenced int
+	Saved      int64
+	Deleted    int
+	Failed     int
+}
+
+// pushBatch is a group of files uploaded to one collection in a single request
+type pushBatch struct {
+	Collection *models.Collection
+	Files      []*models.File
+
+	// For files whose contents an earlier batch uploads, the path of the file
+	// uploaded with them
+	Originals map[string]string
+}
+
+// batchResult is the outcome of uploading a batch
+type batchResult struct {
+	Batch    *pushBatch
+	Response *api.PushResponse
+	Err      error
+}
+
+// splitBatches splits the files pushed to a collection into batches of at most
+// maxFiles files and maxBytes bytes, where zero means no limit. A file larger
+// than maxBytes gets a batch of its own.
+func splitBatches(collection *models.Collection, files []*models.File, maxFiles int, maxBytes int64) []*pushBatch {
+	var batches []*pushBatch
+	var current *pushBatch
+	var currentBytes int64
+	for _, file := range files {
+		full := current != nil && ((maxFiles > 0 && len(current.Files) >= maxFiles) ||
+			(maxBytes > 0 && currentBytes+file.Size > maxBytes))
+		if current == nil || full {
+			current = &pushBatch{Collection: collection}
+			currentBytes = 0
+			batches = append(batches, current)
+		}
+		current.Files = append(current.Files, file)
+		currentBytes += file.Size
+	}
+	return batches
+}
+
+// deferRepeats takes the files whose contents an earlier batch uploads out of
+// their batches, and returns them in batches of their own to push once the
+// others are done. Batches run at the same time, so each would otherwise
+// upload the same contents; pushed afterwards, the repeats are sent as
+// references. Repeats within a batch stay, as the batch sends those as
+// references itself.
+func deferRepeats(batches []*pushBatch) ([]*pushBatch, []*pushBatch) {
+	uploadedBy := make(map[string]string)
+	var first, later []*pushBatch
+	for _, batch := range batches {
+		kept := &pushBatch{Collection: batch.Collection}
+		repeats := &pushBatch{Collection: batch.Collection, Originals: make(map[string]string)}
+		inBatch := make(map[string]bool)
+		for _, file := range batch.Files {
+			original, uploaded := uploadedBy[file.Hash]
+			if file.Hash != "" && uploaded && !inBatch[file.Hash] {
+				repeats.Files = append(repeats.Files, file)
+				repeats.Originals[file.Path] = original
+				continue
+			}
+
+			kept.Files = append(kept.Files, file)
+			if file.Hash != "" && !uploaded {
+				uploadedBy[file.Hash] = file.Path
+				inBatch[file.Hash] = true
+			}
+		}
+
+		if len(kept.Files) > 0 {
+			first = append(first, kept)
+		}
+		if len(repeats.Files) > 0 {
+			later = append(later, repeats)
+		}
+	}
+	return first, later
+}
+
+// releaseRepeats returns the files of a deferred batch whose contents were
+// uploaded, and marks the others as failed, since they would reference
+// contents the server never received
+func releaseRepeats(index *models.Index, view *ui.ProgressView, batch *pushBatch, summary *pushSummary) *pushBatch {
+	released := &pushBatch{Collection: batch.Collection}
+	for _, file := range batch.Files {
+		original := batch.Originals[file.Path]
+		if synced, ok := index.GetSyncedFile(original); ok && synced.Hash == file.Hash {
+			released.Files = append(released.Files, file)
+			continue
+		}
+
+		message := fmt.Sprintf("not uploaded: uploading %s with the same contents failed", original)
+		view.Println(color.RedString("  %s: %s", file.Path, message))
+		index.MarkFailed(file.Path, message)
+		view.Finish(file.Path)
+		summary.Failed++
+	}
+	return released
+}
+
+// pushBatches uploads batches and then the repeats deferRepeats held back, and
+// records the result of each in the index
+func pushBatches(ctx context.Context, client *api.Client, index *models.Index, indexPath string, objects *models.ObjectStore, view *ui.ProgressView, repoRoot, projectID string, batches, repeats []*pushBatch, jobs int, summaries map[string]*pushSummary) error {
+	if err := recordBatches(ctx, client, index, indexPath, objects, view, repoRoot, projectID, batches, jobs, summaries); err != nil {
+		return err
+	}
+	if ctx.Err() != nil || len(repeats) == 0 {
+		return nil
+	}
+
+	// The server now has the contents the other batches uploaded
+	var released []*pushBatch
+	for _, batch := range repeats {
+		if batch = releaseRepeats(index, view, batch, summaries[batch.Collection.Name]); len(batch.Files) > 0 {
+			released = append(released, batch)
+		}
+	}
+	if err := index.Save(indexPath); err != nil {
+		return err
+	}
+	return recordBatches(ctx, client, index, indexPath, objects, view, repoRoot, projectID, released, jobs, summaries)
+}
+
+// recordBatches uploads batches and records the result of each in the index,
+// saving it after every batch so finished uploads aren't pushed again
+func recordBatches(ctx context.Context, client *api.Client, index *models.Index, indexPath string, objects *models.ObjectStore, view *ui.ProgressView, repoRoot, projectID string, batches []*pushBatch, jobs int, summaries map[string]*pushSummary) error {
+	for result := range uploadBatches(ctx, client, objects, repoRoot, projectID, batches, jobs) {
+		if result.Err != nil && ctx.Err() != nil {
+			// Interrupted before the server confirmed the batch; its files stay staged
+			continue
+		}
+
+		applyBatchResult(index, view, result, summaries[result.Batch.Collection.Name])
+		for _, file := range result.Batch.Files {
+			view.Finish(file.Path)
+		}
+
+		if err := index.Save(indexPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadBatches uploads batches on a number of workers and sends the result of
+// each batch as it finishes. The channel is closed once every batch is done, or
+// once the batches already started are done after ctx is cancelled.
+func uploadBatches(ctx context.Context, client *api.Client, objects *models.ObjectStore, repoRoot, projectID string, batches []*pushBatch, jobs int) <-chan *batchResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	queue := make(chan *pushBatch)
+	results := make(chan *batchResult, len(batches))
+
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				resp, err := client.PushFilesToProjectCollection(ctx, repoRoot, batch.Files, projectID, batch.Collection)
+				if err == nil && objects != nil {
+					cachePushedFiles(objects, repoRoot, batch.Files, resp)
+				}
+				results <- &batchResult{Batch: batch, Response: resp, Err: err}
+			}
+		}()
+	}
+
+	go func() {
+		// Once ctx is cancelled no more batches are started
+	feed:
+		for _, batch := range batches {
+			select {
+			case queue <- batch:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// cachePushedFiles keeps a copy of the uploaded files in the object cache; a file
+// changed since it was staged is skipped
+func cachePushedFiles(objects *models.ObjectStore, repoRoot string, files []*models.File, resp *api.PushResponse) {
+	hashes := make(map[string]string, len(files))
+	for _, file := range files {
+		hashes[file.Path] = file.Hash
+	}
+
+	for _, uploaded := range resp.UploadedFiles {
+		_ = objects.Put(hashes[uploaded.Path], filepath.Join(repoRoot, filepath.FromSlash(uploaded.Path)))
+	}
+}
+
+// applyBatchResult marks the uploaded files of a batch as synced and the others
+// as failed, and adds the outcome to the collection's summary
+func applyBatchResult(index *models.Index, view *ui.ProgressView, result *batchResult, summary *pushSummary) {
+	collectionName := result.Batch.Collection.Name
+	if result.Err != nil {
+		view.Println(color.RedString("push of %d files to collection '%s' failed: %v", len(result.Batch.Files), collectionName, result.Err))
+		for _, file := range result.Batch.Files {
+			index.MarkFailed(file.Path, result.Err.Error())
+		}
+		summary.Failed += len(result.Batch.Files)
+		return
+	}
+
+	resp := result.Response
+	if len(resp.Errors) > 0 {
+		view.Printf("Some files failed to upload to collection '%s':\n", collectionName)
+		for _, uploadErr := range resp.Errors {
+			view.Println(color.RedString("  %s: %s", uploadErr.Path, uploadErr.Error))
+			index.MarkFailed(uploadErr.Path, uploadErr.Error)
+		}
+		summary.Failed += len(resp.Errors)
+	}
+
+	// Update index with new remote URLs
+	for _, uploaded := range resp.UploadedFiles {
+		index.MarkSynced(uploaded.Path, uploaded.RemoteURL, collectionName)
+		summary.Bytes += uploaded.Size
+	}
+	// Files sent as references weren't uploaded, so their bytes don't count
+	summary.Bytes -= resp.SavedBytes
+	summary.Uploaded += len(resp.UploadedFiles)
+	summary.Referenced += resp.Referenced
+	summary.Saved += resp.SavedBytes
+}
+
+// deleteFromCollection removes files whose deletion is staged from a collection,
+// records the deletions that failed, and adds the outcome to the collection's
+// summary
+func deleteFromCollection(ctx context.Context, client *api.Client, index *models.Index, projectID string, collection *models.Collection, deletions []*models.File, summary *pushSummary) {
+	paths := make([]string, 0, len(deletions))
+	for _, file := range deletions {
+		paths = append(paths, file.Path)
+	}
+
+	resp, err := client.DeleteFilesFromProjectCollection(ctx, paths, projectID, collection)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted; the deletions stay staged
+		return
+	}
+	if err != nil {
+		color.Red("deleting files from collection '%s' failed: %v\n", collection.Name, err)
+		for _, file := range deletions {
+			index.MarkFailed(file.Path, err.Error())
+		}
+		summary.Failed += len(deletions)
+		return
+	}
+
+	if len(resp.Errors) > 0 {
+		fmt.Printf("Some files failed to delete from collection '%s':\n", collection.Name)
+		for _, deleteErr := range resp.Errors {
+			color.Red("  %s: %s\n", deleteErr.Path, deleteErr.Error)
+			index.MarkFailed(deleteErr.Path, deleteErr.Error)
+		}
+		summary.Failed += len(resp.Errors)
+	}
+
+	// Forget deletions the server has confirmed
+	for _, path := range resp.DeletedFiles {
+		index.ConfirmDeletion(path)
+	}
+	summary.Deleted += len(resp.DeletedFiles)
+}
+
 func init() {
 	rootCmd.AddCommand(pushCmd)
 
 	pushCmd.Flags().Bool("non-interactive", false, "Do not prompt for login")
-	pushCmd.Flags().String("collection", "", "Collection to push to (defaults to the default collection)")
+	pushCmd.Flags().String("collection", "", "Collection for files not tagged or routed to one (defaults to the default collection)")
 	pushCmd.Flags().String("project", "", "Project to push to (overrides the linked project)")
+	pushCmd.Flags().Bool("rehash", false, "Rehash every file instead of trusting unchanged size and modification times")
+	pushCmd.Flags().Int("upload-jobs", 4, "Number of batches to upload in parallel")
+	pushCmd.Flags().Int("batch-size", 100, "Maximum number of files per upload request (0 for no limit)")
+	pushCmd.Flags().String("batch-bytes", "256MB", "Maximum total size of the files in an upload request, e.g. 64MB (0 for no limit)")
+	pushCmd.Flags().Bool("retry-failed", false, "Push only the files and deletions whose last push failed")
+	pushCmd.Flags().Bool("dry-run", false, "Show what would be pushed without uploading or deleting anything")
+	pushCmd.Flags().Bool("json", false, "With --dry-run, print the plan as JSON")
 }
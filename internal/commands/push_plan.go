@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/api"
+	"hhx/internal/models"
+	"hhx/internal/util"
+	"path"
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// Actions a push plan lists for a file
+const (
+	planNew       = "new"
+	planModified  = "modified"
+	planDuplicate = "duplicate"
+	planDelete    = "delete"
+)
+
+// pushPlan is what 'hhx push --dry-run' reports a push would do
+type pushPlan struct {
+	Remote      string               `json:"remote"`
+	Project     string               `json:"project"`
+	ProjectID   string               `json:"project_id"`
+	Collections []pushPlanCollection `json:"collections"`
+	Files       []pushPlanFile       `json:"files"`
+	Totals      pushPlanTotals       `json:"totals"`
+}
+
+// pushPlanCollection is a collection a push would change
+type pushPlanCollection struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// pushPlanFile is what a push would do with one file
+type pushPlanFile struct {
+	Action     string `json:"action"`
+	Path       string `json:"path"`
+	Collection string `json:"collection"`
+	RemotePath string `json:"remote_path"`
+	Size       int64  `json:"size"`
+}
+
+// pushPlanTotals counts the files of a push plan by action
+type pushPlanTotals struct {
+	New            int   `json:"new"`
+	Modified       int   `json:"modified"`
+	Duplicates     int   `json:"duplicates"`
+	Deletions      int   `json:"deletions"`
+	UploadBytes    int64 `json:"upload_bytes"`
+	DuplicateBytes int64 `json:"duplicate_bytes"`
+}
+
+// planPush works out what pushing the batches and deletions would do, asking the
+// server only questions that don't change anything
+func planPush(ctx context.Context, client *api.Client, remote, project, projectID string, collections []*models.Collection, batches []*pushBatch, deletionsByCollection map[string][]*models.File) (*pushPlan, error) {
+	plan := &pushPlan{
+		Remote:      remote,
+		Project:     project,
+		ProjectID:   projectID,
+		Collections: []pushPlanCollection{},
+		Files:       []pushPlanFile{},
+	}
+
+	// Batches are listed in order, and a push uploads the contents of the
+	// first file that has them
+	filesByCollection := make(map[string][]*models.File)
+	for _, batch := range batches {
+		name := batch.Collection.Name
+		filesByCollection[name] = append(filesByCollection[name], batch.Files...)
+	}
+
+	remotePlan, err := client.PlanPush(ctx, projectID, collections, filesByCollection)
+	if err != nil {
+		return nil, fmt.Errorf("planning push: %w", err)
+	}
+
+	for _, collection := range collections {
+		plan.Collections = append(plan.Collections, pushPlanCollection{
+			Name:   collection.Name,
+			Path:   collection.Path,
+			Exists: remotePlan.CollectionExists[collection.Name],
+		})
+
+		var files []pushPlanFile
+		for _, file := range filesByCollection[collection.Name] {
+			action := planNew
+			switch {
+			case remotePlan.References[file.Path]:
+				action = planDuplicate
+			case file.RemoteURL != "":
+				action = planModified
+			}
+			files = append(files, newPushPlanFile(action, collection, file))
+		}
+		for _, file := range deletionsByCollection[collection.Name] {
+			files = append(files, newPushPlanFile(planDelete, collection, file))
+		}
+
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		})
+		plan.Files = append(plan.Files, files...)
+	}
+
+	for _, file := range plan.Files {
+		switch file.Action {
+		case planNew:
+			plan.Totals.New++
+			plan.Totals.UploadBytes += file.Size
+		case planModified:
+			plan.Totals.Modified++
+			plan.Totals.UploadBytes += file.Size
+		case planDuplicate:
+			plan.Totals.Duplicates++
+			plan.Totals.DuplicateBytes += file.Size
+		case planDelete:
+			plan.Totals.Deletions++
+		}
+	}
+
+	return plan, nil
+}
+
+// newPushPlanFile describes a file of a push plan
+func newPushPlanFile(action string, collection *models.Collection, file *models.File) pushPlanFile {
+	return pushPlanFile{
+		Action:     action,
+		Path:       file.Path,
+		Collection: collection.Name,
+		RemotePath: path.Join(collection.Path, file.Path),
+		Size:       file.Size,
+	}
+}
+
+// printPushPlan prints a push plan as a table, or as JSON
+func printPushPlan(plan *pushPlan, asJSON bool) {
+	if asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			fmt.Println("error encoding push plan:", err)
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("Dry run: nothing will be uploaded to or deleted from project '%s' on '%s'.\n\n", plan.Project, plan.Remote)
+	if len(plan.Files) == 0 {
+		fmt.Println("No files to push.")
+		return
+	}
+
+	for _, collection := range plan.Collections {
+		if !collection.Exists {
+			color.Red("Collection '%s' does not exist on the remote server; pushing to it would fail.\n", collection.Name)
+		}
+	}
+
+	for _, file := range plan.Files {
+		action := fmt.Sprintf("%-10s", file.Action)
+		switch file.Action {
+		case planNew:
+			action = color.GreenString(action)
+		case planModified:
+			action = color.YellowString(action)
+		case planDuplicate:
+			action = color.CyanString(action)
+		case planDelete:
+			action = color.RedString(action)
+		}
+		fmt.Printf("  %s %-15s %10s  %s\n", action, file.Collection, util.FormatSize(file.Size), file.RemotePath)
+	}
+
+	totals := plan.Totals
+	fmt.Printf("\nWould upload %d new and %d modified files (%s), skip %d duplicates (%s) and delete %d files in %d collections\n",
+		totals.New, totals.Modified, util.FormatSize(totals.UploadBytes),
+		totals.Duplicates, util.FormatSize(totals.DuplicateBytes),
+		totals.Deletions, len(plan.Collections))
+}
@@ -0,0 +1,575 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPushTest stages files with the given contents in a new repository and
+// returns its index, the index path, and the staged files in path order
+func newPushTest(t *testing.T, contents map[string]string) (*models.Index, string, []*models.File) {
+	t.Helper()
+
+	repoRoot := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repoRoot, ".hhx"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	index := models.NewIndex(repoRoot)
+	stageTestFiles(t, index, repoRoot, contents)
+
+	files := index.GetStagedFiles()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return index, filepath.Join(repoRoot, ".hhx", "index"), files
+}
+
+// splitByCollection puts files in batches of one file, pushed to the
+// collection named by the first directory of their path
+func splitByCollection(files []*models.File) ([]*models.Collection, []*pushBatch, map[string]*pushSummary) {
+	var collections []*models.Collection
+	var batches []*pushBatch
+	summaries := make(map[string]*pushSummary)
+	for _, file := range files {
+		name := strings.SplitN(file.Path, "/", 2)[0]
+		if summaries[name] == nil {
+			summaries[name] = &pushSummary{Collection: name}
+			collections = append(collections, &models.Collection{Name: name, Type: models.CollectionTypeBucket, Path: name})
+		}
+		collection := collections[len(collections)-1]
+		batches = append(batches, splitBatches(collection, []*models.File{file}, 1, 0)...)
+	}
+	return collections, batches, summaries
+}
+
+func TestPushUploadsRepeatedContentsOnce(t *testing.T) {
+	server := newTestServer(t, "data", "models")
+	client := newTestClient(t, server)
+	index, indexPath, files := newPushTest(t, map[string]string{
+		"data/c.bin":   "same",
+		"data/d.bin":   "other",
+		"models/a.bin": "same",
+		"models/b.bin": "same",
+	})
+
+	_, batches, summaries := splitByCollection(files)
+	batches, repeats := deferRepeats(batches)
+	if len(batches) != 2 || len(repeats) != 2 {
+		t.Fatalf("%d batches and %d deferred, want 2 and 2", len(batches), len(repeats))
+	}
+
+	err := pushBatches(context.Background(), client, index, indexPath, nil, nil, index.RepoRoot, testProjectID, batches, repeats, 4, summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first file with the contents uploads them; the rest reference them
+	if len(server.received) != 2 || server.received["data/c.bin"] != 1 || server.received["data/d.bin"] != 1 {
+		t.Errorf("server received contents of %v, want data/c.bin and data/d.bin once", server.received)
+	}
+	sort.Strings(server.references)
+	if got := strings.Join(server.references, " "); got != "models/a.bin models/b.bin" {
+		t.Errorf("references sent: %s", got)
+	}
+
+	for _, file := range files {
+		if _, ok := index.GetSyncedFile(file.Path); !ok {
+			t.Errorf("%s not synced", file.Path)
+		}
+	}
+	if summary := summaries["models"]; summary.Uploaded != 2 || summary.Referenced != 2 || summary.Bytes != 0 {
+		t.Errorf("models summary: %+v, want 2 referenced files and no bytes uploaded", summary)
+	}
+}
+
+func TestPushFailsRepeatsOfFailedUpload(t *testing.T) {
+	server := newTestServer(t, "data", "models")
+	client := newTestClient(t, server)
+	index, indexPath, files := newPushTest(t, map[string]string{
+		"data/c.bin":   "same",
+		"models/a.bin": "same",
+	})
+	server.rejected["data/c.bin"] = "disk full"
+
+	_, batches, summaries := splitByCollection(files)
+	batches, repeats := deferRepeats(batches)
+	err := pushBatches(context.Background(), client, index, indexPath, nil, nil, index.RepoRoot, testProjectID, batches, repeats, 4, summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(server.references) != 0 {
+		t.Errorf("references sent to contents the server never received: %v", server.references)
+	}
+
+	failed := index.GetFailedFiles()
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Path < failed[j].Path
+	})
+	if len(failed) != 2 {
+		t.Fatalf("%d files failed, want 2", len(failed))
+	}
+	if failed[0].Path != "data/c.bin" || failed[0].Error != "disk full" {
+		t.Errorf("data/c.bin failed with %q", failed[0].Error)
+	}
+	if failed[1].Path != "models/a.bin" || !strings.Contains(failed[1].Error, "data/c.bin") {
+		t.Errorf("models/a.bin failed with %q, want it to name data/c.bin", failed[1].Error)
+	}
+	if summaries["models"].Failed != 1 {
+		t.Errorf("models summary: %+v, want 1 failed file", summaries["models"])
+	}
+
+	// The failures were saved
+	saved, err := models.LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.GetFailedFiles()) != 2 {
+		t.Errorf("%d failed files saved, want 2", len(saved.GetFailedFiles()))
+	}
+}
+
+func TestPlanPushFindsRepeatsAcrossBatches(t *testing.T) {
+	server := newTestServer(t, "data", "models")
+	client := newTestClient(t, server)
+	_, _, files := newPushTest(t, map[string]string{
+		"data/c.bin":   "same",
+		"data/d.bin":   "on server",
+		"models/a.bin": "same",
+	})
+	server.existing[files[1].Hash] = true
+
+	collections, batches, _ := splitByCollection(files)
+	plan, err := planPush(context.Background(), client, "origin", "project", testProjectID, collections, batches, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"data/c.bin":   planNew,
+		"data/d.bin":   planDuplicate,
+		"models/a.bin": planDuplicate,
+	}
+	for _, file := range plan.Files {
+		if file.Action != want[file.Path] {
+			t.Errorf("%s planned as %s, want %s", file.Path, file.Action, want[file.Path])
+		}
+	}
+	if plan.Totals.New != 1 || plan.Totals.Duplicates != 2 {
+		t.Errorf("totals: %+v, want 1 new and 2 duplicates", plan.Totals)
+	}
+	if server.forms != 0 {
+		t.Errorf("dry run sent %d forms", server.forms)
+	}
+}
+
+func TestDeleteFromCollectionConfirmsDeletions(t *testing.T) {
+	server := newTestServer(t, "data")
+	client := newTestClient(t, server)
+	index := models.NewIndex(t.TempDir())
+	for _, path := range []string{"data/a.bin", "data/b.bin"} {
+		index.RecordSynced(&models.File{Path: path, Hash: hashOf(path), Collection: "data"})
+		if err := index.StageDeletion(filepath.Join(index.RepoRoot, filepath.FromSlash(path))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.rejected["data/b.bin"] = "locked"
+
+	collection := &models.Collection{Name: "data", Type: models.CollectionTypeBucket, Path: "data"}
+	summary := &pushSummary{Collection: "data"}
+	deleteFromCollection(context.Background(), client, index, testProjectID, collection, index.GetStagedDeletions(), summary)
+
+	if len(server.deleted) != 1 || server.deleted[0] != "data/a.bin" {
+		t.Errorf("server deleted %v, want data/a.bin", server.deleted)
+	}
+	if summary.Deleted != 1 || summary.Failed != 1 {
+		t.Errorf("summary: %+v, want 1 deleted and 1 failed", summary)
+	}
+
+	// The confirmed deletion is forgotten; the rejected one stays staged
+	if _, ok := index.GetTrackedFile("data/a.bin"); ok {
+		t.Error("data/a.bin still tracked after the server deleted it")
+	}
+	deletions := index.GetStagedDeletions()
+	if len(deletions) != 1 || deletions[0].Path != "data/b.bin" || deletions[0].Error != "locked" {
+		t.Errorf("staged deletions: %+v, want data/b.bin failed with its error", deletions)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	collection := &models.Collection{Name: "data"}
+	files := func(sizes ...int64) []*models.File {
+		var files []*models.File
+		for i, size := range sizes {
+			files = append(files, &models.File{Path: fmt.Sprintf("f%d", i), Size: size})
+		}
+		return files
+	}
+
+	tests := []struct {
+		name     string
+		files    []*models.File
+		maxFiles int
+		maxBytes int64
+		want     []int
+	}{
+		{name: "no limits", files: files(1, 2, 3), want: []int{3}},
+		{name: "file limit", files: files(1, 1, 1, 1, 1), maxFiles: 2, want: []int{2, 2, 1}},
+		{name: "byte limit", files: files(4, 4, 4), maxBytes: 10, want: []int{2, 1}},
+		{name: "file over the byte limit on its own", files: files(2, 50, 2), maxBytes: 10, want: []int{1, 1, 1}},
+		{name: "both limits", files: files(1, 1, 1, 8, 1), maxFiles: 2, maxBytes: 10, want: []int{2, 2, 1}},
+		{name: "no files", files: nil, maxFiles: 2, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := splitBatches(collection, tt.files, tt.maxFiles, tt.maxBytes)
+
+			var got []int
+			var paths []string
+			for _, batch := range batches {
+				got = append(got, len(batch.Files))
+				for _, file := range batch.Files {
+					paths = append(paths, file.Path)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("batch sizes %v, want %v", got, tt.want)
+			}
+			for i, path := range paths {
+				if path != tt.files[i].Path {
+					t.Errorf("batches hold %v, want the files in order", paths)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPushKeepsBatchesThatSucceeded(t *testing.T) {
+	server := newTestServer(t, "data", "models")
+	client := newTestClient(t, server)
+	index, indexPath, files := newPushTest(t, map[string]string{
+		"data/a.bin":   "a",
+		"data/b.bin":   "b",
+		"data/c.bin":   "c",
+		"models/d.bin": "d",
+	})
+	server.broken["models"] = "quota exceeded"
+
+	_, batches, summaries := splitByCollection(files)
+	err := pushBatches(context.Background(), client, index, indexPath, nil, nil, index.RepoRoot, testProjectID, batches, nil, 2, summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.forms != 3 {
+		t.Errorf("server received %d forms, want one per data batch", server.forms)
+	}
+
+	// What was saved is what survives a crash after the push
+	saved, err := models.LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"data/a.bin", "data/b.bin", "data/c.bin"} {
+		if synced, ok := saved.GetSyncedFile(path); !ok || synced.RemoteURL != "/files/"+path || synced.Collection != "data" {
+			t.Errorf("%s not saved as synced to data: %+v", path, synced)
+		}
+	}
+	failed := saved.GetFailedFiles()
+	if len(failed) != 1 || failed[0].Path != "models/d.bin" || !strings.Contains(failed[0].Error, "quota exceeded") {
+		t.Errorf("failed files: %+v, want models/d.bin with the server's error", failed)
+	}
+
+	if summary := summaries["data"]; summary.Uploaded != 3 || summary.Bytes != 3 || summary.Failed != 0 {
+		t.Errorf("data summary: %+v, want 3 files and 3 bytes uploaded", summary)
+	}
+	if summary := summaries["models"]; summary.Uploaded != 0 || summary.Failed != 1 {
+		t.Errorf("models summary: %+v, want 1 failed file", summary)
+	}
+}
+
+func TestPushUploadsBatchesInParallel(t *testing.T) {
+	server := newTestServer(t, "data")
+	client := newTestClient(t, server)
+	index, indexPath, files := newPushTest(t, map[string]string{
+		"data/a.bin": "a",
+		"data/b.bin": "b",
+		"data/c.bin": "c",
+		"data/d.bin": "d",
+		"data/e.bin": "e",
+	})
+	server.formDelay = 50 * time.Millisecond
+
+	_, batches, summaries := splitByCollection(files)
+	err := pushBatches(context.Background(), client, index, indexPath, nil, nil, index.RepoRoot, testProjectID, batches, nil, 2, summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if server.maxInFlight != 2 {
+		t.Errorf("server handled up to %d batches at once, want 2", server.maxInFlight)
+	}
+	if summaries["data"].Uploaded != 5 {
+		t.Errorf("data summary: %+v, want 5 files uploaded", summaries["data"])
+	}
+}
+
+func TestInterruptedPushKeepsConfirmedBatches(t *testing.T) {
+	server := newTestServer(t, "data")
+	client := newTestClient(t, server)
+	index, indexPath, files := newPushTest(t, map[string]string{
+		"data/a.bin": "a",
+		"data/b.bin": "b",
+		"data/c.bin": "c",
+	})
+
+	// Ctrl-C while the second batch is being sent
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.beforeForm = func(r *http.Request, form int) bool {
+		if form != 2 {
+			return true
+		}
+		cancel()
+
+		// Wait until the client gives up on the request
+		_, _ = io.Copy(io.Discard, r.Body)
+		return false
+	}
+
+	_, batches, summaries := splitByCollection(files)
+	err := pushBatches(ctx, client, index, indexPath, nil, nil, index.RepoRoot, testProjectID, batches, nil, 1, summaries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.arrived != 2 {
+		t.Errorf("%d batches sent, want none started after the interruption", server.arrived)
+	}
+
+	saved, err := models.LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := saved.GetSyncedFile("data/a.bin"); !ok {
+		t.Error("data/a.bin, confirmed before the interruption, not saved as synced")
+	}
+	staged := saved.GetStagedFiles()
+	if len(staged) != 2 {
+		t.Fatalf("%d files still staged, want data/b.bin and data/c.bin", len(staged))
+	}
+	if failed := saved.GetFailedFiles(); len(failed) != 0 {
+		t.Errorf("interrupted files recorded as failed: %+v", failed)
+	}
+	if summaries["data"].Uploaded != 1 || summaries["data"].Failed != 0 {
+		t.Errorf("data summary: %+v, want 1 file uploaded", summaries["data"])
+	}
+}
+
+func TestInterruptedDeletionStaysStaged(t *testing.T) {
+	server := newTestServer(t, "data")
+	client := newTestClient(t, server)
+	index := models.NewIndex(t.TempDir())
+	index.RecordSynced(&models.File{Path: "data/a.bin", Hash: hashOf("a"), Collection: "data"})
+	if err := index.StageDeletion(filepath.Join(index.RepoRoot, "data", "a.bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	collection := &models.Collection{Name: "data", Type: models.CollectionTypeBucket, Path: "data"}
+	summary := &pushSummary{Collection: "data"}
+	deleteFromCollection(ctx, client, index, testProjectID, collection, index.GetStagedDeletions(), summary)
+
+	deletions := index.GetStagedDeletions()
+	if len(deletions) != 1 || deletions[0].Error != "" {
+		t.Errorf("staged deletions: %+v, want data/a.bin staged without an error", deletions)
+	}
+	if len(server.deleted) != 0 || summary.Failed != 0 {
+		t.Errorf("server deleted %v and %d deletions failed, want neither", server.deleted, summary.Failed)
+	}
+}
+
+// newPushRepo creates a repository pushing to the stand-in server's data
+// collection, with files staged with the given contents
+func newPushRepo(t *testing.T, server *testServer, contents map[string]string) string {
+	t.Helper()
+
+	return newCommandRepo(t, server, func(repoRoot string) *models.Index {
+		index := models.NewIndex(repoRoot)
+		if err := index.AddCollection(&models.Collection{Name: "data", Type: models.CollectionTypeBucket, Path: "data"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := index.SetDefaultCollection("data"); err != nil {
+			t.Fatal(err)
+		}
+		stageTestFiles(t, index, repoRoot, contents)
+		return index
+	})
+}
+
+func TestPushRetryFailed(t *testing.T) {
+	server := newTestServer(t, "data")
+	cfg := loginTestHome(t, server)
+	repoRoot := newPushRepo(t, server, map[string]string{"a.bin": "a", "b.bin": "b"})
+	server.rejected["b.bin"] = "disk full"
+
+	// A failed file fails the command, so CI notices
+	err := runCommand(t, cfg, repoRoot, "push")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Fatalf("push with a failed file returned %v, want exit status 1", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runCommand(t, cfg, repoRoot, "status"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(output, "Failed to upload:") || !strings.Contains(output, "disk full") {
+		t.Errorf("status doesn't show the failed upload:\n%s", output)
+	}
+
+	// A file staged since isn't part of the retry
+	index := loadCommandIndex(t, repoRoot)
+	stageTestFiles(t, index, repoRoot, map[string]string{"c.bin": "c"})
+	if err := index.Save(filepath.Join(repoRoot, ".hhx", "index")); err != nil {
+		t.Fatal(err)
+	}
+	delete(server.rejected, "b.bin")
+
+	resetFlags(rootCmd)
+	if err := runCommand(t, cfg, repoRoot, "push", "--retry-failed"); err != nil {
+		t.Fatalf("retry returned %v, want success", err)
+	}
+
+	if server.received["a.bin"] != 1 || server.received["b.bin"] != 1 || server.received["c.bin"] != 0 {
+		t.Errorf("server received %v, want a.bin then b.bin once each", server.received)
+	}
+	index = loadCommandIndex(t, repoRoot)
+	if _, ok := index.GetSyncedFile("b.bin"); !ok {
+		t.Error("b.bin not synced after the retry")
+	}
+	if failed := index.GetFailedFiles(); len(failed) != 0 {
+		t.Errorf("failed files after the retry: %+v", failed)
+	}
+	if staged := index.GetStagedFiles(); len(staged) != 1 || staged[0].Path != "c.bin" {
+		t.Errorf("staged files: %+v, want c.bin left staged", staged)
+	}
+}
+
+func TestPushDryRun(t *testing.T) {
+	server := newTestServer(t, "data")
+	cfg := loginTestHome(t, server)
+	server.existing[hashOf("on server")] = true
+
+	repoRoot := newCommandRepo(t, server, func(repoRoot string) *models.Index {
+		index := models.NewIndex(repoRoot)
+		for _, name := range []string{"data", "models"} {
+			if err := index.AddCollection(&models.Collection{Name: name, Type: models.CollectionTypeBucket, Path: name + "-bucket"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := index.SetDefaultCollection("data"); err != nil {
+			t.Fatal(err)
+		}
+		// models isn't on the server
+		if err := index.AddRoute("*.pt", "models"); err != nil {
+			t.Fatal(err)
+		}
+
+		index.RecordSynced(&models.File{Path: "changed.csv", Hash: hashOf("old"), RemoteURL: "/files/changed.csv", Collection: "data"})
+		index.RecordSynced(&models.File{Path: "removed.csv", Hash: hashOf("removed"), Size: 7, RemoteURL: "/files/removed.csv", Collection: "data"})
+		if err := index.StageDeletion(filepath.Join(repoRoot, "removed.csv")); err != nil {
+			t.Fatal(err)
+		}
+		stageTestFiles(t, index, repoRoot, map[string]string{
+			"added.csv":   "added",
+			"changed.csv": "new",
+			"copy.csv":    "on server",
+			"net.pt":      "weights",
+		})
+		return index
+	})
+
+	output := captureStdout(t, func() {
+		if err := runCommand(t, cfg, repoRoot, "push", "--dry-run", "--json"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var plan pushPlan
+	if err := json.Unmarshal([]byte(output), &plan); err != nil {
+		t.Fatalf("dry run didn't print only JSON: %v\n%s", err, output)
+	}
+
+	want := []pushPlanFile{
+		{Action: planNew, Path: "added.csv", Collection: "data", RemotePath: "data-bucket/added.csv", Size: 5},
+		{Action: planModified, Path: "changed.csv", Collection: "data", RemotePath: "data-bucket/changed.csv", Size: 3},
+		{Action: planDuplicate, Path: "copy.csv", Collection: "data", RemotePath: "data-bucket/copy.csv", Size: 9},
+		{Action: planDelete, Path: "removed.csv", Collection: "data", RemotePath: "data-bucket/removed.csv", Size: 7},
+		{Action: planNew, Path: "net.pt", Collection: "models", RemotePath: "models-bucket/net.pt", Size: 7},
+	}
+	if fmt.Sprint(plan.Files) != fmt.Sprint(want) {
+		t.Errorf("planned files:\n%+v\nwant:\n%+v", plan.Files, want)
+	}
+	wantTotals := pushPlanTotals{New: 2, Modified: 1, Duplicates: 1, Deletions: 1, UploadBytes: 15, DuplicateBytes: 9}
+	if plan.Totals != wantTotals {
+		t.Errorf("totals: %+v, want %+v", plan.Totals, wantTotals)
+	}
+	if len(plan.Collections) != 2 || !plan.Collections[0].Exists || plan.Collections[1].Exists {
+		t.Errorf("collections: %+v, want data on the server and models missing", plan.Collections)
+	}
+	if plan.ProjectID != testProjectID || plan.Remote != "origin" {
+		t.Errorf("plan for project %s on %s", plan.ProjectID, plan.Remote)
+	}
+
+	// Nothing was sent or recorded
+	if server.arrived != 0 || len(server.deleted) != 0 {
+		t.Errorf("dry run sent %d forms and deleted %v", server.arrived, server.deleted)
+	}
+	index := loadCommandIndex(t, repoRoot)
+	if len(index.GetStagedFiles()) != 4 || len(index.GetStagedDeletions()) != 1 {
+		t.Errorf("dry run changed the staging area: %d files and %d deletions staged",
+			len(index.GetStagedFiles()), len(index.GetStagedDeletions()))
+	}
+}
+
+func TestPrintPushPlan(t *testing.T) {
+	plan := &pushPlan{
+		Remote:      "origin",
+		Project:     "project",
+		Collections: []pushPlanCollection{{Name: "data", Path: "data", Exists: true}},
+		Files: []pushPlanFile{
+			{Action: planNew, Path: "a.csv", Collection: "data", RemotePath: "data/a.csv", Size: 2048},
+		},
+		Totals: pushPlanTotals{New: 1, UploadBytes: 2048},
+	}
+
+	output := captureStdout(t, func() { printPushPlan(plan, false) })
+	for _, want := range []string{
+		"Dry run: nothing will be uploaded to or deleted from project 'project' on 'origin'.",
+		"data/a.csv",
+		"Would upload 1 new and 0 modified files (2.00 KB), skip 0 duplicates (0 B) and delete 0 files in 1 collections",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("plan output lacks %q:\n%s", want, output)
+		}
+	}
+
+	output = captureStdout(t, func() { printPushPlan(&pushPlan{Remote: "origin", Project: "project"}, false) })
+	if !strings.Contains(output, "No files to push.") {
+		t.Errorf("empty plan output:\n%s", output)
+	}
+}
@@ -0,0 +1,424 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// remoteNamePattern is what a remote name may look like
+var remoteNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// remoteCmd represents the remote command
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Manage the servers the repository pushes to and pulls from",
+	Long: `Manage the named servers, or remotes, of the repository.
+
+Push, pull and other commands use the current remote unless given another by name.
+Each remote can be linked to its own project with --project; remotes without one
+use the project the repository is linked to.`,
+	Example: `  hhx remote list -v
+  hhx remote add backup https://backup.example.com --project my-project
+  hhx remote use backup`,
+}
+
+// remoteAddCmd represents the remote add command
+var remoteAddCmd = &cobra.Command{
+	Use:   "add [name] [url]",
+	Short: "Add a remote",
+	Long: `Add a remote server under a name. With --project the remote is linked to its own
+project, given by name or ID; names are looked up on the new remote.`,
+	Example: `  hhx remote add backup https://backup.example.com
+  hhx remote add staging https://staging.example.com --project my-project`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, remoteURL := args[0], args[1]
+		project, _ := cmd.Flags().GetString("project")
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		if err := validateRemoteName(name); err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		if _, ok := repoConfig.Remotes[name]; ok {
+			fmt.Printf("Error: remote '%s' already exists\n", name)
+			return nil
+		}
+		if err := validateRemoteURL(remoteURL); err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		projectID := ""
+		if project != "" {
+			projectID, err = findRemoteProjectID(cmd.Context(), remoteURL, project)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return nil
+			}
+		}
+
+		if repoConfig.Remotes == nil {
+			repoConfig.Remotes = make(map[string]string)
+		}
+		repoConfig.Remotes[name] = remoteURL
+		setRemoteProject(repoConfig, name, projectID)
+
+		if err := config.SaveRepoConfig(repoConfig); err != nil {
+			fmt.Println("error saving repository config:", err)
+			return nil
+		}
+
+		if projectID != "" {
+			fmt.Printf("Added remote '%s' (%s), linked to project %s\n", name, remoteURL, projectID)
+		} else {
+			fmt.Printf("Added remote '%s' (%s)\n", name, remoteURL)
+		}
+		return nil
+	},
+}
+
+// remoteRemoveCmd represents the remote remove command
+var remoteRemoveCmd = &cobra.Command{
+	Use:     "remove [name]",
+	Aliases: []string{"rm"},
+	Short:   "Remove a remote",
+	Long:    `Remove a remote and its linked project. The current remote can't be removed; switch to another with 'hhx remote use' first.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		if _, ok := repoConfig.Remotes[name]; !ok {
+			fmt.Println("Error: unknown remote:", name)
+			return nil
+		}
+		if name == repoConfig.CurrentRemote {
+			fmt.Printf("Error: '%s' is the current remote. Switch to another with 'hhx remote use <name>' first\n", name)
+			return nil
+		}
+
+		delete(repoConfig.Remotes, name)
+		setRemoteProject(repoConfig, name, "")
+
+		if err := config.SaveRepoConfig(repoConfig); err != nil {
+			fmt.Println("error saving repository config:", err)
+			return nil
+		}
+
+		fmt.Printf("Removed remote '%s'\n", name)
+		return nil
+	},
+}
+
+// remoteRenameCmd represents the remote rename command
+var remoteRenameCmd = &cobra.Command{
+	Use:   "rename [old] [new]",
+	Short: "Rename a remote",
+	Long:  `Rename a remote, keeping its URL and linked project. If it is the current remote, it stays current.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldName, newName := args[0], args[1]
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		remoteURL, ok := repoConfig.Remotes[oldName]
+		if !ok {
+			fmt.Println("Error: unknown remote:", oldName)
+			return nil
+		}
+		if err := validateRemoteName(newName); err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		if _, ok := repoConfig.Remotes[newName]; ok {
+			fmt.Printf("Error: remote '%s' already exists\n", newName)
+			return nil
+		}
+
+		projectID := repoConfig.RemoteProjects[oldName]
+		delete(repoConfig.Remotes, oldName)
+		setRemoteProject(repoConfig, oldName, "")
+		repoConfig.Remotes[newName] = remoteURL
+		setRemoteProject(repoConfig, newName, projectID)
+		if repoConfig.CurrentRemote == oldName {
+			repoConfig.CurrentRemote = newName
+		}
+
+		if err := config.SaveRepoConfig(repoConfig); err != nil {
+			fmt.Println("error saving repository config:", err)
+			return nil
+		}
+
+		fmt.Printf("Renamed remote '%s' to '%s'\n", oldName, newName)
+		return nil
+	},
+}
+
+// remoteSetURLCmd represents the remote set-url command
+var remoteSetURLCmd = &cobra.Command{
+	Use:   "set-url [name] [url]",
+	Short: "Change the URL of a remote",
+	Long: `Change the URL of a remote. With --project the remote is also linked to a project,
+given by name or ID; names are looked up on the new URL.`,
+	Example: `  hhx remote set-url origin https://hhx.example.com
+  hhx remote set-url backup https://backup.example.com --project my-project`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, remoteURL := args[0], args[1]
+		project, _ := cmd.Flags().GetString("project")
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		if _, ok := repoConfig.Remotes[name]; !ok {
+			fmt.Println("Error: unknown remote:", name)
+			return nil
+		}
+		if err := validateRemoteURL(remoteURL); err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		if project != "" {
+			projectID, err := findRemoteProjectID(cmd.Context(), remoteURL, project)
+			if err != nil {
+				fmt.Println("Error:", err)
+				return nil
+			}
+			setRemoteProject(repoConfig, name, projectID)
+		}
+		repoConfig.Remotes[name] = remoteURL
+
+		if err := config.SaveRepoConfig(repoConfig); err != nil {
+			fmt.Println("error saving repository config:", err)
+			return nil
+		}
+
+		fmt.Printf("Remote '%s' now points to %s\n", name, remoteURL)
+		return nil
+	},
+}
+
+// remoteUseCmd represents the remote use command
+var remoteUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Make a remote the current one",
+	Long:  `Make a remote the one push, pull and other commands use when no remote is given.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		if _, ok := repoConfig.Remotes[name]; !ok {
+			fmt.Println("Error: unknown remote:", name)
+			return nil
+		}
+
+		repoConfig.CurrentRemote = name
+		if err := config.SaveRepoConfig(repoConfig); err != nil {
+			fmt.Println("error saving repository config:", err)
+			return nil
+		}
+
+		fmt.Printf("Switched to remote '%s' (%s)\n", name, repoConfig.Remotes[name])
+		return nil
+	},
+}
+
+// remoteListCmd represents the remote list command
+var remoteListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List remotes",
+	Long: `List the remotes of the repository; the current one is marked with '*'.
+With --verbose each remote's URL and project are shown, and each URL is checked
+to be reachable.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		repoConfig, err := loadRemoteConfig()
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+
+		if len(repoConfig.Remotes) == 0 {
+			fmt.Println("No remotes. Add one with 'hhx remote add <name> <url>'.")
+			return nil
+		}
+
+		names := make([]string, 0, len(repoConfig.Remotes))
+		for name := range repoConfig.Remotes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var reachable map[string]error
+		if verbose {
+			reachable = checkRemotes(cmd.Context(), repoConfig.Remotes)
+		}
+
+		for _, name := range names {
+			marker := " "
+			if name == repoConfig.CurrentRemote {
+				marker = "*"
+			}
+
+			if !verbose {
+				fmt.Printf("%s %s\n", marker, name)
+				continue
+			}
+
+			project := repoConfig.ProjectFor(name)
+			if project == "" {
+				project = "-"
+			}
+			fmt.Printf("%s %-15s %-40s project: %s  ", marker, name, repoConfig.Remotes[name], project)
+			if err := reachable[name]; err != nil {
+				color.Red("unreachable: %v\n", err)
+			} else {
+				color.Green("reachable\n")
+			}
+		}
+
+		return nil
+	},
+}
+
+// loadRemoteConfig loads the config of the repository the command runs in
+func loadRemoteConfig() (*config.RepoConfig, error) {
+	if _, err := findRepoRoot(); err != nil {
+		return nil, fmt.Errorf("could not find repo root: %w", err)
+	}
+
+	repoConfig, err := config.LoadRepoConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading repository config: %w", err)
+	}
+	return repoConfig, nil
+}
+
+// validateRemoteName checks that a name can be used for a remote. 'all' is
+// taken by 'hhx push all'.
+func validateRemoteName(name string) error {
+	if !remoteNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid remote name '%s': use letters, digits, '.', '_' and '-'", name)
+	}
+	if name == "all" {
+		return fmt.Errorf("'all' can't be used as a remote name")
+	}
+	return nil
+}
+
+// validateRemoteURL checks that a URL points to an HTTP or HTTPS server
+func validateRemoteURL(remoteURL string) error {
+	parsed, err := url.Parse(remoteURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid remote URL '%s': expected http:// or https:// followed by a host", remoteURL)
+	}
+	return nil
+}
+
+// setRemoteProject links a remote to a project ID, or unlinks it if the ID is
+// empty
+func setRemoteProject(repoConfig *config.RepoConfig, remote, projectID string) {
+	if projectID == "" {
+		delete(repoConfig.RemoteProjects, remote)
+		if len(repoConfig.RemoteProjects) == 0 {
+			repoConfig.RemoteProjects = nil
+		}
+		return
+	}
+
+	if repoConfig.RemoteProjects == nil {
+		repoConfig.RemoteProjects = make(map[string]string)
+	}
+	repoConfig.RemoteProjects[remote] = projectID
+}
+
+// findRemoteProjectID resolves a project name or ID on the server at remoteURL
+func findRemoteProjectID(ctx context.Context, remoteURL, project string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	tokenStore := models.NewTokenStore(filepath.Join(homeDir, ".hhx"))
+	client := newClient(remoteURL, tokenStore)
+	if client.AuthToken == "" {
+		return "", fmt.Errorf("not logged in. Please run 'hhx login' first")
+	}
+
+	return client.FindProjectID(ctx, project)
+}
+
+// checkRemotes checks every remote's URL at once and returns why the
+// unreachable ones couldn't be reached
+func checkRemotes(ctx context.Context, remotes map[string]string) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(remotes))
+
+	for name, remoteURL := range remotes {
+		wg.Add(1)
+		go func(name, remoteURL string) {
+			defer wg.Done()
+			err := newClient(remoteURL, nil).Ping(ctx)
+
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, remoteURL)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func init() {
+	rootCmd.AddCommand(remoteCmd)
+	remoteCmd.AddCommand(remoteAddCmd)
+	remoteCmd.AddCommand(remoteRemoveCmd)
+	remoteCmd.AddCommand(remoteRenameCmd)
+	remoteCmd.AddCommand(remoteSetURLCmd)
+	remoteCmd.AddCommand(remoteUseCmd)
+	remoteCmd.AddCommand(remoteListCmd)
+
+	remoteAddCmd.Flags().String("project", "", "Project to link the remote to, by name or ID")
+	remoteSetURLCmd.Flags().String("project", "", "Project to link the remote to, by name or ID")
+	remoteListCmd.Flags().BoolP("verbose", "v", false, "Show URLs and projects, and check that each URL is reachable")
+}
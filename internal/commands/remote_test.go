@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckRemotes(t *testing.T) {
+	// Any answer counts, whatever its status
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	results := checkRemotes(context.Background(), map[string]string{
+		"origin": server.URL,
+		"backup": closedURL,
+	})
+
+	if len(results) != 2 {
+		t.Fatalf("got results for %d remotes, want 2", len(results))
+	}
+	if err := results["origin"]; err != nil {
+		t.Errorf("origin reported unreachable: %v", err)
+	}
+	if results["backup"] == nil {
+		t.Errorf("backup at %s reported reachable", closedURL)
+	}
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [file/directory]",
+	Short: "Restore files to their synced version",
+	Long: `Roll modified or deleted files back to the version last synced with the remote server.
+
+The synced content is read from the local object cache in .hhx/objects, or downloaded
+from the server, and written back once its hash has been verified. Restoring a deleted
+file also drops its deletion from the index, and restoring a staged file unstages it.
+
+With --staged, files are only removed from the staging area and the working tree is
+left untouched.`,
+	Example: `  hhx restore data/train.csv        # Restore a modified or deleted file
+  hhx restore data/                  # Restore every changed file in a directory
+  hhx restore --staged data/train.csv # Unstage a file but keep local changes`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			fmt.Println("Error: no files specified")
+			err := cmd.Usage()
+			if err != nil {
+				fmt.Println("error displaying usage:", err)
+			}
+			return nil
+		}
+
+		staged, _ := cmd.Flags().GetBool("staged")
+
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		pathspecs, err := resolvePathspecs(repoRoot, args)
+		if err != nil {
+			fmt.Println("error:", err)
+			return nil
+		}
+
+		// Collect the files every pathspec refers to
+		var candidates []string
+		if staged {
+			for _, file := range index.GetStagedFiles() {
+				candidates = append(candidates, file.Path)
+			}
+			for _, file := range index.GetStagedDeletions() {
+				candidates = append(candidates, file.Path)
+			}
+		} else {
+			for _, entry := range index.Tracked() {
+				candidates = append(candidates, entry.Path)
+			}
+		}
+		sort.Strings(candidates)
+
+		var paths []string
+		for i, spec := range pathspecs {
+			matched := false
+			for _, path := range candidates {
+				if matchesPathspecs(path, []string{spec}) {
+					paths = append(paths, path)
+					matched = true
+				}
+			}
+
+			if !matched {
+				if staged {
+					fmt.Printf("error: pathspec '%s' did not match any staged files\n", args[i])
+				} else {
+					fmt.Printf("error: pathspec '%s' did not match any synced files\n", args[i])
+				}
+				return nil
+			}
+		}
+
+		if staged {
+			for _, path := range paths {
+				index.UnstageFile(filepath.Join(repoRoot, filepath.FromSlash(path)))
+				fmt.Printf("Unstaged %s\n", path)
+			}
+		} else {
+			fetcher := newObjectFetcher(repoRoot, repoConfig)
+			restored, failed := 0, 0
+
+			// Restored files no longer have changes to upload
+			stagedPaths := make(map[string]bool)
+			for _, file := range index.GetStagedFiles() {
+				stagedPaths[file.Path] = true
+			}
+
+			for i, path := range paths {
+				if i > 0 && paths[i-1] == path {
+					continue
+				}
+
+				tracked, ok := index.GetTrackedFile(path)
+				if !ok {
+					continue
+				}
+
+				fullPath := filepath.Join(repoRoot, filepath.FromSlash(path))
+
+				// Files that already match their synced version only need their
+				// deletion or staged changes dropped
+				if info, err := os.Stat(fullPath); err == nil {
+					local, err := models.NewFileFromPath(repoRoot, fullPath)
+					if err != nil {
+						fmt.Println("error reading", path, ":", err)
+						failed++
+						continue
+					}
+					if local.Hash == tracked.Hash {
+						if tracked.Status != models.StatusSynced || stagedPaths[path] {
+							if err := index.RestoreSynced(path, info); err != nil {
+								fmt.Println("error updating index for", path, ":", err)
+								failed++
+								continue
+							}
+							if stagedPaths[path] {
+								index.UnstageFile(fullPath)
+							}
+							fmt.Printf("Restored %s\n", path)
+							restored++
+						}
+						continue
+					}
+				}
+
+				if _, err := fetcher.fetch(cmd.Context(), tracked.RemoteURL, tracked.Hash); err != nil {
+					fmt.Printf("error fetching the synced version of %s: %v\n", path, err)
+					failed++
+					continue
+				}
+
+				if _, err := fetcher.objects.Checkout(tracked.Hash, fullPath); err != nil {
+					fmt.Println("error restoring", path, ":", err)
+					failed++
+					continue
+				}
+
+				info, err := os.Stat(fullPath)
+				if err != nil {
+					fmt.Println("error reading", path, ":", err)
+					failed++
+					continue
+				}
+				if err := index.RestoreSynced(path, info); err != nil {
+					fmt.Println("error updating index for", path, ":", err)
+					failed++
+					continue
+				}
+				if stagedPaths[path] {
+					index.UnstageFile(fullPath)
+				}
+
+				fmt.Printf("Restored %s\n", path)
+				restored++
+			}
+
+			if restored == 0 && failed == 0 {
+				fmt.Println("Nothing to restore, files already match their synced version.")
+			}
+		}
+
+		// Save the index
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	restoreCmd.Flags().Bool("staged", false, "Only remove the files from the staging area")
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newRestoreRepo creates a repository where a.txt and b.txt were synced with
+// the server, which serves them with the given contents, and a.txt was then
+// modified and staged
+func newRestoreRepo(t *testing.T, server *testServer, served map[string]string) string {
+	t.Helper()
+
+	for path, content := range served {
+		server.testRemoteFile(path, content, content)
+	}
+	return newCommandRepo(t, server, func(repoRoot string) *models.Index {
+		index := models.NewIndex(repoRoot)
+		for _, path := range []string{"a.txt", "b.txt"} {
+			writeTestFile(t, repoRoot, path, "synced "+path)
+			index.RecordSynced(&models.File{Path: path, Hash: hashOf("synced " + path), RemoteURL: "/files/" + path})
+		}
+		stageTestFiles(t, index, repoRoot, map[string]string{"a.txt": "modified"})
+		return index
+	})
+}
+
+func TestRestoreModifiedAndDeletedFiles(t *testing.T) {
+	server := newTestServer(t)
+	cfg := loginTestHome(t, server)
+	repoRoot := newRestoreRepo(t, server, map[string]string{"a.txt": "synced a.txt", "b.txt": "synced b.txt"})
+	if err := os.Remove(filepath.Join(repoRoot, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(t, cfg, repoRoot, "rm", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCommand(t, cfg, repoRoot, "restore", "a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	index := loadCommandIndex(t, repoRoot)
+	for _, path := range []string{"a.txt", "b.txt"} {
+		if got := readTestFile(t, repoRoot, path); got != "synced "+path {
+			t.Errorf("%s contains %q after restoring it", path, got)
+		}
+		if _, ok := index.GetSyncedFile(path); !ok {
+			t.Errorf("%s not synced after restoring it", path)
+		}
+	}
+	if staged := index.GetStagedFiles(); len(staged) != 0 {
+		t.Errorf("%d files still staged after restoring them", len(staged))
+	}
+	if deletions := index.GetStagedDeletions(); len(deletions) != 0 {
+		t.Errorf("%d deletions still staged after restoring the files", len(deletions))
+	}
+
+	// The downloads were cached, so restoring again doesn't need the server
+	server.Close()
+	writeTestFile(t, repoRoot, "a.txt", "modified again")
+	if err := runCommand(t, cfg, repoRoot, "restore", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, repoRoot, "a.txt"); got != "synced a.txt" {
+		t.Errorf("a.txt contains %q after restoring it from the cache", got)
+	}
+}
+
+func TestRestoreRejectsHashMismatch(t *testing.T) {
+	server := newTestServer(t)
+	cfg := loginTestHome(t, server)
+	repoRoot := newRestoreRepo(t, server, map[string]string{"a.txt": "corrupted"})
+
+	if err := runCommand(t, cfg, repoRoot, "restore", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readTestFile(t, repoRoot, "a.txt"); got != "modified" {
+		t.Errorf("a.txt contains %q, want the local changes kept", got)
+	}
+	index := loadCommandIndex(t, repoRoot)
+	if staged := index.GetStagedFiles(); len(staged) != 1 || staged[0].Path != "a.txt" {
+		t.Errorf("staged files: %v, want a.txt still staged", staged)
+	}
+	if objects := models.NewObjectStore(repoRoot); objects.Has(hashOf("synced a.txt")) {
+		t.Error("content with the wrong hash cached")
+	}
+}
+
+func TestRestoreStagedKeepsWorkingTree(t *testing.T) {
+	server := newTestServer(t)
+	cfg := loginTestHome(t, server)
+	repoRoot := newRestoreRepo(t, server, nil)
+
+	if err := runCommand(t, cfg, repoRoot, "restore", "--staged", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readTestFile(t, repoRoot, "a.txt"); got != "modified" {
+		t.Errorf("a.txt contains %q, want the local changes kept", got)
+	}
+	if staged := loadCommandIndex(t, repoRoot).GetStagedFiles(); len(staged) != 0 {
+		t.Errorf("%d files still staged", len(staged))
+	}
+}
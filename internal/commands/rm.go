@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm [file/directory]",
+	Short: "Remove files and stage their deletion",
+	Long: `Remove synced files from the working tree and stage their deletion, so they are
+removed from the remote collection on the next push.`,
+	Example: `  hhx rm file.txt          # Delete a file and stage its deletion
+  hhx rm -r directory/     # Delete all synced files in a directory
+  hhx rm --cached file.txt # Stage the deletion but keep the local file`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			fmt.Println("Error: no files specified")
+			err := cmd.Usage()
+			if err != nil {
+				fmt.Println("error displaying usage:", err)
+			}
+			return nil
+		}
+
+		cached, _ := cmd.Flags().GetBool("cached")
+		recursive, _ := cmd.Flags().GetBool("recursive")
+		force, _ := cmd.Flags().GetBool("force")
+
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			fmt.Println("error getting current directory:", err)
+			return nil
+		}
+
+		// Resolve every argument to the tracked files it refers to
+		var paths []string
+		for _, arg := range args {
+			path := arg
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(cwd, path)
+			}
+
+			relPath, err := filepath.Rel(repoRoot, path)
+			if err != nil {
+				fmt.Println("error getting relative path for", arg, ":", err)
+				return nil
+			}
+			relPath = filepath.ToSlash(relPath)
+
+			if _, ok := index.GetTrackedFile(relPath); ok {
+				paths = append(paths, relPath)
+				continue
+			}
+
+			var matches []string
+			for _, file := range index.GetAllFiles() {
+				if relPath == "." || strings.HasPrefix(file.Path, relPath+"/") {
+					if _, ok := index.GetTrackedFile(file.Path); ok {
+						matches = append(matches, file.Path)
+					}
+				}
+			}
+
+			if len(matches) == 0 {
+				fmt.Printf("error: pathspec '%s' did not match any synced files\n", arg)
+				return nil
+			}
+
+			if !recursive {
+				fmt.Printf("error: not removing '%s' recursively without -r\n", arg)
+				return nil
+			}
+
+			paths = append(paths, matches...)
+		}
+
+		sort.Strings(paths)
+
+		for i, relPath := range paths {
+			if i > 0 && paths[i-1] == relPath {
+				continue
+			}
+
+			fullPath := filepath.Join(repoRoot, filepath.FromSlash(relPath))
+
+			if !cached {
+				if _, err := os.Stat(fullPath); err == nil {
+					// Refuse to throw away local modifications
+					if !force {
+						tracked, _ := index.GetTrackedFile(relPath)
+						local, err := models.NewFileFromPath(repoRoot, fullPath)
+						if err != nil {
+							fmt.Println("error reading", relPath, ":", err)
+							return nil
+						}
+						if local.Hash != tracked.Hash {
+							fmt.Printf("error: '%s' has local modifications (use --cached to keep the file, or -f to force removal)\n", relPath)
+							return nil
+						}
+					}
+
+					if err := os.Remove(fullPath); err != nil {
+						fmt.Println("error removing", relPath, ":", err)
+						return nil
+					}
+				}
+			}
+
+			if err := index.StageDeletion(fullPath); err != nil {
+				fmt.Println("error staging deletion of", relPath, ":", err)
+				return nil
+			}
+
+			fmt.Printf("rm '%s'\n", relPath)
+		}
+
+		// Save the index
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+
+	rmCmd.Flags().Bool("cached", false, "Only stage the deletion and keep the file in the working tree")
+	rmCmd.Flags().BoolP("recursive", "r", false, "Allow recursive removal when a directory is given")
+	rmCmd.Flags().BoolP("force", "f", false, "Remove files even if they have local modifications")
+}
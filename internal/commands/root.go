@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
+	"hhx/internal/api"
 	"hhx/internal/config"
+	"hhx/internal/models"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -20,10 +25,76 @@ Designed for developers and data professionals who need streamlined control over
 	Version: "0.1.0",
 }
 
-// Execute runs the root command
-func Execute(cfg *config.Config) error {
+// Execute runs the root command. Commands stop what they are doing when ctx
+// is cancelled, leaving the repository consistent.
+func Execute(ctx context.Context, cfg *config.Config) error {
 	globalConfig = cfg
-	return rootCmd.Execute()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// resolveHashJobs returns the number of files to hash in parallel, taken from the
+// --jobs flag or the global config; zero means one per CPU
+func resolveHashJobs(cmd *cobra.Command) int {
+	if jobs, err := cmd.Flags().GetInt("jobs"); err == nil && jobs > 0 {
+		return jobs
+	}
+
+	if globalConfig != nil {
+		return globalConfig.HashJobs
+	}
+
+	return 0
+}
+
+// ExitError ends hhx with a status code, for commands that have already told
+// the user what went wrong
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// exitStatus makes a command end hhx with the given status code without
+// printing an error or its usage
+func exitStatus(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &ExitError{Code: code}
+}
+
+// newClient creates an API client for a server, retrying failed requests as
+// the global config says
+func newClient(serverURL string, tokenStore *models.TokenStore) *api.Client {
+	client := api.NewClient(serverURL, tokenStore)
+	if globalConfig != nil {
+		client.SetRetryPolicy(retryPolicy(globalConfig))
+	}
+	return client
+}
+
+// retryPolicy reads the retry settings of a config; settings that are unset or
+// invalid keep their defaults
+func retryPolicy(cfg *config.Config) api.RetryPolicy {
+	policy := api.DefaultRetryPolicy
+	if cfg.RetryAttempts > 0 {
+		policy.MaxAttempts = cfg.RetryAttempts
+	}
+	if delay, err := time.ParseDuration(cfg.RetryBaseDelay); err == nil && delay > 0 {
+		policy.BaseDelay = delay
+	}
+	if delay, err := time.ParseDuration(cfg.RetryMaxDelay); err == nil && delay > 0 {
+		policy.MaxDelay = delay
+	}
+	return policy
+}
+
+// lockIndex takes the index lock for the rest of a command, waiting up to
+// --lock-timeout for another hhx process to release it
+func lockIndex(cmd *cobra.Command, indexPath string) (*models.IndexLock, error) {
+	timeout, _ := cmd.Flags().GetDuration("lock-timeout")
+	return models.AcquireIndexLock(indexPath, timeout)
 }
 
 // findRepoRoot finds the root directory of the repository
@@ -52,4 +123,45 @@ func findRepoRoot() (string, error) {
 	return "", fmt.Errorf("not in a hhx repository (or any parent directory)")
 }
 
-func init() {}
+// resolvePathspecs turns command line paths into paths relative to the
+// repository root, with forward slashes
+func resolvePathspecs(repoRoot string, args []string) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("error getting current directory: %w", err)
+	}
+
+	pathspecs := make([]string, 0, len(args))
+	for _, arg := range args {
+		path := arg
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+
+		relPath, err := filepath.Rel(repoRoot, path)
+		if err != nil || !filepath.IsLocal(relPath) && relPath != "." {
+			return nil, fmt.Errorf("'%s' is outside the repository", arg)
+		}
+		pathspecs = append(pathspecs, filepath.ToSlash(relPath))
+	}
+
+	return pathspecs, nil
+}
+
+// matchesPathspecs reports whether a path is one of the pathspecs or inside one
+// of them; no pathspecs match every path
+func matchesPathspecs(path string, pathspecs []string) bool {
+	if len(pathspecs) == 0 {
+		return true
+	}
+	for _, spec := range pathspecs {
+		if spec == "." || path == spec || strings.HasPrefix(path, spec+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.PersistentFlags().Duration("lock-timeout", 0, "How long to wait for another hhx process to release the index lock (e.g. 30s)")
+}
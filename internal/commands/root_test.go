@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"hhx/internal/config"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestResolveHashJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		config *config.Config
+		want   int
+	}{
+		{name: "defaults to one per CPU", want: 0},
+		{name: "from the config", config: &config.Config{HashJobs: 3}, want: 3},
+		{name: "flag overrides the config", args: []string{"--jobs", "6"}, config: &config.Config{HashJobs: 3}, want: 6},
+		{name: "zero flag keeps the config", args: []string{"--jobs", "0"}, config: &config.Config{HashJobs: 3}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globalConfig = tt.config
+			t.Cleanup(func() { globalConfig = nil })
+
+			cmd := &cobra.Command{}
+			cmd.Flags().Int("jobs", 0, "")
+			if err := cmd.Flags().Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := resolveHashJobs(cmd); got != tt.want {
+				t.Errorf("resolveHashJobs() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hhx/internal/api"
+	"hhx/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProjectID is the project the stand-in server serves
+const testProjectID = "123e4567-e89b-12d3-a456-426614174000"
+
+// testProjectName is the name of the project the stand-in server serves
+const testProjectName = "project"
+
+// testServer is a stand-in for the hhx server that accepts pushes of forms to
+// the collections of one project and serves their files, and records what it
+// received
+type testServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+
+	// Collections of the project
+	collections []string
+
+	// Hashes the server stores, including those of the files it received
+	existing map[string]bool
+
+	// Errors the server reports for files pushed with these paths
+	rejected map[string]string
+
+	// Errors the server fails whole pushes to these collections with
+	broken map[string]string
+
+	// Number of times the contents of each path were received
+	received map[string]int
+
+	// Paths sent as references
+	references []string
+
+	// Number of forms received, and of forms that arrived whether or not
+	// they were received in full
+	forms   int
+	arrived int
+
+	// How long the server takes to answer a form, and the most forms it
+	// handled at once
+	formDelay   time.Duration
+	inFlight    int
+	maxInFlight int
+
+	// Called as each form arrives, numbered from 1; the form is dropped
+	// unless it returns true
+	beforeForm func(r *http.Request, form int) bool
+
+	// Contents the server serves for download, by path
+	files map[string]string
+
+	// Files the server lists in each collection
+	listed map[string][]api.RemoteFile
+
+	// Paths the server deleted
+	deleted []string
+}
+
+// newTestServer starts a stand-in server with the given collections, stopped
+// when the test ends
+func newTestServer(t testing.TB, collections ...string) *testServer {
+	s := &testServer{
+		collections: collections,
+		existing:    make(map[string]bool),
+		rejected:    make(map[string]string),
+		broken:      make(map[string]string),
+		received:    make(map[string]int),
+		files:       make(map[string]string),
+		listed:      make(map[string][]api.RemoteFile),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestClient creates a client logged in to the stand-in server
+func newTestClient(t testing.TB, s *testServer) *api.Client {
+	t.Helper()
+
+	tokenStore := models.NewTokenStore(t.TempDir())
+	if err := tokenStore.SaveToken("test-token"); err != nil {
+		t.Fatal(err)
+	}
+	return api.NewClient(s.URL, tokenStore)
+}
+
+// stageTestFiles writes files with the given contents to repoRoot and stages
+// them in index
+func stageTestFiles(t testing.TB, index *models.Index, repoRoot string, contents map[string]string) {
+	t.Helper()
+
+	var paths []string
+	for path, content := range contents {
+		fullPath := filepath.Join(repoRoot, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, fullPath)
+	}
+	if err := index.StageFiles(paths); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testRemoteFile returns a remote file the stand-in server serves with the
+// given contents, listed with the hash of hashedContent
+func (s *testServer) testRemoteFile(path, content, hashedContent string) api.RemoteFile {
+	s.mu.Lock()
+	s.files[path] = content
+	s.mu.Unlock()
+
+	return api.RemoteFile{
+		Path:      path,
+		RemoteURL: "/files/" + path,
+		Size:      int64(len(content)),
+		Hash:      hashOf(hashedContent),
+	}
+}
+
+// list adds files the stand-in server lists in a collection
+func (s *testServer) list(collection string, files ...api.RemoteFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listed[collection] = append(s.listed[collection], files...)
+}
+
+// hashOf returns the SHA-256 hash of content as hhx records it
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *testServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/files/") {
+		s.mu.Lock()
+		content, ok := s.files[strings.TrimPrefix(r.URL.Path, "/files/")]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, content)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == fmt.Sprintf("/%s/projects/name/%s", api.API_VERSION, testProjectName):
+		writeJSON(w, map[string]models.Project{"project": {ID: testProjectID, Name: testProjectName}})
+		return
+
+	case r.Method == "GET" && r.URL.Path == "/api/collections":
+		collections := []api.CollectionInfo{}
+		for _, name := range s.collections {
+			collections = append(collections, api.CollectionInfo{Name: name, Type: string(models.CollectionTypeBucket), Path: name})
+		}
+		writeJSON(w, collections)
+		return
+	}
+
+	prefix := fmt.Sprintf("/%s/projects/%s/", api.API_VERSION, testProjectID)
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case r.Method == "GET" && len(parts) == 1 && parts[0] == "collections":
+		var collections []map[string]string
+		for _, name := range s.collections {
+			collections = append(collections, map[string]string{"name": name})
+		}
+		writeJSON(w, map[string]interface{}{"collections": collections})
+
+	case r.Method == "GET" && len(parts) == 3 && parts[0] == "collections" && parts[2] == "files":
+		s.mu.Lock()
+		files := append([]api.RemoteFile{}, s.listed[parts[1]]...)
+		s.mu.Unlock()
+		writeJSON(w, map[string][]api.RemoteFile{"files": files})
+
+	case r.Method == "POST" && len(parts) == 2 && parts[0] == "files" && parts[1] == "exists":
+		s.handleExists(w, r)
+
+	case r.Method == "POST" && len(parts) == 3 && parts[2] == "files":
+		s.mu.Lock()
+		message, broken := s.broken[parts[1]]
+		s.mu.Unlock()
+		if broken {
+			http.Error(w, message, http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.inFlight++
+		s.maxInFlight = max(s.maxInFlight, s.inFlight)
+		s.arrived++
+		delay, beforeForm, arrived := s.formDelay, s.beforeForm, s.arrived
+		s.mu.Unlock()
+
+		if beforeForm != nil && !beforeForm(r, arrived) {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+			return
+		}
+
+		time.Sleep(delay)
+		s.handleForm(w, r)
+
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+
+	case r.Method == "DELETE" && len(parts) == 3 && parts[2] == "files":
+		s.handleDelete(w, r)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *testServer) handleExists(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Hashes []string `json:"hashes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	existing := []string{}
+	for _, hash := range request.Hashes {
+		if s.existing[hash] {
+			existing = append(existing, hash)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, map[string][]string{"existing": existing})
+}
+
+// handleForm reads a pushed form, storing the contents of the files it
+// doesn't reject
+func (s *testServer) handleForm(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := api.PushResponse{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(part.FormName(), "file_meta_") {
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			continue
+		}
+
+		var meta struct {
+			Path      string `json:"path"`
+			Size      int64  `json:"size"`
+			Hash      string `json:"hash"`
+			Reference bool   `json:"reference"`
+		}
+		if err := json.NewDecoder(part).Decode(&meta); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		if message, ok := s.rejected[meta.Path]; ok {
+			s.mu.Unlock()
+			response.Errors = append(response.Errors, api.UploadError{Path: meta.Path, Error: message})
+			continue
+		}
+		if meta.Reference {
+			s.references = append(s.references, meta.Path)
+		} else {
+			s.received[meta.Path]++
+			s.existing[meta.Hash] = true
+		}
+		s.mu.Unlock()
+
+		response.UploadedFiles = append(response.UploadedFiles, api.UploadedFile{
+			Path:      meta.Path,
+			RemoteURL: "/files/" + meta.Path,
+			Size:      meta.Size,
+			Hash:      meta.Hash,
+		})
+	}
+
+	s.mu.Lock()
+	s.forms++
+	s.mu.Unlock()
+	writeJSON(w, response)
+}
+
+// handleDelete deletes the requested paths it doesn't reject
+func (s *testServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Paths []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	response := api.DeleteResponse{DeletedFiles: []string{}}
+	for _, path := range request.Paths {
+		if message, ok := s.rejected[path]; ok {
+			response.Errors = append(response.Errors, api.UploadError{Path: path, Error: message})
+			continue
+		}
+		s.deleted = append(s.deleted, path)
+		response.DeletedFiles = append(response.DeletedFiles, path)
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, response)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"hhx/internal/models"
+	"hhx/internal/util"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show [snapshot]",
+	Short: "Show a snapshot",
+	Long: `Show a snapshot and every file it records. The snapshot can be given by its full ID
+or an unambiguous prefix, and defaults to the latest snapshot.`,
+	Example: `  hhx show                 # Show the latest snapshot
+  hhx show 3f2a9c1b        # Show a snapshot by ID prefix
+  hhx show 3f2a9c1b --json # Print the snapshot as JSON`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		store, err := loadSnapshotStore()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		} else {
+			id, err = store.Head()
+			if err != nil {
+				fmt.Println("error reading snapshot head:", err)
+				return nil
+			}
+			if id == "" {
+				fmt.Println("No snapshots yet. Record one with 'hhx commit -m <message>'.")
+				return nil
+			}
+		}
+
+		snapshot, err := store.Load(id)
+		if err != nil {
+			fmt.Println(describeSnapshotError(id, err))
+			return nil
+		}
+
+		if asJSON {
+			output := struct {
+				ID string `json:"id"`
+				*models.Snapshot
+			}{snapshot.ID, snapshot}
+
+			data, err := json.MarshalIndent(output, "", "  ")
+			if err != nil {
+				fmt.Println("error encoding snapshot:", err)
+				return nil
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		var totalSize int64
+		for _, entry := range snapshot.Files {
+			totalSize += entry.Size
+		}
+
+		color.Yellow("snapshot %s\n", snapshot.ID)
+		if snapshot.Parent != "" {
+			fmt.Printf("Parent: %s\n", snapshot.Parent)
+		}
+		fmt.Printf("Author: %s\n", snapshot.Author)
+		fmt.Printf("Date:   %s\n", snapshot.Time.Local().Format(time.RFC1123Z))
+		fmt.Println()
+		for _, line := range strings.Split(snapshot.Message, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+		fmt.Println()
+
+		fmt.Printf("%d files (%s):\n", len(snapshot.Files), util.FormatSize(totalSize))
+		for _, entry := range snapshot.Files {
+			collection := entry.Collection
+			if collection == "" {
+				collection = "-"
+			}
+			fmt.Printf("  %s  %10s  %-15s %s\n", models.ShortID(entry.Hash), util.FormatSize(entry.Size), collection, entry.Path)
+			if entry.RemoteURL != "" {
+				fmt.Printf("  %s  %s\n", strings.Repeat(" ", models.ShortIDLength), entry.RemoteURL)
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().Bool("json", false, "Print the snapshot as JSON, including full hashes")
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"hhx/internal/config"
 	"hhx/internal/models"
@@ -14,9 +15,11 @@ var stageCmd = &cobra.Command{
 	Use:   "stage [file/directory]",
 	Short: "Stage files for upload",
 	Long:  `Stage files or directories for upload to the remote server.`,
-	Example: `  hhx stage file.txt       # Stage a single file
-  hhx stage directory/     # Stage all files in a directory
-  hhx stage .              # Stage all files in the current directory`,
+	Example: `  hhx stage file.txt                  # Stage a single file
+  hhx stage directory/                # Stage all files in a directory
+  hhx stage .                         # Stage all files in the current directory
+  hhx stage removed.txt               # Stage the deletion of a removed file
+  hhx stage --collection=models ckpt/ # Stage files for the models collection`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			fmt.Println("Error: no files specified")
@@ -42,6 +45,14 @@ var stageCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -49,6 +60,22 @@ var stageCmd = &cobra.Command{
 			return nil
 		}
 
+		if rehash, _ := cmd.Flags().GetBool("rehash"); rehash {
+			index.DisableStatCache()
+		}
+		index.SetHashJobs(resolveHashJobs(cmd))
+
+		// Tag staged files with a collection, overriding routes
+		if collectionName, _ := cmd.Flags().GetString("collection"); collectionName != "" {
+			if err := index.SetStagingCollection(collectionName); err != nil {
+				fmt.Println("Error: collection not found:", collectionName)
+				return nil
+			}
+		}
+
+		// Files are collected and hashed together once all arguments are processed
+		var files []string
+
 		// Process each argument
 		for _, arg := range args {
 			// Get absolute path
@@ -64,8 +91,20 @@ var stageCmd = &cobra.Command{
 
 			// Check if the path exists
 			info, err := os.Stat(path)
-			if err != nil {
-				fmt.Println("error accessing %s: %w", arg, err)
+			if os.IsNotExist(err) {
+				// Stage the deletion of a tracked file
+				fmt.Printf("Staging deletion of %s...\n", arg)
+				if err := index.StageDeletion(path); err != nil {
+					if errors.Is(err, models.ErrFileNotFound) {
+						fmt.Printf("error: pathspec '%s' did not match any tracked files\n", arg)
+					} else {
+						fmt.Println("error staging deletion of", arg, ":", err)
+					}
+					return nil
+				}
+				continue
+			} else if err != nil {
+				fmt.Println("error accessing", arg, ":", err)
 				return nil
 			}
 
@@ -73,16 +112,20 @@ var stageCmd = &cobra.Command{
 				// Stage all files in the directory
 				fmt.Printf("Staging files in directory %s...\n", arg)
 				if err := index.StageDirectory(path); err != nil {
-					fmt.Println("error staging directory %s: %w", arg, err)
+					fmt.Println("error staging directory", arg, ":", err)
 					return nil
 				}
 			} else {
 				// Stage a single file
 				fmt.Printf("Staging file %s...\n", arg)
-				if err := index.StageFile(path); err != nil {
-					fmt.Println("error staging file %s: %w", arg, err)
-					return nil
-				}
+				files = append(files, path)
+			}
+		}
+
+		if len(files) > 0 {
+			if err := index.StageFiles(files); err != nil {
+				fmt.Println("error staging files:", err)
+				return nil
 			}
 		}
 
@@ -99,4 +142,8 @@ var stageCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(stageCmd)
+
+	stageCmd.Flags().String("collection", "", "Collection to push the staged files to (overrides routes)")
+	stageCmd.Flags().Bool("rehash", false, "Rehash every file instead of trusting unchanged size and modification times")
+	stageCmd.Flags().Int("jobs", 0, "Number of files to hash in parallel (defaults to one per CPU)")
 }
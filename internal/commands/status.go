@@ -14,7 +14,10 @@ import (
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show the working tree status",
-	Long:  `Display the state of the working directory and the staging area.`,
+	Long: `Display the state of the working directory and the staging area.
+
+Files whose size, modification time, inode and change time are unchanged since they
+were last hashed are not read again. Use --rehash to hash every file.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Find repository root
 		_, err := findRepoRoot()
@@ -35,6 +38,14 @@ var statusCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
@@ -42,6 +53,11 @@ var statusCmd = &cobra.Command{
 			return nil
 		}
 
+		if rehash, _ := cmd.Flags().GetBool("rehash"); rehash {
+			index.DisableStatCache()
+		}
+		index.SetHashJobs(resolveHashJobs(cmd))
+
 		// Scan working directory for changes
 		newFiles, modifiedFiles, deletedFiles, err := index.ScanWorkingDirectory()
 		if err != nil {
@@ -49,29 +65,71 @@ var statusCmd = &cobra.Command{
 			return nil
 		}
 
-		// Get staged files
-		stagedFiles := index.GetStagedFiles()
+		// Save the index so the recorded file metadata saves rehashing next time
+		if err := index.Save(repoConfig.IndexPath); err != nil {
+			fmt.Println("error saving index:", err)
+			return nil
+		}
+
+		// Get staged files and deletions, keeping those whose push failed apart
+		var stagedFiles, stagedDeletions []*models.File
+		for _, file := range index.GetStagedFiles() {
+			if file.Status != models.StatusFailed {
+				stagedFiles = append(stagedFiles, file)
+			}
+		}
+		for _, file := range index.GetStagedDeletions() {
+			if file.Error == "" {
+				stagedDeletions = append(stagedDeletions, file)
+			}
+		}
+		failedFiles := index.GetFailedFiles()
 
 		// Format output
 		fmt.Printf("On remote: %s (%s)\n", repoConfig.CurrentRemote, repoConfig.Remotes[repoConfig.CurrentRemote])
 		fmt.Println()
 
+		// Files whose last push failed
+		if len(failedFiles) > 0 {
+			fmt.Println("Failed to upload:")
+			fmt.Println("  (use \"hhx push --retry-failed\" to try again, or \"hhx unstage <file>...\" to give up)")
+			fmt.Println()
+
+			sort.Slice(failedFiles, func(i, j int) bool {
+				return failedFiles[i].Path < failedFiles[j].Path
+			})
+
+			for _, file := range failedFiles {
+				if file.Status == models.StatusDeleted {
+					color.Red("	deleting:   %s\n", file.Path)
+				} else {
+					color.Red("	uploading:  %s\n", file.Path)
+				}
+				fmt.Printf("\t            %s\n", file.Error)
+			}
+			fmt.Println()
+		}
+
 		// Changes to be uploaded
-		if len(stagedFiles) > 0 {
+		if len(stagedFiles) > 0 || len(stagedDeletions) > 0 {
 			fmt.Println("Changes to be uploaded:")
 			fmt.Println("  (use \"hhx unstage <file>...\" to unstage)")
 			fmt.Println()
 
 			// Sort files by path
-			sort.Slice(stagedFiles, func(i, j int) bool {
-				return stagedFiles[i].Path < stagedFiles[j].Path
+			changes := append(stagedFiles, stagedDeletions...)
+			sort.Slice(changes, func(i, j int) bool {
+				return changes[i].Path < changes[j].Path
 			})
 
-			// Print new files
-			for _, file := range stagedFiles {
-				if file.RemoteURL == "" {
+			// Print new, modified and deleted files
+			for _, file := range changes {
+				switch {
+				case file.Status == models.StatusDeleted:
+					color.Red("\tdeleted:    %s\n", file.Path)
+				case file.RemoteURL == "":
 					color.Green("\tnew file:   %s\n", file.Path)
-				} else {
+				default:
 					color.Yellow("\tmodified:   %s\n", file.Path)
 				}
 			}
@@ -81,7 +139,7 @@ var statusCmd = &cobra.Command{
 		// Changes not staged for upload
 		if len(modifiedFiles) > 0 || len(deletedFiles) > 0 {
 			fmt.Println("Changes not staged for upload:")
-			fmt.Println("  (use \"hhx stage <file>...\" to update what will be uploaded)")
+			fmt.Println("  (use \"hhx stage <file>...\" or \"hhx rm <file>...\" to update what will be uploaded)")
 			fmt.Println()
 
 			// Sort files by path
@@ -144,14 +202,18 @@ var statusCmd = &cobra.Command{
 		}
 
 		// Summary
-		stagedCount := len(stagedFiles)
+		stagedCount := len(stagedFiles) + len(stagedDeletions)
+		failedCount := len(failedFiles)
 		notStagedCount := len(modifiedFiles) + len(deletedFiles)
 		untrackedCount := len(newFiles)
 
-		if stagedCount == 0 && notStagedCount == 0 && untrackedCount == 0 {
+		if stagedCount == 0 && failedCount == 0 && notStagedCount == 0 && untrackedCount == 0 {
 			fmt.Println("No changes (working directory clean)")
 		} else {
 			parts := []string{}
+			if failedCount > 0 {
+				parts = append(parts, fmt.Sprintf("%d failed to upload", failedCount))
+			}
 			if stagedCount > 0 {
 				parts = append(parts, fmt.Sprintf("%d to be uploaded", stagedCount))
 			}
@@ -170,4 +232,7 @@ var statusCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().Bool("rehash", false, "Rehash every file instead of trusting unchanged size and modification times")
+	statusCmd.Flags().Int("jobs", 0, "Number of files to hash in parallel (defaults to one per CPU)")
 }
@@ -1,9 +1,9 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
-	"hhx/internal/api"
 	"hhx/internal/config"
 	"hhx/internal/models"
 	"hhx/internal/util"
@@ -49,8 +49,8 @@ var storageListCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		buckets, err := client.ListBuckets(projectID)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		buckets, err := client.ListBuckets(cmd.Context(), projectID)
 		if err != nil {
 			fmt.Println("Error listing buckets:", err)
 			return nil
@@ -109,8 +109,8 @@ var storageCreateCmd = &cobra.Command{
 		}
 
 		// Create client and call CreateBucket
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		bucket, err := client.CreateBucket(projectID, bucketName, public, fileSizeLimit, allowedMimeTypes)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		bucket, err := client.CreateBucket(cmd.Context(), projectID, bucketName, public, fileSizeLimit, allowedMimeTypes)
 		if err != nil {
 			fmt.Println("Error creating bucket:", err)
 			return nil
@@ -156,8 +156,8 @@ var storageGetCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		bucket, err := client.GetBucket(projectID, bucketName)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		bucket, err := client.GetBucket(cmd.Context(), projectID, bucketName)
 		if err != nil {
 			fmt.Println("Error getting bucket:", err)
 			return nil
@@ -234,8 +234,8 @@ var storageUpdateCmd = &cobra.Command{
 			updates["allowedMimeTypes"] = strings.Split(allowedMimeTypes, ",")
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		err = client.UpdateBucket(projectID, bucketName, updates)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		err = client.UpdateBucket(cmd.Context(), projectID, bucketName, updates)
 		if err != nil {
 			fmt.Println("Error updating bucket:", err)
 			return nil
@@ -294,8 +294,8 @@ var storageDeleteCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		err = client.DeleteBucket(projectID, bucketName)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		err = client.DeleteBucket(cmd.Context(), projectID, bucketName)
 		if err != nil {
 			fmt.Println("Error deleting bucket:", err)
 			return nil
@@ -355,8 +355,8 @@ var storageEmptyCmd = &cobra.Command{
 			return nil
 		}
 
-		client := api.NewClient(globalConfig.ServerURL, tokenStore)
-		err = client.EmptyBucket(projectID, bucketName)
+		client := newClient(globalConfig.ServerURL, tokenStore)
+		err = client.EmptyBucket(cmd.Context(), projectID, bucketName)
 		if err != nil {
 			fmt.Println("Error emptying bucket:", err)
 			return nil
@@ -406,7 +406,7 @@ func resolveProjectID(cmd *cobra.Command) (string, error) {
 			return projectFlag, nil
 		}
 		// If it's not a UUID, we look it up
-		return lookupProjectIDByName(projectFlag)
+		return lookupProjectIDByName(cmd.Context(), projectFlag)
 	}
 
 	// If no flag, check local repo config
@@ -414,19 +414,22 @@ func resolveProjectID(cmd *cobra.Command) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if projectID := repoConfig.RemoteProjects[repoConfig.CurrentRemote]; projectID != "" {
+		return projectID, nil
+	}
 	if repoConfig.ProjectID != "" {
 		return repoConfig.ProjectID, nil
 	}
 	if repoConfig.ProjectName != "" {
 		// We only have the name, so lookup
-		return lookupProjectIDByName(repoConfig.ProjectName)
+		return lookupProjectIDByName(cmd.Context(), repoConfig.ProjectName)
 	}
 
 	return "", nil
 }
 
 // lookupProjectIDByName calls the API to find a project by name
-func lookupProjectIDByName(name string) (string, error) {
+func lookupProjectIDByName(ctx context.Context, name string) (string, error) {
 	globalConfigDir, err := config.GetGlobalConfigDir()
 	if err != nil {
 		return "", err
@@ -440,8 +443,8 @@ func lookupProjectIDByName(name string) (string, error) {
 	if err != nil || token == "" {
 		return "", fmt.Errorf("not logged in")
 	}
-	client := api.NewClient(globalConfig.ServerURL, tokenStore)
-	proj, err := client.GetProjectByName(name)
+	client := newClient(globalConfig.ServerURL, tokenStore)
+	proj, err := client.GetProjectByName(ctx, name)
 	if err != nil {
 		return "", err
 	}
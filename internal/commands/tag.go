@@ -0,0 +1,256 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"hhx/internal/api"
+	"hhx/internal/config"
+	"hhx/internal/models"
+	"hhx/internal/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag [name]",
+	Short: "Name the current set of synced files",
+	Long: `Freeze the path, hash and remote URL of every synced file under a name, such as a
+dataset version. Tags are stored in .hhx/tags and on the server, and can't be changed
+once created. Restore the exact files of a tag with 'hhx pull --tag <name>'.`,
+	Example: `  hhx tag v1.3 -m "Cleaned RL rollouts"   # Tag the synced files
+  hhx tag list                            # List tags
+  hhx pull --tag v1.3                     # Restore the files of a tag`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return listTags(cmd)
+		}
+
+		name := args[0]
+		message, _ := cmd.Flags().GetString("message")
+		projectName, _ := cmd.Flags().GetString("project")
+
+		if err := models.ValidateTagName(name); err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		// Find repository root
+		repoRoot, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		activeProject := repoConfig.ProjectFor(repoConfig.CurrentRemote)
+		if projectName != "" {
+			activeProject = projectName
+		}
+
+		if activeProject == "" {
+			fmt.Println("Error: no project specified or linked. Use --project to specify a project or link a project with 'hhx project link'")
+			return nil
+		}
+
+		// Load index
+		index, err := models.LoadIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error loading index:", err)
+			return nil
+		}
+
+		tags := models.NewTagStore(repoRoot)
+		if tags.Exists(name) {
+			fmt.Printf("Error: tag '%s' already exists\n", name)
+			return nil
+		}
+
+		head, err := models.NewSnapshotStore(repoRoot).Head()
+		if err != nil {
+			fmt.Println("error reading snapshot head:", err)
+			return nil
+		}
+
+		tag := &models.Tag{
+			Name:     name,
+			Message:  message,
+			Author:   snapshotAuthor(),
+			Time:     time.Now().UTC(),
+			Snapshot: head,
+			Files:    index.Manifest(),
+		}
+
+		if len(tag.Files) == 0 {
+			fmt.Println("Error: there are no synced files to tag. Push or pull files first")
+			return nil
+		}
+
+		client, err := newRemoteClient(repoConfig, repoConfig.CurrentRemote)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		// Store the tag on the server first so a local tag is always shared
+		if err := client.CreateProjectTag(cmd.Context(), activeProject, tag); err != nil {
+			if errors.Is(err, models.ErrTagExists) {
+				fmt.Printf("Error: tag '%s' already exists on the server\n", name)
+			} else {
+				fmt.Println("error creating tag on the server:", err)
+			}
+			return nil
+		}
+
+		if err := tags.Save(tag); err != nil {
+			fmt.Println("error saving tag:", err)
+			return nil
+		}
+
+		var totalSize int64
+		for _, entry := range tag.Files {
+			totalSize += entry.Size
+		}
+
+		fmt.Printf("Tagged %d files (%s) as '%s' in project '%s'.\n",
+			len(tag.Files), util.FormatSize(totalSize), name, activeProject)
+
+		if pending := len(index.GetStagedFiles()) + len(index.GetStagedDeletions()); pending > 0 {
+			fmt.Printf("\nNote: %d staged changes have not been pushed and are not part of this tag.\n", pending)
+		}
+
+		return nil
+	},
+}
+
+var tagListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tags",
+	Long:  `List the tags stored in the repository, and with --remote the tags stored on the server.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return listTags(cmd)
+	},
+}
+
+// listTags prints local tags, plus the server's tags if --remote is set
+func listTags(cmd *cobra.Command) error {
+	showRemote, _ := cmd.Flags().GetBool("remote")
+	projectName, _ := cmd.Flags().GetString("project")
+
+	// Find repository root
+	repoRoot, err := findRepoRoot()
+	if err != nil {
+		fmt.Println("could not find repo root:", err)
+		return nil
+	}
+
+	localTags, err := models.NewTagStore(repoRoot).List()
+	if err != nil {
+		fmt.Println("error reading tags:", err)
+		return nil
+	}
+
+	tags := localTags
+	isLocal := make(map[string]bool)
+	for _, tag := range localTags {
+		isLocal[tag.Name] = true
+	}
+
+	if showRemote {
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		activeProject := repoConfig.ProjectFor(repoConfig.CurrentRemote)
+		if projectName != "" {
+			activeProject = projectName
+		}
+
+		client, err := newRemoteClient(repoConfig, repoConfig.CurrentRemote)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+
+		remoteTags, err := client.ListProjectTags(cmd.Context(), activeProject)
+		if err != nil {
+			fmt.Println("error listing tags on the server:", err)
+			return nil
+		}
+
+		for _, tag := range remoteTags {
+			if !isLocal[tag.Name] {
+				tags = append(tags, tag)
+			}
+		}
+
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].Time.Before(tags[j].Time)
+		})
+	}
+
+	if len(tags) == 0 {
+		fmt.Println("No tags yet. Create one with 'hhx tag <name>'.")
+		return nil
+	}
+
+	for _, tag := range tags {
+		color.New(color.FgYellow).Printf("%-20s", tag.Name)
+		fmt.Printf(" %s", tag.Time.Local().Format("2006-01-02 15:04"))
+		if isLocal[tag.Name] {
+			fmt.Printf("  %5d files", len(tag.Files))
+		} else {
+			fmt.Printf("  %11s", "(remote)")
+		}
+		if tag.Message != "" {
+			fmt.Printf("  %s", firstLine(tag.Message))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// newRemoteClient creates an API client for a configured remote, failing if the user isn't logged in
+func newRemoteClient(repoConfig *config.RepoConfig, remote string) (*api.Client, error) {
+	remoteURL, ok := repoConfig.Remotes[remote]
+	if !ok {
+		return nil, fmt.Errorf("unknown remote: %s", remote)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+	configDir := filepath.Join(homeDir, ".hhx")
+	tokenStore := models.NewTokenStore(configDir)
+	client := newClient(remoteURL, tokenStore)
+	if client.AuthToken == "" {
+		return nil, fmt.Errorf("not logged in. Please run 'hhx login' first")
+	}
+
+	return client, nil
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagListCmd)
+
+	tagCmd.Flags().StringP("message", "m", "", "Message describing the tag")
+	tagCmd.PersistentFlags().String("project", "", "Project to store the tag in (overrides the linked project)")
+	tagListCmd.Flags().Bool("remote", false, "Include tags stored on the server")
+}
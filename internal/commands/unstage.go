@@ -41,6 +41,14 @@ var unstageCmd = &cobra.Command{
 			return nil
 		}
 
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
 		// Load index
 		index, err := models.LoadIndex(repoConfig.IndexPath)
 		if err != nil {
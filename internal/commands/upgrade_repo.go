@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"hhx/internal/config"
+	"hhx/internal/migrate"
+	"hhx/internal/models"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// upgradeRepoCmd represents the upgrade-repo command
+var upgradeRepoCmd = &cobra.Command{
+	Use:   "upgrade-repo",
+	Short: "Upgrade repository files to the current format",
+	Long: `Upgrade the repository config and index to the format written by this version of hhx.
+
+Other commands read older formats as they are and upgrade the files the next time they
+save them; this command does it explicitly and reports what changed. The original of every upgraded file is
+kept next to it as <file>.v<version>.bak.
+
+Repositories written by a newer version of hhx are left untouched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Find repository root
+		_, err := findRepoRoot()
+		if err != nil {
+			fmt.Println("could not find repo root:", err)
+			return nil
+		}
+
+		configPath, err := config.GetRepoConfigPath()
+		if err != nil {
+			fmt.Println("error finding repository config:", err)
+			return nil
+		}
+
+		// Check both files before changing either, so a repository written by a
+		// newer hhx is never partly upgraded
+		configVersion, err := fileFormatVersion(configPath)
+		if err != nil {
+			fmt.Println("error reading repository config:", err)
+			return nil
+		}
+		if configVersion > config.RepoConfigFormatVersion {
+			fmt.Printf("Error: the repository config is at format version %d, but this hhx supports up to version %d.\n",
+				configVersion, config.RepoConfigFormatVersion)
+			fmt.Println("It was written by a newer version of hhx; upgrade hhx instead.")
+			return nil
+		}
+
+		// Load repository config
+		repoConfig, err := config.LoadRepoConfig()
+		if err != nil {
+			fmt.Println("error loading repository config:", err)
+			return nil
+		}
+
+		// Lock the index until the command finishes
+		lock, err := lockIndex(cmd, repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil
+		}
+		defer lock.Release()
+
+		configResult, err := config.UpgradeRepoConfig(configPath)
+		if err != nil {
+			fmt.Println("error upgrading repository config:", err)
+			return nil
+		}
+
+		indexVersion, err := models.IndexFileVersion(repoConfig.IndexPath)
+		if os.IsNotExist(err) {
+			printUpgradeResult("Repository config", configResult)
+			fmt.Println("Index: not created yet")
+			return nil
+		}
+		if err != nil {
+			fmt.Println("error reading index:", err)
+			return nil
+		}
+		if indexVersion > models.IndexFormatVersion {
+			printUpgradeResult("Repository config", configResult)
+			fmt.Printf("Error: the index is at format version %d, but this hhx supports up to version %d.\n",
+				indexVersion, models.IndexFormatVersion)
+			fmt.Println("It was written by a newer version of hhx; upgrade hhx instead.")
+			return nil
+		}
+
+		indexResult, err := models.UpgradeIndex(repoConfig.IndexPath)
+		if err != nil {
+			fmt.Println("error upgrading index:", err)
+			return nil
+		}
+
+		printUpgradeResult("Repository config", configResult)
+		printUpgradeResult("Index", indexResult)
+		return nil
+	},
+}
+
+// fileFormatVersion reads the format version recorded in a repository file
+func fileFormatVersion(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return migrate.Version(data)
+}
+
+// printUpgradeResult describes the upgrade of one repository file
+func printUpgradeResult(name string, result *migrate.Result) {
+	if !result.Upgraded() {
+		fmt.Printf("%s: already at format version %d\n", name, result.To)
+		return
+	}
+
+	fmt.Printf("%s: upgraded from format version %d to %d\n", name, result.From, result.To)
+	for _, description := range result.Applied {
+		fmt.Println("  -", description)
+	}
+	fmt.Println("  original saved as", result.BackupPath)
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeRepoCmd)
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"hhx/internal/migrate"
 	"os"
 	"path/filepath"
 )
@@ -20,8 +22,37 @@ type Config struct {
 
 	// Default local repository path
 	DefaultRepoPath string `json:"default_repo_path,omitempty"`
+
+	// Number of files hashed in parallel (0 uses one worker per CPU)
+	HashJobs int `json:"hash_jobs,omitempty"`
+
+	// Where file contents are cached by hash: "repo" (the default), "shared" or "off"
+	ObjectCache string `json:"object_cache,omitempty"`
+
+	// Maximum size of the object cache in bytes (0 for no limit)
+	CacheMaxSize int64 `json:"cache_max_size,omitempty"`
+
+	// Attempts made at each API request before giving up (0 uses the default, 1 disables retries)
+	RetryAttempts int `json:"retry_attempts,omitempty"`
+
+	// Delay before the first retry of a failed request, and the longest delay
+	// between retries, as durations such as "500ms" or "30s"
+	RetryBaseDelay string `json:"retry_base_delay,omitempty"`
+	RetryMaxDelay  string `json:"retry_max_delay,omitempty"`
 }
 
+// Object cache modes
+const (
+	// ObjectCacheRepo caches file contents in each repository's .hhx/objects
+	ObjectCacheRepo = "repo"
+
+	// ObjectCacheShared caches file contents in ~/.hhx/cache, shared by all repositories
+	ObjectCacheShared = "shared"
+
+	// ObjectCacheOff stops push and pull from caching file contents
+	ObjectCacheOff = "off"
+)
+
 // GetGlobalConfigDir returns the path to the global configuration directory
 func GetGlobalConfigDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -31,6 +62,15 @@ func GetGlobalConfigDir() (string, error) {
 	return filepath.Join(homeDir, ".hhx"), nil
 }
 
+// GetSharedCacheDir returns the path to the object cache shared by all repositories
+func GetSharedCacheDir() (string, error) {
+	configDir, err := GetGlobalConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "cache"), nil
+}
+
 // GetGlobalConfigPath returns the path to the global configuration file
 func GetGlobalConfigPath() (string, error) {
 	configDir, err := GetGlobalConfigDir()
@@ -124,8 +164,47 @@ func GetRepoConfigPath() (string, error) {
 	return "", os.ErrNotExist
 }
 
+// RepoConfigFormatVersion is the version of the repository config format
+// written by this build
+const RepoConfigFormatVersion = 2
+
+// repoConfigMigrations upgrades repository configs written by older versions of hhx
+var repoConfigMigrations = migrate.NewRegistry("repository config", RepoConfigFormatVersion,
+	migrate.Migration{
+		From:        0,
+		Description: "record the repository config format version",
+		Apply:       func(doc map[string]json.RawMessage) error { return nil },
+	},
+	migrate.Migration{
+		From:        1,
+		Description: "move the index from .hhx/index.json to the binary .hhx/index",
+		Apply: func(doc map[string]json.RawMessage) error {
+			var indexPath string
+			if raw, ok := doc["index_path"]; ok {
+				if err := json.Unmarshal(raw, &indexPath); err != nil {
+					return err
+				}
+			}
+			if filepath.Base(indexPath) != "index.json" {
+				return nil
+			}
+
+			// The JSON index is converted the next time it is loaded
+			raw, err := json.Marshal(filepath.Join(filepath.Dir(indexPath), "index"))
+			if err != nil {
+				return err
+			}
+			doc["index_path"] = raw
+			return nil
+		},
+	},
+)
+
 // RepoConfig represents repository-specific configuration
 type RepoConfig struct {
+	// Version of the config file format
+	FormatVersion int `json:"format_version"`
+
 	// Remote name and URL mapping
 	Remotes map[string]string `json:"remotes"`
 
@@ -140,9 +219,26 @@ type RepoConfig struct {
 
 	// Project name
 	ProjectName string `json:"project_name,omitempty"`
+
+	// Project ID linked to each remote whose project isn't the repository's
+	RemoteProjects map[string]string `json:"remote_projects,omitempty"`
+}
+
+// ProjectFor returns the project to use on a remote: the project linked to the
+// remote, or else the repository's project, by name if it has one
+func (c *RepoConfig) ProjectFor(remote string) string {
+	if projectID := c.RemoteProjects[remote]; projectID != "" {
+		return projectID
+	}
+	if c.ProjectName != "" {
+		return c.ProjectName
+	}
+	return c.ProjectID
 }
 
-// LoadRepoConfig loads the repository configuration
+// LoadRepoConfig loads the repository configuration. A config written in an
+// older format is upgraded in memory and left unchanged on disk until it is
+// next saved.
 func LoadRepoConfig() (*RepoConfig, error) {
 	path, err := GetRepoConfigPath()
 	if err != nil {
@@ -153,6 +249,10 @@ func LoadRepoConfig() (*RepoConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	data, _, err = repoConfigMigrations.Upgrade(data)
+	if err != nil {
+		return nil, err
+	}
 
 	var cfg RepoConfig
 	if err := json.Unmarshal(data, &cfg); err != nil {
@@ -169,7 +269,30 @@ func SaveRepoConfig(cfg *RepoConfig) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	return cfg.Save(path)
+}
+
+// UpgradeRepoConfig upgrades the repository config at path to the current
+// format, backing up the original if anything changed
+func UpgradeRepoConfig(path string) (*migrate.Result, error) {
+	_, result, err := repoConfigMigrations.Load(path)
+	return result, err
+}
+
+// Save saves the repository configuration to the given file path. A config
+// there in an older format is backed up first.
+func (c *RepoConfig) Save(path string) error {
+	if old, err := os.ReadFile(path); err == nil {
+		if version, err := migrate.Version(old); err == nil && version < RepoConfigFormatVersion {
+			if _, err := migrate.Backup(path, version, old); err != nil {
+				return fmt.Errorf("backing up %s: %w", path, err)
+			}
+		}
+	}
+
+	c.FormatVersion = RepoConfigFormatVersion
+
+	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
 		return err
 	}
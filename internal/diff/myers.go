@@ -0,0 +1,160 @@
+// Package diff compares file contents line by line and tables row by row.
+package diff
+
+import "strings"
+
+// Op is the kind of change an Edit makes
+type Op int
+
+const (
+	Equal  Op = iota // Line is present in both versions
+	Delete           // Line is only present in the old version
+	Insert           // Line is only present in the new version
+)
+
+// Edit is a single line of a line-by-line diff
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// maxEditDistance bounds the work spent finding a minimal diff. Beyond it the
+// changed region is reported as replaced, which is correct but not minimal.
+const maxEditDistance = 2000
+
+// SplitLines splits text into lines, keeping each line's terminating newline so
+// a missing newline at the end of the file shows up as a change
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns the edits turning a into b, using Myers' algorithm
+func Lines(a, b []string) []Edit {
+	// Lines shared at the start and end are common in edited files and are
+	// cheap to strip before running the quadratic part
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Text: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Text: line})
+	}
+
+	return edits
+}
+
+// myers finds a shortest edit script between a and b
+func myers(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+
+	max := n + m
+	offset := max
+	v := make([]int, 2*max+2)
+
+	// trace[d] holds v[-d..d] as it was before round d, for backtracking
+	var trace [][]int
+	found := -1
+
+	for d := 0; d <= max && found < 0; d++ {
+		if d > maxEditDistance {
+			return replace(a, b)
+		}
+
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = d
+				break
+			}
+		}
+	}
+
+	// Walk back from the end, collecting edits in reverse
+	reversed := make([]Edit, 0, n+m)
+	x, y := n, m
+	for d := found; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, Edit{Op: Equal, Text: a[x]})
+		}
+
+		if x == prevX {
+			reversed = append(reversed, Edit{Op: Insert, Text: b[prevY]})
+		} else {
+			reversed = append(reversed, Edit{Op: Delete, Text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		reversed = append(reversed, Edit{Op: Equal, Text: a[x]})
+	}
+
+	edits := make([]Edit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// replace reports every line of a as deleted and every line of b as inserted
+func replace(a, b []string) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, Edit{Op: Delete, Text: line})
+	}
+	for _, line := range b {
+		edits = append(edits, Edit{Op: Insert, Text: line})
+	}
+	return edits
+}
@@ -0,0 +1,64 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"one line", "a\n", []string{"a\n"}},
+		{"missing trailing newline", "a\nb", []string{"a\n", "b"}},
+		{"blank lines", "\n\n", []string{"\n", "\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitLines(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitLines(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{"both empty", "", "", []Edit{}},
+		{"from empty", "", "a\n", []Edit{{Insert, "a\n"}}},
+		{"to empty", "a\n", "", []Edit{{Delete, "a\n"}}},
+		{"identical", "a\nb\n", "a\nb\n", []Edit{{Equal, "a\n"}, {Equal, "b\n"}}},
+		{
+			"changed line",
+			"a\nb\nc\n", "a\nx\nc\n",
+			[]Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}},
+		},
+		{
+			"inserted and deleted lines",
+			"a\nb\nc\nd\n", "b\nc\ne\nd\n",
+			[]Edit{{Delete, "a\n"}, {Equal, "b\n"}, {Equal, "c\n"}, {Insert, "e\n"}, {Equal, "d\n"}},
+		},
+		{
+			"missing trailing newline",
+			"a\nb\n", "a\nb",
+			[]Edit{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(SplitLines(tt.a), SplitLines(tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
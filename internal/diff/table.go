@@ -0,0 +1,307 @@
+package diff
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Table is tabular data read from a CSV or JSONL file
+type Table struct {
+	// Column names in the order they first appear
+	Columns []string
+
+	// Rows keyed by column name
+	Rows []map[string]string
+}
+
+// ReadCSV reads a CSV file whose first record is the header
+func ReadCSV(r io.Reader) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return &Table{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Columns: header}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+// ReadJSONL reads a file with one JSON object per line. Values that aren't
+// strings are kept in their JSON form.
+func ReadJSONL(r io.Reader) (*Table, error) {
+	table := &Table{}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// New columns are added in sorted order, since JSON objects are unordered
+		var newColumns []string
+		row := make(map[string]string, len(object))
+		for column, raw := range object {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				row[column] = s
+			} else {
+				row[column] = string(raw)
+			}
+
+			if !seen[column] {
+				seen[column] = true
+				newColumns = append(newColumns, column)
+			}
+		}
+		sort.Strings(newColumns)
+		table.Columns = append(table.Columns, newColumns...)
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, scanner.Err()
+}
+
+// CellChange is a value that differs between two versions of a row
+type CellChange struct {
+	Column string
+	Old    string
+	New    string
+}
+
+// RowChange is a row that was added, removed or changed
+type RowChange struct {
+	// Primary key of the row, formatted as column=value pairs
+	Key string
+
+	// Values of the row in the version it is present in, for added and removed rows
+	Values []CellChange
+
+	// Values that differ, for changed rows
+	Cells []CellChange
+}
+
+// TableDiff is the difference between two versions of a table
+type TableDiff struct {
+	Keys           []string
+	AddedColumns   []string
+	RemovedColumns []string
+	Added          []RowChange
+	Removed        []RowChange
+	Changed        []RowChange
+	Unchanged      int
+}
+
+// Empty reports whether the tables hold the same columns and rows
+func (d *TableDiff) Empty() bool {
+	return len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 &&
+		len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// CompareTables matches the rows of two tables on their primary key columns and
+// reports the rows and values that differ
+func CompareTables(oldTable, newTable *Table, keys []string) (*TableDiff, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no primary key columns")
+	}
+
+	result := &TableDiff{Keys: keys}
+
+	oldColumns := make(map[string]bool)
+	for _, column := range oldTable.Columns {
+		oldColumns[column] = true
+	}
+	newColumns := make(map[string]bool)
+	for _, column := range newTable.Columns {
+		newColumns[column] = true
+	}
+
+	for _, key := range keys {
+		if len(oldTable.Rows) > 0 && !oldColumns[key] || len(newTable.Rows) > 0 && !newColumns[key] {
+			return nil, fmt.Errorf("primary key column %q is missing", key)
+		}
+	}
+
+	var shared []string
+	for _, column := range newTable.Columns {
+		if oldColumns[column] {
+			shared = append(shared, column)
+		} else {
+			result.AddedColumns = append(result.AddedColumns, column)
+		}
+	}
+	for _, column := range oldTable.Columns {
+		if !newColumns[column] {
+			result.RemovedColumns = append(result.RemovedColumns, column)
+		}
+	}
+
+	oldRows, err := indexRows(oldTable, keys)
+	if err != nil {
+		return nil, fmt.Errorf("old version: %w", err)
+	}
+	newRows, err := indexRows(newTable, keys)
+	if err != nil {
+		return nil, fmt.Errorf("new version: %w", err)
+	}
+
+	for _, row := range newTable.Rows {
+		key := rowKey(row, keys)
+		oldRow, ok := oldRows[key]
+		if !ok {
+			result.Added = append(result.Added, RowChange{Key: key, Values: rowValues(row, newTable.Columns, keys, false)})
+			continue
+		}
+
+		var cells []CellChange
+		for _, column := range shared {
+			if oldRow[column] != row[column] {
+				cells = append(cells, CellChange{Column: column, Old: oldRow[column], New: row[column]})
+			}
+		}
+		if len(cells) > 0 {
+			result.Changed = append(result.Changed, RowChange{Key: key, Cells: cells})
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	for _, row := range oldTable.Rows {
+		key := rowKey(row, keys)
+		if _, ok := newRows[key]; !ok {
+			result.Removed = append(result.Removed, RowChange{Key: key, Values: rowValues(row, oldTable.Columns, keys, true)})
+		}
+	}
+
+	return result, nil
+}
+
+// indexRows maps each row of a table by its primary key, rejecting duplicate keys
+func indexRows(table *Table, keys []string) (map[string]map[string]string, error) {
+	rows := make(map[string]map[string]string, len(table.Rows))
+	for _, row := range table.Rows {
+		key := rowKey(row, keys)
+		if _, exists := rows[key]; exists {
+			return nil, fmt.Errorf("duplicate primary key %s", key)
+		}
+		rows[key] = row
+	}
+	return rows, nil
+}
+
+// rowKey formats the primary key of a row
+func rowKey(row map[string]string, keys []string) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + row[key]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rowValues lists the non-key values of a row
+func rowValues(row map[string]string, columns, keys []string, old bool) []CellChange {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+
+	var values []CellChange
+	for _, column := range columns {
+		if isKey[column] {
+			continue
+		}
+		value := CellChange{Column: column}
+		if old {
+			value.Old = row[column]
+		} else {
+			value.New = row[column]
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// WriteTableDiff writes a table diff in a line-oriented format: added rows start
+// with '+', removed rows with '-' and changed rows with '~'
+func WriteTableDiff(w io.Writer, oldName, newName string, d *TableDiff) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	fmt.Fprintf(&sb, "@@ rows keyed on %s @@\n", strings.Join(d.Keys, ", "))
+
+	if len(d.AddedColumns) > 0 {
+		fmt.Fprintf(&sb, "+ columns: %s\n", strings.Join(d.AddedColumns, ", "))
+	}
+	if len(d.RemovedColumns) > 0 {
+		fmt.Fprintf(&sb, "- columns: %s\n", strings.Join(d.RemovedColumns, ", "))
+	}
+
+	for _, row := range d.Removed {
+		fmt.Fprintf(&sb, "- %s: %s\n", row.Key, formatValues(row.Values, true))
+	}
+	for _, row := range d.Added {
+		fmt.Fprintf(&sb, "+ %s: %s\n", row.Key, formatValues(row.Values, false))
+	}
+	for _, row := range d.Changed {
+		fmt.Fprintf(&sb, "~ %s\n", row.Key)
+		for _, cell := range row.Cells {
+			fmt.Fprintf(&sb, "~     %s: %q -> %q\n", cell.Column, cell.Old, cell.New)
+		}
+	}
+
+	fmt.Fprintf(&sb, "%d rows added, %d removed, %d changed, %d unchanged\n",
+		len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatValues formats the values of an added or removed row
+func formatValues(values []CellChange, old bool) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		v := value.New
+		if old {
+			v = value.Old
+		}
+		parts[i] = fmt.Sprintf("%s=%q", value.Column, v)
+	}
+	return strings.Join(parts, ", ")
+}
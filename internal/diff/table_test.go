@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareCSV(t *testing.T) {
+	const base = "id,name,score\n1,alice,10\n2,bob,20\n3,carol,30\n"
+
+	tests := []struct {
+		name      string
+		old, new  string
+		added     []string
+		removed   []string
+		changed   []string
+		unchanged int
+	}{
+		{name: "both empty", old: "", new: ""},
+		{name: "identical", old: base, new: base, unchanged: 3},
+		{
+			name:      "rows reordered",
+			old:       base,
+			new:       "id,name,score\n3,carol,30\n1,alice,10\n2,bob,20\n",
+			unchanged: 3,
+		},
+		{
+			name:      "columns reordered",
+			old:       base,
+			new:       "score,id,name\n10,1,alice\n20,2,bob\n30,3,carol\n",
+			unchanged: 3,
+		},
+		{
+			name:      "missing trailing newline",
+			old:       base,
+			new:       strings.TrimSuffix(base, "\n"),
+			unchanged: 3,
+		},
+		{
+			name:      "rows added, removed and changed",
+			old:       base,
+			new:       "id,name,score\n2,bob,25\n1,alice,10\n4,dave,40\n",
+			added:     []string{"id=4"},
+			removed:   []string{"id=3"},
+			changed:   []string{"id=2"},
+			unchanged: 1,
+		},
+		{
+			name:  "from empty",
+			old:   "",
+			new:   "id,name\n1,alice\n",
+			added: []string{"id=1"},
+		},
+	}
+
+	keys := func(rows []RowChange) []string {
+		var keys []string
+		for _, row := range rows {
+			keys = append(keys, row.Key)
+		}
+		return keys
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldTable, err := ReadCSV(strings.NewReader(tt.old))
+			if err != nil {
+				t.Fatal(err)
+			}
+			newTable, err := ReadCSV(strings.NewReader(tt.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d, err := CompareTables(oldTable, newTable, []string{"id"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := keys(d.Added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("added %v, want %v", got, tt.added)
+			}
+			if got := keys(d.Removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("removed %v, want %v", got, tt.removed)
+			}
+			if got := keys(d.Changed); !reflect.DeepEqual(got, tt.changed) {
+				t.Errorf("changed %v, want %v", got, tt.changed)
+			}
+			if d.Unchanged != tt.unchanged {
+				t.Errorf("%d rows unchanged, want %d", d.Unchanged, tt.unchanged)
+			}
+			if empty := len(tt.added)+len(tt.removed)+len(tt.changed) == 0; d.Empty() != empty {
+				t.Errorf("Empty() = %v, want %v", d.Empty(), empty)
+			}
+		})
+	}
+}
+
+func TestCompareTablesRejectsDuplicateKeys(t *testing.T) {
+	oldTable, err := ReadCSV(strings.NewReader("id,name\n1,alice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTable, err := ReadCSV(strings.NewReader("id,name\n1,alice\n1,bob\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CompareTables(oldTable, newTable, []string{"id"}); err == nil {
+		t.Error("CompareTables accepted a duplicate primary key")
+	}
+}
+
+func TestWriteTableDiff(t *testing.T) {
+	oldTable, err := ReadJSONL(strings.NewReader(`{"id":1,"name":"alice"}` + "\n" + `{"id":2,"name":"bob"}` + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTable, err := ReadJSONL(strings.NewReader(`{"id":2,"name":"robert"}` + "\n" + `{"id":1,"name":"alice"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := CompareTables(oldTable, newTable, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := WriteTableDiff(&sb, "a/t.jsonl", "b/t.jsonl", d); err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a/t.jsonl\n+++ b/t.jsonl\n@@ rows keyed on id @@\n" +
+		"~ id=2\n~     name: \"bob\" -> \"robert\"\n" +
+		"0 rows added, 0 removed, 1 changed, 1 unchanged\n"
+	if got := sb.String(); got != want {
+		t.Errorf("WriteTableDiff() =\n%s\nwant\n%s", got, want)
+	}
+}
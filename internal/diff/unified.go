@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// BinarySniffLength is how much of a file is checked when deciding whether it is binary
+const BinarySniffLength = 8000
+
+// IsBinary reports whether data looks like binary rather than text
+func IsBinary(data []byte) bool {
+	sniff := data
+	if len(sniff) > BinarySniffLength {
+		sniff = sniff[:BinarySniffLength]
+	}
+
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return true
+	}
+
+	if utf8.Valid(sniff) {
+		return false
+	}
+
+	// The sample may end in the middle of a multi-byte character
+	if len(data) > BinarySniffLength {
+		for i := 1; i < utf8.UTFMax; i++ {
+			if utf8.Valid(sniff[:len(sniff)-i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Unified returns a unified diff between two texts with the given number of
+// context lines, or an empty string if they are identical
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	if context < 0 {
+		context = 0
+	}
+
+	edits := Lines(SplitLines(oldText), SplitLines(newText))
+
+	// Line numbers in each version before every edit
+	oldAt := make([]int, len(edits)+1)
+	newAt := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		oldAt[i+1], newAt[i+1] = oldAt[i], newAt[i]
+		if edit.Op != Insert {
+			oldAt[i+1]++
+		}
+		if edit.Op != Delete {
+			newAt[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(edits); {
+		// Find the next change
+		for i < len(edits) && edits[i].Op == Equal {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Grow the hunk until a run of unchanged lines is too long to bridge
+		end := i
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end += context
+				if end > len(edits) {
+					end = len(edits)
+				}
+				break
+			}
+			end = run
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(oldAt[start], oldAt[end]-oldAt[start]),
+			hunkRange(newAt[start], newAt[end]-newAt[start]))
+
+		for _, edit := range edits[start:end] {
+			switch edit.Op {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(edit.Text)
+			if !strings.HasSuffix(edit.Text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// hunkRange formats the start and length of a hunk the way diff and patch expect
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{"both empty", "", "", 3, ""},
+		{"identical", "a\nb\n", "a\nb\n", 3, ""},
+		{
+			"from empty",
+			"", "a\nb\n", 3,
+			"--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"to empty",
+			"a\n", "", 3,
+			"--- a/f\n+++ b/f\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			"changed line with context",
+			"1\n2\n3\n4\n5\n6\n7\n", "1\n2\n3\nx\n5\n6\n7\n", 1,
+			"--- a/f\n+++ b/f\n@@ -3,3 +3,3 @@\n 3\n-4\n+x\n 5\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n", "x\n2\n3\n4\n5\n6\ny\n", 1,
+			"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n",
+		},
+		{
+			"missing trailing newline",
+			"a\nb\n", "a\nb", 3,
+			"--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a/f", "b/f", tt.old, tt.new, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"empty", nil, false},
+		{"text", []byte("hello\n"), false},
+		{"NUL byte", []byte("a\x00b"), true},
+		{"invalid UTF-8", []byte{0xff, 0xfe, 'a'}, true},
+		{"UTF-8", []byte("héllo wörld\n"), false},
+		{"NUL after the first block", append([]byte(strings.Repeat("a", BinarySniffLength)), 0), false},
+		{
+			"character cut at the end of the first block",
+			[]byte(strings.Repeat("a", BinarySniffLength-1) + "é"),
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.data); got != tt.want {
+				t.Errorf("IsBinary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// VersionField is the JSON field recording the format version of a file.
+// Files written before versioning was introduced have no such field and are
+// treated as version 0.
+const VersionField = "format_version"
+
+// ErrNewerFormat is returned when a file was written by a newer version of hhx
+var ErrNewerFormat = errors.New("written by a newer version of hhx")
+
+// Migration upgrades a JSON document from one format version to the next
+type Migration struct {
+	// Version the migration upgrades from; it produces version From+1
+	From int
+
+	// Short description of what changes, shown by 'hhx upgrade-repo'
+	Description string
+
+	// Apply rewrites the top-level fields of the document in place
+	Apply func(doc map[string]json.RawMessage) error
+}
+
+// Registry holds the migrations for one kind of file
+type Registry struct {
+	// Name of the file kind, used in messages
+	Name string
+
+	// Version written by this build of hhx
+	Current int
+
+	migrations map[int]Migration
+}
+
+// NewRegistry creates a registry for files currently at the given version
+func NewRegistry(name string, current int, migrations ...Migration) *Registry {
+	r := &Registry{
+		Name:       name,
+		Current:    current,
+		migrations: make(map[int]Migration, len(migrations)),
+	}
+	for _, m := range migrations {
+		r.migrations[m.From] = m
+	}
+	return r
+}
+
+// Result describes an upgrade of a single file
+type Result struct {
+	// Version the file was at before upgrading
+	From int
+
+	// Version the file is at now
+	To int
+
+	// Descriptions of the migrations that were applied, in order
+	Applied []string
+
+	// Copy of the original file, if it was upgraded
+	BackupPath string
+}
+
+// Upgraded reports whether any migrations were applied
+func (r *Result) Upgraded() bool {
+	return r.From != r.To
+}
+
+// Version returns the format version recorded in a JSON document
+func Version(data []byte) (int, error) {
+	var doc struct {
+		Version int `json:"format_version"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	return doc.Version, nil
+}
+
+// Upgrade applies the migrations needed to bring a JSON document to the current
+// version. Documents already at the current version are returned unchanged.
+func (r *Registry) Upgrade(data []byte) ([]byte, *Result, error) {
+	version, err := Version(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &Result{From: version, To: version}
+	if version > r.Current {
+		return nil, result, fmt.Errorf("%s format version %d is %w (this hhx supports up to version %d); upgrade hhx",
+			r.Name, version, ErrNewerFormat, r.Current)
+	}
+	if version == r.Current {
+		return data, result, nil
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, result, err
+	}
+
+	for ; version < r.Current; version++ {
+		m, ok := r.migrations[version]
+		if !ok {
+			return nil, result, fmt.Errorf("no migration for %s format version %d", r.Name, version)
+		}
+		if err := m.Apply(doc); err != nil {
+			return nil, result, fmt.Errorf("migrating %s from format version %d: %w", r.Name, version, err)
+		}
+		result.Applied = append(result.Applied, m.Description)
+	}
+
+	doc[VersionField] = json.RawMessage(fmt.Sprint(r.Current))
+	result.To = r.Current
+
+	upgraded, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, result, err
+	}
+	return upgraded, result, nil
+}
+
+// Load reads a file and upgrades it to the current version. An upgraded file is
+// written back in place, after the original is copied next to it as
+// <name>.v<version>.bak.
+func (r *Registry) Load(path string) ([]byte, *Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upgraded, result, err := r.Upgrade(data)
+	if err != nil || !result.Upgraded() {
+		return upgraded, result, err
+	}
+
+	result.BackupPath, err = Backup(path, result.From, data)
+	if err != nil {
+		return nil, result, fmt.Errorf("backing up %s: %w", path, err)
+	}
+
+	if err := writeFile(path, upgraded); err != nil {
+		return nil, result, fmt.Errorf("writing upgraded %s: %w", path, err)
+	}
+
+	return upgraded, result, nil
+}
+
+// Backup writes the original contents of a file at the given version next to it
+// as <name>.v<version>.bak and returns the backup's path. An earlier backup of
+// the same version is kept, since it holds the same data.
+func Backup(path string, version int, data []byte) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, version)
+
+	f, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return backupPath, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	return backupPath, f.Close()
+}
+
+// writeFile replaces a file by writing a temporary file next to it and renaming
+// it into place
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -33,3 +33,33 @@ var (
 	// ErrFileAlreadyExists is returned when a file already exists
 	ErrFileAlreadyExists = errors.New("file already exists")
 )
+
+// Snapshot-related errors
+var (
+	// ErrSnapshotNotFound is returned when no snapshot matches an ID
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrAmbiguousSnapshot is returned when a short ID matches more than one snapshot
+	ErrAmbiguousSnapshot = errors.New("snapshot ID is ambiguous")
+
+	// ErrNothingToCommit is returned when a snapshot would be identical to its parent
+	ErrNothingToCommit = errors.New("nothing to commit")
+)
+
+// Tag-related errors
+var (
+	// ErrTagExists is returned when trying to create a tag that already exists
+	ErrTagExists = errors.New("tag already exists")
+
+	// ErrTagNotFound is returned when a tag is not found
+	ErrTagNotFound = errors.New("tag not found")
+
+	// ErrInvalidTagName is returned when a tag name contains unsupported characters
+	ErrInvalidTagName = errors.New("invalid tag name")
+)
+
+// Repository-related errors
+var (
+	// ErrIndexLocked is returned when another process holds the index lock
+	ErrIndexLocked = errors.New("index is locked by another hhx process")
+)
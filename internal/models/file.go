@@ -17,6 +17,8 @@ const (
 	StatusModified  FileStatus = "modified"  // File is tracked but modified
 	StatusStaged    FileStatus = "staged"    // File is staged for commit
 	StatusSynced    FileStatus = "synced"    // File is synced with the server
+	StatusDeleted   FileStatus = "deleted"   // File deletion is staged for upload
+	StatusFailed    FileStatus = "failed"    // File is staged but its last upload failed
 )
 
 // File represents a file in the repository
@@ -28,6 +30,46 @@ type File struct {
 	Status       FileStatus `json:"status"`               // File status
 	RemoteURL    string     `json:"remote_url,omitempty"` // URL of the file on the server
 	Collection   string     `json:"collection,omitempty"` // Collection name
+	Stat         *StatInfo  `json:"stat,omitempty"`       // File system metadata the hash was computed from
+	Error        string     `json:"error,omitempty"`      // Why the last push of the file or its deletion failed
+}
+
+// racyWindow is how recently a file may have been modified for its metadata to
+// be trusted. Files written within the same timestamp granularity as hashing
+// could change again without their mtime moving, so they are always rehashed.
+const racyWindow = 2 * time.Second
+
+// StatInfo records the file system metadata a file's hash was computed from, so
+// the hash can be reused as long as the metadata is unchanged
+type StatInfo struct {
+	Size  int64  `json:"size"`            // File size in bytes
+	MTime int64  `json:"mtime"`           // Modification time in nanoseconds
+	CTime int64  `json:"ctime,omitempty"` // Change time in nanoseconds, where supported
+	Inode uint64 `json:"inode,omitempty"` // Inode number, where supported
+}
+
+// newStatInfo captures the metadata of a file
+func newStatInfo(info os.FileInfo) *StatInfo {
+	stat := &StatInfo{
+		Size:  info.Size(),
+		MTime: info.ModTime().UnixNano(),
+	}
+	fillPlatformStat(stat, info)
+	return stat
+}
+
+// statForCache captures the metadata of a file that was just hashed, or returns
+// nil if the file was modified too recently for the metadata to be trusted
+func statForCache(info os.FileInfo) *StatInfo {
+	if time.Since(info.ModTime()) < racyWindow {
+		return nil
+	}
+	return newStatInfo(info)
+}
+
+// Matches reports whether a file still has the metadata its hash was computed from
+func (s *StatInfo) Matches(info os.FileInfo) bool {
+	return s != nil && *s == *newStatInfo(info)
 }
 
 // NewFileFromPath creates a new File instance from the given path
@@ -56,15 +98,19 @@ func NewFileFromPath(repoRoot, path string) (*File, error) {
 		return nil, err
 	}
 
-	file := &File{
+	return newFile(relativePath, info, hash), nil
+}
+
+// newFile creates an untracked File for a hashed file
+func newFile(relativePath string, info os.FileInfo, hash string) *File {
+	return &File{
 		Path:         relativePath,
 		Size:         info.Size(),
 		Hash:         hash,
 		LastModified: info.ModTime(),
 		Status:       StatusUntracked,
+		Stat:         statForCache(info),
 	}
-
-	return file, nil
 }
 
 // hashFile calculates the SHA-256 hash of a file
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// hashJob is a file waiting to be hashed by a hashPool
+type hashJob struct {
+	path    string      // Absolute path of the file
+	relPath string      // Path relative to the repository root, with forward slashes
+	info    os.FileInfo // Metadata captured when the file was found
+	hash    string      // SHA-256 hash, filled in by the pool unless cached
+	cached  bool        // Whether the hash was reused from the index
+	err     error       // Error hashing the file
+}
+
+// file creates an untracked File from a hashed job
+func (j *hashJob) file() *File {
+	return newFile(j.relPath, j.info, j.hash)
+}
+
+// hashPool hashes files on a bounded number of workers while the caller keeps
+// walking the tree. Jobs are returned in the order they were submitted, so
+// results don't depend on which worker finishes first.
+type hashPool struct {
+	jobs    chan *hashJob
+	wg      sync.WaitGroup
+	ordered []*hashJob
+}
+
+// newHashPool starts a pool with the given number of workers; zero or less
+// starts one worker per CPU
+func newHashPool(workers int) *hashPool {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	pool := &hashPool{
+		jobs: make(chan *hashJob, workers*4),
+	}
+
+	for i := 0; i < workers; i++ {
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			for job := range pool.jobs {
+				job.hash, job.err = hashFile(job.path)
+			}
+		}()
+	}
+
+	return pool
+}
+
+// submit queues a file for hashing; jobs with a cached hash skip the workers.
+// submit must not be called concurrently.
+func (p *hashPool) submit(job *hashJob) {
+	p.ordered = append(p.ordered, job)
+	if !job.cached {
+		p.jobs <- job
+	}
+}
+
+// wait waits until every submitted file is hashed and returns the jobs in
+// submission order. The pool can't be used afterwards.
+func (p *hashPool) wait() []*hashJob {
+	close(p.jobs)
+	p.wg.Wait()
+	return p.ordered
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashPoolKeepsSubmissionOrder(t *testing.T) {
+	root := t.TempDir()
+	pool := newHashPool(8)
+
+	const files = 200
+	for i := 0; i < files; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file%03d", i))
+		// Larger files first, so later jobs tend to finish earlier
+		if err := os.WriteFile(path, make([]byte, (files-i)*1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.submit(&hashJob{path: path, relPath: filepath.Base(path), info: info})
+	}
+
+	jobs := pool.wait()
+	if len(jobs) != files {
+		t.Fatalf("%d jobs returned, want %d", len(jobs), files)
+	}
+	for i, job := range jobs {
+		if want := fmt.Sprintf("file%03d", i); job.relPath != want {
+			t.Fatalf("job %d is %s, want %s", i, job.relPath, want)
+		}
+		if want := hashOfContent(string(make([]byte, (files-i)*1024))); job.err != nil || job.hash != want {
+			t.Errorf("%s hashed as %s (%v), want %s", job.relPath, job.hash, job.err, want)
+		}
+	}
+}
+
+func TestHashPoolSkipsCachedJobs(t *testing.T) {
+	pool := newHashPool(2)
+
+	// Hashing a file that doesn't exist would fail
+	pool.submit(&hashJob{path: filepath.Join(t.TempDir(), "missing"), relPath: "missing", hash: "cached-hash", cached: true})
+
+	jobs := pool.wait()
+	if len(jobs) != 1 || jobs[0].err != nil || jobs[0].hash != "cached-hash" {
+		t.Errorf("cached job: %+v, want its hash kept without hashing", jobs[0])
+	}
+}
+
+func TestStageDirectoryDoesNotDependOnJobs(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		path := filepath.Join(root, "episodes", fmt.Sprintf("ep%02d", i%5), fmt.Sprintf("step%02d.bin", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	staged := make(map[int]map[string]string)
+	for _, jobs := range []int{1, 8} {
+		idx := NewIndex(root)
+		idx.SetHashJobs(jobs)
+		if err := idx.StageDirectory(filepath.Join(root, "episodes")); err != nil {
+			t.Fatal(err)
+		}
+
+		staged[jobs] = make(map[string]string)
+		for _, file := range idx.GetStagedFiles() {
+			staged[jobs][file.Path] = file.Hash
+		}
+	}
+
+	if len(staged[1]) != 50 {
+		t.Fatalf("%d files staged with one job, want 50", len(staged[1]))
+	}
+	for path, hash := range staged[1] {
+		if staged[8][path] != hash {
+			t.Errorf("%s hashed as %s with one job and %s with eight", path, hash, staged[8][path])
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package models
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IgnoreFileName is the name of the files holding ignore patterns. They can be
+// placed at the repository root or in any subdirectory.
+const IgnoreFileName = ".hhxignore"
+
+// defaultIgnorePatterns are applied before any ignore file, so they can be
+// overridden with negated patterns
+var defaultIgnorePatterns = []string{
+	".*/",
+	"build/",
+	"cmake-build-debug/",
+}
+
+// builtinIgnoredDirs are never walked, regardless of any ignore file
+var builtinIgnoredDirs = []string{".hhx", ".git"}
+
+// IgnoreRule is a single pattern read from an ignore file
+type IgnoreRule struct {
+	// Ignore file the rule was read from, relative to the repository root
+	Source string
+
+	// Line number of the rule in its ignore file
+	Line int
+
+	// Pattern as written in the ignore file
+	Pattern string
+
+	// Whether the pattern re-includes paths excluded by earlier patterns
+	Negate bool
+
+	// Whether the pattern only matches directories
+	DirOnly bool
+
+	// Directory the pattern is relative to ("" for the repository root)
+	base string
+
+	re *regexp.Regexp
+}
+
+// matches reports whether the rule matches a path relative to the repository root
+func (r *IgnoreRule) matches(relPath string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = relPath[len(r.base)+1:]
+	}
+
+	return r.re.MatchString(relPath)
+}
+
+// IgnoreMatcher decides which paths are ignored using gitignore semantics.
+// Ignore files are read lazily, the first time a path below them is checked.
+type IgnoreMatcher struct {
+	repoRoot string
+	defaults []*IgnoreRule
+
+	mu    sync.Mutex
+	rules map[string][]*IgnoreRule
+	dirs  map[string]*IgnoreRule
+}
+
+// NewIgnoreMatcher creates a matcher for the repository at repoRoot
+func NewIgnoreMatcher(repoRoot string) *IgnoreMatcher {
+	m := &IgnoreMatcher{
+		repoRoot: repoRoot,
+		rules:    make(map[string][]*IgnoreRule),
+		dirs:     make(map[string]*IgnoreRule),
+	}
+
+	for i, pattern := range defaultIgnorePatterns {
+		if rule := parseIgnoreRule(pattern, "", "<default>", i+1); rule != nil {
+			m.defaults = append(m.defaults, rule)
+		}
+	}
+
+	return m
+}
+
+// IsIgnored reports whether a path relative to the repository root is ignored
+func (m *IgnoreMatcher) IsIgnored(relPath string, isDir bool) bool {
+	rule := m.Match(relPath, isDir)
+	return rule != nil && !rule.Negate
+}
+
+// Match returns the rule that decides whether a path relative to the repository
+// root is ignored, or nil if no rule matches. A negated rule means the path was
+// explicitly re-included.
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) *IgnoreRule {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "" || relPath == "." {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A path inside an ignored directory can't be re-included
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule := m.matchDir(strings.Join(parts[:i], "/")); rule != nil && !rule.Negate {
+			return rule
+		}
+	}
+
+	return m.match(relPath, isDir)
+}
+
+// matchDir returns the rule deciding whether a directory is ignored, caching
+// the result since every path below it asks the same question
+func (m *IgnoreMatcher) matchDir(relDir string) *IgnoreRule {
+	if rule, ok := m.dirs[relDir]; ok {
+		return rule
+	}
+
+	rule := m.match(relDir, true)
+	m.dirs[relDir] = rule
+	return rule
+}
+
+// match returns the last rule matching a path, without looking at its parents
+func (m *IgnoreMatcher) match(relPath string, isDir bool) *IgnoreRule {
+	for _, name := range builtinIgnoredDirs {
+		if relPath == name || strings.HasPrefix(relPath, name+"/") {
+			return &IgnoreRule{Source: "<builtin>", Pattern: name + "/", DirOnly: true}
+		}
+	}
+
+	var matched *IgnoreRule
+	for _, rule := range m.defaults {
+		if rule.matches(relPath, isDir) {
+			matched = rule
+		}
+	}
+
+	// Ignore files closer to the path take precedence over those further up
+	dir := ""
+	parts := strings.Split(relPath, "/")
+	for i := 0; i < len(parts); i++ {
+		for _, rule := range m.loadRules(dir) {
+			if rule.matches(relPath, isDir) {
+				matched = rule
+			}
+		}
+		dir = path.Join(dir, parts[i])
+	}
+
+	return matched
+}
+
+// loadRules returns the rules of the ignore file in a directory relative to the
+// repository root, reading it on first use
+func (m *IgnoreMatcher) loadRules(relDir string) []*IgnoreRule {
+	if rules, ok := m.rules[relDir]; ok {
+		return rules
+	}
+
+	source := path.Join(relDir, IgnoreFileName)
+	rules, err := readIgnoreFile(filepath.Join(m.repoRoot, filepath.FromSlash(source)), relDir, source)
+	if err != nil {
+		// Unreadable ignore files behave as if they were empty
+		rules = nil
+	}
+
+	m.rules[relDir] = rules
+	return rules
+}
+
+// readIgnoreFile parses the ignore file at path
+func readIgnoreFile(path, base, source string) ([]*IgnoreRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []*IgnoreRule
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if rule := parseIgnoreRule(scanner.Text(), base, source, line); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, scanner.Err()
+}
+
+// parseIgnoreRule parses a single line of an ignore file, returning nil for
+// blank lines and comments
+func parseIgnoreRule(line, base, source string, lineNumber int) *IgnoreRule {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	rule := &IgnoreRule{
+		Source:  source,
+		Line:    lineNumber,
+		Pattern: line,
+		base:    base,
+	}
+
+	pattern := line
+	if strings.HasPrefix(pattern, "!") {
+		rule.Negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, "\\!") || strings.HasPrefix(pattern, "\\#") {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.DirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	if pattern == "" {
+		return nil
+	}
+
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return nil
+	}
+	rule.re = re
+
+	return rule
+}
+
+// compileGlob compiles a gitignore-style glob into a regular expression. Patterns
+// containing a slash are anchored to their base directory, others match a name
+// at any depth. "*" and "?" never match a slash, "**" matches any number of
+// directories.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		atSegmentStart := i == 0 || pattern[i-1] == '/'
+
+		switch {
+		case c == '*' && atSegmentStart && strings.HasPrefix(pattern[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && atSegmentStart && pattern[i:] == "**":
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			sb.WriteString("[")
+			if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+				sb.WriteString("^")
+				class = class[1:]
+			}
+			sb.WriteString(strings.ReplaceAll(class, `\`, `\\`))
+			sb.WriteString("]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		// Patterns without a slash match a name at any depth
+		{"*.log", "debug.log", true},
+		{"*.log", "logs/debug.log", true},
+		{"*.log", "debug.log.txt", false},
+		{"cache", "a/b/cache", true},
+
+		// Patterns with a slash are anchored
+		{"/cache", "cache", true},
+		{"/cache", "a/cache", false},
+		{"docs/*.pdf", "docs/a.pdf", true},
+		{"docs/*.pdf", "x/docs/a.pdf", false},
+
+		// "*" and "?" stay within a path segment
+		{"docs/*.pdf", "docs/sub/a.pdf", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file/.txt", false},
+
+		// "**" matches any number of directories
+		{"**/cache", "cache", true},
+		{"**/cache", "a/b/cache", true},
+		{"data/**/cache", "data/cache", true},
+		{"data/**/cache", "data/a/b/cache", true},
+		{"data/**/cache", "other/cache", false},
+		{"checkpoints/**", "checkpoints/run1/model.pt", true},
+		{"checkpoints/**", "old/checkpoints/model.pt", false},
+
+		// Character classes and escapes
+		{"run[0-9].csv", "run7.csv", true},
+		{"run[!0-9].csv", "run7.csv", false},
+		{"run[!0-9].csv", "runx.csv", true},
+		{`\*.txt`, "*.txt", true},
+		{`\*.txt`, "a.txt", false},
+	}
+
+	for _, tt := range tests {
+		re, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("compileGlob(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	ignoreFiles := map[string]string{
+		IgnoreFileName:                       "# Logs, except the ones we keep\n*.log\n!keep.log\n\ntmp/\n/out\ndata/**/cache\ndocs/*.pdf\n!.github/\n",
+		filepath.Join("sub", IgnoreFileName): "!*.log\nsecret.txt\n",
+	}
+	for name, content := range ignoreFiles {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"pattern", "debug.log", false, true},
+		{"pattern in a subdirectory", "logs/debug.log", false, true},
+		{"negation", "keep.log", false, false},
+		{"negation in a subdirectory", "logs/keep.log", false, false},
+		{"directory-only pattern on a directory", "tmp", true, true},
+		{"directory-only pattern on a file", "tmp", false, false},
+		{"file in an ignored directory", "tmp/notes.txt", false, true},
+		{"negation inside an ignored directory", "tmp/keep.log", false, true},
+		{"anchored pattern", "out", false, true},
+		{"anchored pattern in a subdirectory", "src/out", false, false},
+		{"double star matching no directories", "data/cache", true, true},
+		{"double star matching directories", "data/a/b/cache", true, true},
+		{"double star outside its base", "other/cache", true, false},
+		{"star within a segment", "docs/guide.pdf", false, true},
+		{"star across segments", "docs/old/guide.pdf", false, false},
+		{"nested negation overrides the root", "sub/debug.log", false, false},
+		{"nested pattern", "sub/secret.txt", false, true},
+		{"nested pattern outside its directory", "secret.txt", false, false},
+		{"nested pattern below its directory", "sub/deep/secret.txt", false, true},
+		{"default pattern", ".cache", true, true},
+		{"default pattern overridden", ".github", true, false},
+		{"default directory-only pattern on a file", ".env", false, false},
+		{"builtin directory", ".hhx/index", false, true},
+		{"not matched", "data/train.csv", false, false},
+	}
+
+	matcher := NewIgnoreMatcher(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.IsIgnored(tt.path, tt.isDir); got != tt.ignored {
+				t.Errorf("IsIgnored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherReportsDecidingRule(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("*.log\n!keep.log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := NewIgnoreMatcher(root).Match("keep.log", false)
+	if rule == nil || !rule.Negate || rule.Source != IgnoreFileName || rule.Line != 2 {
+		t.Fatalf("deciding rule: %+v, want the negation on line 2 of %s", rule, IgnoreFileName)
+	}
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +10,9 @@ import (
 
 // Index represents the staging area and metadata store
 type Index struct {
+	// Version of the index file format
+	FormatVersion int `json:"format_version"`
+
 	// Files in the staging area, keyed by their path
 	Files map[string]*File `json:"files"`
 
@@ -26,75 +28,62 @@ type Index struct {
 	// Default collection to use when none is specified
 	DefaultCollection string `json:"default_collection,omitempty"`
 
+	// Rules routing files to collections by path
+	Routes []*CollectionRoute `json:"routes,omitempty"`
+
 	// Repository root directory
 	RepoRoot string `json:"repo_root"`
 
 	// Mutex for concurrent access
 	mu sync.RWMutex `json:"-"`
-}
 
-// NewIndex creates a new index
-func NewIndex(repoRoot string) *Index {
-	return &Index{
-		Files:       make(map[string]*File),
-		Deleted:     make(map[string]*File),
-		Synced:      make(map[string]*File),
-		Collections: make(map[string]*Collection),
-		RepoRoot:    repoRoot,
-	}
-}
+	// Whether recorded file metadata is ignored and every file is rehashed
+	noStatCache bool
 
-// Load loads the index from the given file
-func LoadIndex(path string) (*Index, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Determine repository root from index path
-			repoRoot := filepath.Dir(filepath.Dir(path))
-			return NewIndex(repoRoot), nil
-		}
-		return nil, err
-	}
+	// Number of files hashed in parallel, zero for one per CPU
+	hashJobs int
 
-	var index Index
-	if err := json.Unmarshal(data, &index); err != nil {
-		return nil, err
-	}
+	// Collection newly staged files are tagged with, overriding routes
+	stagingCollection string
 
-	// Initialize maps if they're nil
-	if index.Files == nil {
-		index.Files = make(map[string]*File)
-	}
-	if index.Deleted == nil {
-		index.Deleted = make(map[string]*File)
-	}
-	if index.Synced == nil {
-		index.Synced = make(map[string]*File)
-	}
-	if index.Collections == nil {
-		index.Collections = make(map[string]*Collection)
-	}
+	// Entry segments read from disk that haven't been decoded yet
+	pending   [][]byte
+	decode    sync.Once
+	decodeErr error
 
-	return &index, nil
-}
+	// Paths whose entries changed since the index was loaded or saved
+	changed map[string]bool
 
-// Save saves the index to the given file
-func (idx *Index) Save(path string) error {
-	idx.mu.RLock()
-	defer idx.mu.RUnlock()
+	// Metadata segment as last read or written, to detect changes
+	savedMeta []byte
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+	// Index file as last read or written, to check changes can be appended
+	diskInfo os.FileInfo
+	diskSize int64
 
-	data, err := json.MarshalIndent(idx, "", "  ")
-	if err != nil {
-		return err
-	}
+	// Number of entry records in the last full write and appended since
+	baseRecords    int
+	journalRecords int
+
+	// Whether the next save must rewrite the whole file
+	rewrite bool
 
-	return os.WriteFile(path, data, 0644)
+	// File in an older format the index was read from, if any
+	upgrade *indexUpgrade
+}
+
+// NewIndex creates a new index
+func NewIndex(repoRoot string) *Index {
+	return &Index{
+		FormatVersion: IndexFormatVersion,
+		Files:         make(map[string]*File),
+		Deleted:       make(map[string]*File),
+		Synced:        make(map[string]*File),
+		Collections:   make(map[string]*Collection),
+		RepoRoot:      repoRoot,
+		changed:       make(map[string]bool),
+		rewrite:       true,
+	}
 }
 
 // AddCollection adds a new collection to the index
@@ -132,6 +121,9 @@ func (idx *Index) UpdateCollection(collection *Collection) error {
 		return fmt.Errorf("collection name cannot be empty")
 	}
 
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
 	// Find the collection in the index
 	for i, c := range idx.Collections {
 		if c.Name == collection.Name {
@@ -146,6 +138,10 @@ func (idx *Index) UpdateCollection(collection *Collection) error {
 
 // GetFilesByCollection returns all files in a specific collection
 func (idx *Index) GetFilesByCollection(collectionName string) []*File {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
 	var files []*File
 
 	for _, file := range idx.Files {
@@ -169,6 +165,15 @@ func (idx *Index) RemoveCollection(name string) error {
 
 	delete(idx.Collections, name)
 
+	// Drop routes that would send files to the removed collection
+	routes := idx.Routes[:0]
+	for _, route := range idx.Routes {
+		if route.Collection != name {
+			routes = append(routes, route)
+		}
+	}
+	idx.Routes = routes
+
 	// If the default collection was removed, update the default
 	if idx.DefaultCollection == name {
 		if len(idx.Collections) > 0 {
@@ -241,40 +246,130 @@ func (idx *Index) SetDefaultCollection(name string) error {
 	return nil
 }
 
-// StageFile stages a file
-func (idx *Index) StageFile(path string) error {
+// DisableStatCache forces every file to be rehashed, even if its size, mtime,
+// inode and change time match the ones recorded in the index
+func (idx *Index) DisableStatCache() {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	file, err := NewFileFromPath(idx.RepoRoot, path)
-	if err != nil {
-		return err
+	idx.noStatCache = true
+}
+
+// SetHashJobs sets how many files are hashed in parallel; zero or less uses
+// one worker per CPU
+func (idx *Index) SetHashJobs(jobs int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.hashJobs = jobs
+}
+
+// newHashJob prepares a file for hashing, reusing the hash recorded for it if
+// its metadata is unchanged since it was hashed; the caller must hold the lock
+func (idx *Index) newHashJob(path, relPath string, info os.FileInfo) *hashJob {
+	job := &hashJob{path: path, relPath: relPath, info: info}
+	if idx.noStatCache {
+		return job
 	}
 
-	if file == nil {
-		// Skip directories
-		return nil
+	for _, entry := range []*File{idx.Files[relPath], idx.Synced[relPath]} {
+		if entry != nil && entry.Hash != "" && entry.Stat.Matches(info) {
+			job.hash = entry.Hash
+			job.cached = true
+			break
+		}
+	}
+
+	return job
+}
+
+// hashFiles calls collect with the index read-locked and hashes the files it
+// submits in parallel. The jobs are returned in submission order.
+func (idx *Index) hashFiles(collect func(pool *hashPool) error) ([]*hashJob, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	pool := newHashPool(idx.hashJobs)
+	err := collect(pool)
+	jobs := pool.wait()
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if this file was previously synced
+	return jobs, nil
+}
+
+// stage records a hashed file in the staging area; the caller must hold the lock
+func (idx *Index) stage(file *File) {
+	// Keep the remote URL of previously synced files so they show as modified
 	if synced, ok := idx.Synced[file.Path]; ok {
-		if synced.Hash == file.Hash {
-			file.Status = StatusSynced
-			file.RemoteURL = synced.RemoteURL
-		} else {
-			file.Status = StatusModified
-			file.RemoteURL = synced.RemoteURL
-		}
-	} else {
-		file.Status = StatusUntracked
+		file.RemoteURL = synced.RemoteURL
+		file.Collection = synced.Collection
+	}
+
+	// Keep the collection a file was tagged with when it is staged again
+	if staged, ok := idx.Files[file.Path]; ok && staged.Collection != "" {
+		file.Collection = staged.Collection
+	}
+
+	if idx.stagingCollection != "" {
+		file.Collection = idx.stagingCollection
 	}
 
-	// Now that we've updated the status, mark it as staged
 	file.Status = StatusStaged
 	idx.Files[file.Path] = file
 
 	// Remove from deleted if it was there
 	delete(idx.Deleted, file.Path)
+	idx.touch(file.Path)
+}
+
+// StageFile stages a file
+func (idx *Index) StageFile(path string) error {
+	return idx.StageFiles([]string{path})
+}
+
+// StageFiles stages several files, hashing them in parallel. Nothing is staged
+// if any of the files can't be read.
+func (idx *Index) StageFiles(paths []string) error {
+	jobs, err := idx.hashFiles(func(pool *hashPool) error {
+		for _, path := range paths {
+			relPath, err := filepath.Rel(idx.RepoRoot, path)
+			if err != nil {
+				return err
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+
+			// Skip directories
+			if info.IsDir() {
+				continue
+			}
+
+			pool.submit(idx.newHashJob(path, filepath.ToSlash(relPath), info))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, job := range jobs {
+		if job.err != nil {
+			return job.err
+		}
+	}
+
+	for _, job := range jobs {
+		idx.stage(job.file())
+	}
 
 	return nil
 }
@@ -290,74 +385,299 @@ func (idx *Index) UnstageFile(path string) {
 	}
 	relPath = filepath.ToSlash(relPath)
 
+	idx.loadEntries()
 	delete(idx.Files, relPath)
+	idx.touch(relPath)
+
+	// Turn a staged deletion back into an unstaged one
+	if deleted, ok := idx.Deleted[relPath]; ok && deleted.Status == StatusDeleted {
+		deleted.Status = StatusUntracked
+		deleted.Error = ""
+	}
 }
 
-// StageDirectory stages all files in a directory recursively
-func (idx *Index) StageDirectory(dirPath string) error {
+// StageDeletion stages the deletion of a tracked file so it is removed from
+// the remote collection on the next push
+func (idx *Index) StageDeletion(path string) error {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	relPath, err := filepath.Rel(idx.RepoRoot, path)
+	if err != nil {
+		return err
+	}
 
-		if info.IsDir() {
-			// Skip .git, .hhx directories and other hidden directories
-			if info.Name() == ".git" || info.Name() == ".hhx" || info.Name()[0] == '.' {
-				return filepath.SkipDir
+	idx.loadEntries()
+	return idx.stageDeletion(filepath.ToSlash(relPath))
+}
+
+// stageDeletion stages the deletion of a tracked file; the caller must hold the lock
+func (idx *Index) stageDeletion(relPath string) error {
+	file, ok := idx.Synced[relPath]
+	if !ok {
+		file, ok = idx.Deleted[relPath]
+	}
+	if !ok {
+		return ErrFileNotFound
+	}
+
+	file.Status = StatusDeleted
+	file.Error = ""
+	idx.Deleted[relPath] = file
+	delete(idx.Synced, relPath)
+	delete(idx.Files, relPath)
+	idx.touch(relPath)
+
+	return nil
+}
+
+// stageMissingFiles stages the deletion of tracked files under relDir that no
+// longer exist in the working tree; the caller must hold the lock
+func (idx *Index) stageMissingFiles(relDir string) error {
+	prefix := relDir + "/"
+	if relDir == "." {
+		prefix = ""
+	}
+
+	var missing []string
+	for _, tracked := range []map[string]*File{idx.Synced, idx.Deleted} {
+		for path := range tracked {
+			if !strings.HasPrefix(path, prefix) {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(idx.RepoRoot, filepath.FromSlash(path))); os.IsNotExist(err) {
+				missing = append(missing, path)
 			}
-			return nil
 		}
+	}
 
-		file, err := NewFileFromPath(idx.RepoRoot, path)
-		if err != nil {
+	for _, path := range missing {
+		if err := idx.stageDeletion(path); err != nil {
 			return err
 		}
+	}
 
-		if file == nil {
-			return nil
+	return nil
+}
+
+// GetStagedDeletions returns all files whose deletion is staged
+func (idx *Index) GetStagedDeletions() []*File {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	var files []*File
+	for _, file := range idx.Deleted {
+		if file.Status == StatusDeleted {
+			files = append(files, file)
 		}
+	}
+	return files
+}
 
-		// Check if this file was previously synced
-		if synced, ok := idx.Synced[file.Path]; ok {
-			if synced.Hash == file.Hash {
-				file.Status = StatusSynced
-				file.RemoteURL = synced.RemoteURL
-			} else {
-				file.Status = StatusModified
-				file.RemoteURL = synced.RemoteURL
+// ConfirmDeletion forgets a deleted file once the server has removed it
+func (idx *Index) ConfirmDeletion(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadEntries()
+
+	delete(idx.Deleted, path)
+	idx.touch(path)
+}
+
+// StageDirectory stages all files in a directory recursively
+func (idx *Index) StageDirectory(dirPath string) error {
+	ignore := NewIgnoreMatcher(idx.RepoRoot)
+
+	jobs, err := idx.hashFiles(func(pool *hashPool) error {
+		return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
 			}
-		} else {
-			file.Status = StatusUntracked
-		}
 
-		// Now that we've updated the status, mark it as staged
-		file.Status = StatusStaged
-		idx.Files[file.Path] = file
+			relPath, err := filepath.Rel(idx.RepoRoot, path)
+			if err != nil {
+				return err
+			}
 
-		return nil
+			// Skip paths matched by .hhxignore, except the directory being staged
+			if path != dirPath && ignore.IsIgnored(relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			pool.submit(idx.newHashJob(path, filepath.ToSlash(relPath), info))
+			return nil
+		})
 	})
+	if err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, job := range jobs {
+		if job.err != nil {
+			return job.err
+		}
+	}
+
+	for _, job := range jobs {
+		idx.stage(job.file())
+	}
+
+	// Stage deletions of tracked files that were removed from the directory
+	relDir, err := filepath.Rel(idx.RepoRoot, dirPath)
+	if err != nil {
+		return err
+	}
+
+	return idx.stageMissingFiles(filepath.ToSlash(relDir))
 }
 
-// MarkSynced marks a file as synced
-func (idx *Index) MarkSynced(path string, remoteURL string) {
+// MarkSynced marks a file as synced to a collection
+func (idx *Index) MarkSynced(path string, remoteURL string, collection string) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
+	idx.loadEntries()
 
 	if file, ok := idx.Files[path]; ok {
 		file.Status = StatusSynced
+		file.Error = ""
 		file.RemoteURL = remoteURL
+		file.Collection = collection
 		idx.Synced[path] = file
 		delete(idx.Files, path)
+		idx.touch(path)
+	}
+}
+
+// MarkFailed records why pushing a staged file or deletion failed. The file
+// stays staged, so the next push tries it again.
+func (idx *Index) MarkFailed(path string, message string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadEntries()
+
+	if file, ok := idx.Files[path]; ok {
+		file.Status = StatusFailed
+		file.Error = message
+		idx.touch(path)
+		return
+	}
+
+	// A failed deletion stays marked deleted, which is what keeps it staged
+	if file, ok := idx.Deleted[path]; ok && file.Status == StatusDeleted {
+		file.Error = message
+		idx.touch(path)
+	}
+}
+
+// GetFailedFiles returns the staged files and deletions whose last push failed
+func (idx *Index) GetFailedFiles() []*File {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	var files []*File
+	for _, file := range idx.Files {
+		if file.Status == StatusFailed {
+			files = append(files, file)
+		}
+	}
+	for _, file := range idx.Deleted {
+		if file.Status == StatusDeleted && file.Error != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// RecordSynced records a file as synced with the server, e.g. after it was pulled
+func (idx *Index) RecordSynced(file *File) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadEntries()
+
+	file.Status = StatusSynced
+	idx.Synced[file.Path] = file
+	delete(idx.Files, file.Path)
+	delete(idx.Deleted, file.Path)
+	idx.touch(file.Path)
+}
+
+// RestoreSynced records that a tracked file was written back with its synced
+// content, turning a deletion back into a synced file
+func (idx *Index) RestoreSynced(path string, info os.FileInfo) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadEntries()
+
+	file, ok := idx.Synced[path]
+	if !ok {
+		file, ok = idx.Deleted[path]
+		if !ok {
+			return ErrFileNotFound
+		}
+		delete(idx.Deleted, path)
+	}
+
+	file.Status = StatusSynced
+	file.LastModified = info.ModTime()
+	file.Stat = statForCache(info)
+	idx.Synced[path] = file
+	idx.touch(path)
+	return nil
+}
+
+// ForgetSynced stops tracking a synced file without staging its deletion, so
+// the file stays on the server
+func (idx *Index) ForgetSynced(path string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.loadEntries()
+
+	delete(idx.Synced, path)
+	delete(idx.Deleted, path)
+	idx.touch(path)
+}
+
+// GetSyncedFile returns the synced entry for a path, if any
+func (idx *Index) GetSyncedFile(path string) (*File, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	file, ok := idx.Synced[path]
+	return file, ok
+}
+
+// GetTrackedFile returns the last synced entry for a path, including files
+// that have since been deleted from the working tree
+func (idx *Index) GetTrackedFile(path string) (*File, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	if file, ok := idx.Synced[path]; ok {
+		return file, true
 	}
+	file, ok := idx.Deleted[path]
+	return file, ok
 }
 
 // GetStagedFiles returns all staged files
 func (idx *Index) GetStagedFiles() []*File {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
+	idx.loadEntries()
 
 	files := make([]*File, 0, len(idx.Files))
 	for _, file := range idx.Files {
@@ -370,6 +690,7 @@ func (idx *Index) GetStagedFiles() []*File {
 func (idx *Index) GetAllFiles() []*File {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
+	idx.loadEntries()
 
 	files := make([]*File, 0, len(idx.Files)+len(idx.Synced)+len(idx.Deleted))
 
@@ -390,6 +711,53 @@ func (idx *Index) GetAllFiles() []*File {
 
 // ScanWorkingDirectory scans the working directory for changes
 func (idx *Index) ScanWorkingDirectory() ([]*File, []*File, []*File, error) {
+	ignore := NewIgnoreMatcher(idx.RepoRoot)
+
+	jobs, err := idx.hashFiles(func(pool *hashPool) error {
+		return filepath.Walk(idx.RepoRoot, func(path string, info os.FileInfo, err error) error {
+			// Handle errors from filepath.Walk
+			if err != nil {
+				fmt.Printf("Warning: Failed to close response body: %v\n", err)
+				// Skip files that can't be accessed instead of stopping the entire walk
+				return nil
+			}
+
+			// Skip if info is nil
+			if info == nil {
+				fmt.Printf("Warning: FileInfo is nil for path: %s\n", path)
+				return nil
+			}
+
+			// Get relative path
+			relPath, err := filepath.Rel(idx.RepoRoot, path)
+			if err != nil {
+				fmt.Printf("Warning: Failed to get relative path for %s: %v\n", path, err)
+				return nil
+			}
+			relPath = filepath.ToSlash(relPath)
+
+			// Skip paths matched by .hhxignore, along with the .hhx directory itself
+			if ignore.IsIgnored(relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			// Hash the file in the background, unless it is unchanged since it was last hashed
+			pool.submit(idx.newHashJob(path, relPath, info))
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+		return nil, nil, nil, err
+	}
+
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
@@ -402,108 +770,70 @@ func (idx *Index) ScanWorkingDirectory() ([]*File, []*File, []*File, error) {
 	// Track new, modified, and unchanged files
 	var newFiles, modifiedFiles, unchangedFiles []*File
 
-	err := filepath.Walk(idx.RepoRoot, func(path string, info os.FileInfo, err error) error {
-		// Handle errors from filepath.Walk
-		if err != nil {
-			fmt.Printf("Warning: Failed to close response body: %v\n", err)
-			// Skip files that can't be accessed instead of stopping the entire walk
-			return nil
-		}
-
-		// Skip if info is nil
-		if info == nil {
-			fmt.Printf("Warning: FileInfo is nil for path: %s\n", path)
-			return nil
+	for _, job := range jobs {
+		if job.err != nil {
+			fmt.Printf("Warning: Failed to get hash for %s: %v\n", job.path, job.err)
+			// Skip files that can't be hashed
+			continue
 		}
 
-		// Skip .git, .hhx directories and other hidden directories
-		if info.IsDir() {
-			if info.Name() == ".git" || info.Name() == ".hhx" || info.Name()[0] == '.' {
-				return filepath.SkipDir
-			}
+		relPath := job.relPath
 
-			// Skip build and compilation directories
-			if info.Name() == "cmake-build-debug" || info.Name() == "build" {
-				return filepath.SkipDir
+		// A file that was detected as deleted but not staged has reappeared
+		if deleted, ok := idx.Deleted[relPath]; ok && deleted.Status != StatusDeleted {
+			if _, synced := idx.Synced[relPath]; !synced {
+				deleted.Status = StatusSynced
+				idx.Synced[relPath] = deleted
+				delete(idx.Deleted, relPath)
+				idx.touch(relPath)
 			}
-
-			return nil
-		}
-
-		// Get relative path
-		relPath, err := filepath.Rel(idx.RepoRoot, path)
-		if err != nil {
-			fmt.Printf("Warning: Failed to get relative path for %s: %v\n", path, err)
-			return nil
-		}
-		relPath = filepath.ToSlash(relPath)
-
-		// Skip any file in the .hhx directory
-		if strings.HasPrefix(relPath, ".hhx/") {
-			fmt.Printf("Warning: Skipping %s because it's a hhx file\n", path)
-			return nil
-		}
-
-		// Calculate hash
-		hash, err := hashFile(path)
-		if err != nil {
-			fmt.Printf("Warning: Failed to get hash for %s: %v\n", path, err)
-			// Skip files that can't be hashed
-			return nil
 		}
 
 		// Check if this file was previously synced
 		if synced, ok := idx.Synced[relPath]; ok {
 			seen[relPath] = true
 
-			if synced.Hash != hash {
+			if synced.Hash != job.hash {
 				// File was modified
-				file := &File{
-					Path:         relPath,
-					Size:         info.Size(),
-					Hash:         hash,
-					LastModified: info.ModTime(),
-					Status:       StatusModified,
-					RemoteURL:    synced.RemoteURL,
-				}
+				file := job.file()
+				file.Status = StatusModified
+				file.RemoteURL = synced.RemoteURL
 				modifiedFiles = append(modifiedFiles, file)
 			} else {
-				// File is unchanged
+				// File is unchanged, remember its metadata to skip hashing next time
+				if !job.cached {
+					synced.Stat = statForCache(job.info)
+					idx.touch(relPath)
+				}
 				unchangedFiles = append(unchangedFiles, synced)
 			}
 		} else {
 			// New file
-			file := &File{
-				Path:         relPath,
-				Size:         info.Size(),
-				Hash:         hash,
-				LastModified: info.ModTime(),
-				Status:       StatusUntracked,
-			}
-			newFiles = append(newFiles, file)
+			newFiles = append(newFiles, job.file())
 		}
-
-		return nil
-	})
-	if err != nil {
-		fmt.Printf("Warning: %v\n", err)
-		return nil, nil, nil, err
 	}
 
 	// Find deleted files
-	var deletedFiles []*File
 	for path, wasSeen := range seen {
 		if !wasSeen {
 			// File was deleted
 			file := idx.Synced[path]
 			file.Status = StatusUntracked
-			deletedFiles = append(deletedFiles, file)
 
 			// Add to deleted files list
 			idx.Deleted[path] = file
 
 			// Remove from synced files
 			delete(idx.Synced, path)
+			idx.touch(path)
+		}
+	}
+
+	// Report every deletion that hasn't been staged yet
+	var deletedFiles []*File
+	for _, file := range idx.Deleted {
+		if file.Status != StatusDeleted {
+			deletedFiles = append(deletedFiles, file)
 		}
 	}
 
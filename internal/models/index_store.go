@@ -0,0 +1,858 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hhx/internal/migrate"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The index is stored in a compact binary format:
+//
+//	"HHXINDEX" magic, uvarint format version
+//	segments: kind byte, uvarint payload length, payload, CRC-32C of kind and payload
+//
+// A full write holds a metadata segment (collections, routes and other small
+// fields as JSON) followed by an entries segment with every tracked path. Saves
+// that change only a few paths append segments holding just those paths, and
+// the last record for a path wins. Once appended records outgrow the base, the
+// next save rewrites the whole file.
+const indexMagic = "HHXINDEX"
+
+// legacyIndexName is the file indexes were kept in before the binary format
+const legacyIndexName = "index.json"
+
+// Segment kinds
+const (
+	segmentMeta    byte = 1
+	segmentEntries byte = 2
+)
+
+// Flags recording which of the staged, deleted and synced maps hold a path
+const (
+	entryStaged byte = 1 << iota
+	entryDeleted
+	entrySynced
+)
+
+// entryFlags lists the flags in the order entries are stored
+var entryFlags = []byte{entryStaged, entryDeleted, entrySynced}
+
+// Fields whose strings are stored as a suffix of the previous record's value
+const (
+	fieldPath = iota
+	fieldHash
+	fieldStatus
+	fieldRemoteURL
+	fieldCollection
+	fieldError
+	numFields
+)
+
+// errCorruptIndex is returned when the index file can't be decoded
+var errCorruptIndex = errors.New("index file is corrupt")
+
+// errTruncatedSegment is returned for a segment that runs past the end of the
+// file, as a save interrupted while appending leaves behind
+var errTruncatedSegment = fmt.Errorf("%w: last segment is cut short", errCorruptIndex)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// indexMeta holds the fields of the index stored in metadata segments
+type indexMeta struct {
+	Collections       map[string]*Collection `json:"collections,omitempty"`
+	DefaultCollection string                 `json:"default_collection,omitempty"`
+	Routes            []*CollectionRoute     `json:"routes,omitempty"`
+	RepoRoot          string                 `json:"repo_root"`
+}
+
+// LoadIndex loads the index from the given file. Indexes written in an older
+// format, including the JSON format used by older versions of hhx, are read
+// without changing them on disk; the next save converts them.
+func LoadIndex(path string) (*Index, error) {
+	idx, _, err := loadIndex(path)
+	return idx, err
+}
+
+// UpgradeIndex converts the index at path to the current format, backing up
+// the original if anything changed. The caller must hold the index lock.
+func UpgradeIndex(path string) (*migrate.Result, error) {
+	idx, result, err := loadIndex(path)
+	if err != nil || idx.upgrade == nil {
+		return result, err
+	}
+
+	if err := idx.Save(path); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// indexUpgrade records the file in an older format an index was read from,
+// which the next save backs up and replaces
+type indexUpgrade struct {
+	// Path and format version of the file
+	path    string
+	version int
+
+	// Conversion the index went through, completed by the save
+	result *migrate.Result
+}
+
+// IndexFileVersion returns the format version of the index at path, looking
+// for a JSON index next to it if it doesn't exist yet
+func IndexFileVersion(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && filepath.Base(path) != legacyIndexName {
+		data, err = os.ReadFile(filepath.Join(filepath.Dir(path), legacyIndexName))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(indexMagic)) {
+		return migrate.Version(data)
+	}
+	version, n := binary.Uvarint(data[len(indexMagic):])
+	if n <= 0 {
+		return 0, errCorruptIndex
+	}
+	return int(version), nil
+}
+
+// loadIndex loads the index at path and reports any conversion it went through
+func loadIndex(path string) (*Index, *migrate.Result, error) {
+	current := &migrate.Result{From: IndexFormatVersion, To: IndexFormatVersion}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		legacyPath := filepath.Join(filepath.Dir(path), legacyIndexName)
+		if legacyPath != path {
+			if _, err := os.Stat(legacyPath); err == nil {
+				return convertJSONIndex(legacyPath, path)
+			}
+		}
+
+		// Determine repository root from index path
+		repoRoot := filepath.Dir(filepath.Dir(path))
+		return NewIndex(repoRoot), current, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	// Read the file in one allocation of its size
+	data := make([]byte, info.Size())
+	_, err = io.ReadFull(f, data)
+	f.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(indexMagic)) {
+		return convertJSONIndex(path, path)
+	}
+
+	idx, err := decodeIndex(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	idx.diskInfo = info
+	idx.diskSize = int64(len(data))
+
+	if idx.FormatVersion < IndexFormatVersion {
+		return upgradeBinaryIndex(idx, path)
+	}
+
+	return idx, current, nil
+}
+
+// binaryIndexMigrations describes what each version of the binary format added
+var binaryIndexMigrations = map[int]string{
+	binaryIndexFormatVersion: "record why files failed to upload",
+}
+
+// upgradeBinaryIndex marks an index read in an older binary format to be
+// rewritten in the current one on the next save
+func upgradeBinaryIndex(idx *Index, path string) (*Index, *migrate.Result, error) {
+	result := &migrate.Result{From: idx.FormatVersion, To: idx.FormatVersion}
+	for version := idx.FormatVersion; version < IndexFormatVersion; version++ {
+		result.Applied = append(result.Applied, binaryIndexMigrations[version])
+	}
+
+	idx.upgrade = &indexUpgrade{path: path, version: idx.FormatVersion, result: result}
+	idx.rewrite = true
+	return idx, result, nil
+}
+
+// convertJSONIndex reads a JSON index, upgrading older JSON formats. The next
+// save writes it to path in the binary format and keeps the JSON file as a
+// backup.
+func convertJSONIndex(jsonPath, path string) (*Index, *migrate.Result, error) {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upgraded, result, err := jsonIndexMigrations.Upgrade(data)
+	if err != nil {
+		return nil, result, err
+	}
+
+	idx := NewIndex("")
+	if err := json.Unmarshal(upgraded, idx); err != nil {
+		return nil, result, err
+	}
+	idx.FormatVersion = IndexFormatVersion
+
+	// Initialize maps if they're nil
+	if idx.Files == nil {
+		idx.Files = make(map[string]*File)
+	}
+	if idx.Deleted == nil {
+		idx.Deleted = make(map[string]*File)
+	}
+	if idx.Synced == nil {
+		idx.Synced = make(map[string]*File)
+	}
+	if idx.Collections == nil {
+		idx.Collections = make(map[string]*Collection)
+	}
+
+	result.Applied = append(result.Applied, "convert the index to the binary format")
+	idx.upgrade = &indexUpgrade{path: jsonPath, version: result.From, result: result}
+	return idx, result, nil
+}
+
+// decodeIndex reads the header and metadata of a binary index. Entry segments
+// are checked but kept undecoded until the files are first needed.
+func decodeIndex(data []byte) (*Index, error) {
+	rest := data[len(indexMagic):]
+	version, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return nil, errCorruptIndex
+	}
+	if version > IndexFormatVersion {
+		return nil, fmt.Errorf("index format version %d is %w (this hhx supports up to version %d); upgrade hhx",
+			version, migrate.ErrNewerFormat, IndexFormatVersion)
+	}
+	rest = rest[n:]
+
+	idx := NewIndex("")
+	idx.FormatVersion = int(version)
+	idx.rewrite = false
+
+	for segments := 0; len(rest) > 0; segments++ {
+		kind, payload, next, err := readSegment(rest)
+		if err != nil {
+			// A save that was interrupted while appending leaves a partial
+			// segment at the end; drop it and rewrite the index on the next
+			// save. Any other damage means records would be lost.
+			if segments >= 2 && errors.Is(err, errTruncatedSegment) {
+				idx.rewrite = true
+				break
+			}
+			return nil, err
+		}
+		rest = next
+
+		switch kind {
+		case segmentMeta:
+			var meta indexMeta
+			if err := json.Unmarshal(payload, &meta); err != nil {
+				return nil, fmt.Errorf("%w: %v", errCorruptIndex, err)
+			}
+			idx.setMeta(&meta)
+			idx.savedMeta = payload
+		case segmentEntries:
+			count, n := binary.Uvarint(payload)
+			if n <= 0 {
+				return nil, errCorruptIndex
+			}
+			if segments < 2 {
+				idx.baseRecords = int(count)
+			} else {
+				idx.journalRecords += int(count)
+			}
+			if err := validateEntries(payload, idx.FormatVersion); err != nil {
+				return nil, err
+			}
+			idx.pending = append(idx.pending, payload)
+		default:
+			return nil, fmt.Errorf("%w: unknown segment kind %d", errCorruptIndex, kind)
+		}
+	}
+
+	return idx, nil
+}
+
+// readSegment splits the next segment off data, checking its checksum. A
+// segment that runs past the end of data is reported as errTruncatedSegment.
+func readSegment(data []byte) (kind byte, payload, rest []byte, err error) {
+	if len(data) < 1 {
+		return 0, nil, nil, errTruncatedSegment
+	}
+	kind = data[0]
+	length, n := binary.Uvarint(data[1:])
+	if n == 0 {
+		return 0, nil, nil, errTruncatedSegment
+	}
+	if n < 0 {
+		return 0, nil, nil, errCorruptIndex
+	}
+	if available := uint64(len(data) - 1 - n); length > available || available-length < 4 {
+		return 0, nil, nil, errTruncatedSegment
+	}
+
+	start := 1 + n
+	end := start + int(length)
+	payload = data[start:end]
+
+	crc := crc32.Update(crc32.Checksum([]byte{kind}, crcTable), crcTable, payload)
+	if binary.LittleEndian.Uint32(data[end:]) != crc {
+		return 0, nil, nil, errCorruptIndex
+	}
+
+	return kind, payload, data[end+4:], nil
+}
+
+// appendSegment adds a segment to buf
+func appendSegment(buf []byte, kind byte, payload []byte) []byte {
+	buf = append(buf, kind)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+	crc := crc32.Update(crc32.Checksum([]byte{kind}, crcTable), crcTable, payload)
+	return binary.LittleEndian.AppendUint32(buf, crc)
+}
+
+// meta returns the fields stored in metadata segments; the caller must hold the lock
+func (idx *Index) meta() *indexMeta {
+	return &indexMeta{
+		Collections:       idx.Collections,
+		DefaultCollection: idx.DefaultCollection,
+		Routes:            idx.Routes,
+		RepoRoot:          idx.RepoRoot,
+	}
+}
+
+// setMeta replaces the fields stored in metadata segments
+func (idx *Index) setMeta(meta *indexMeta) {
+	idx.Collections = meta.Collections
+	if idx.Collections == nil {
+		idx.Collections = make(map[string]*Collection)
+	}
+	idx.DefaultCollection = meta.DefaultCollection
+	idx.Routes = meta.Routes
+	idx.RepoRoot = meta.RepoRoot
+}
+
+// loadEntries decodes the entry segments read from disk on first use, so
+// commands that only need collections or routes never decode the files. The
+// segments were validated when the index was loaded, so decoding can't fail.
+func (idx *Index) loadEntries() {
+	idx.decode.Do(func() {
+		if len(idx.pending) > 0 && len(idx.Synced) == 0 {
+			idx.Synced = make(map[string]*File, idx.baseRecords)
+		}
+		for i, payload := range idx.pending {
+			if err := idx.applyEntries(payload, i == 0); err != nil {
+				panic(fmt.Sprintf("decoding validated index entries: %v", err))
+			}
+		}
+		idx.pending = nil
+	})
+}
+
+// touch records that the entries for a path changed and must be saved; the
+// caller must hold the lock
+func (idx *Index) touch(path string) {
+	idx.changed[path] = true
+}
+
+// Save saves the index to the given file. Nothing is written if the index is
+// unchanged, and a few changed files are appended rather than rewriting it.
+func (idx *Index) Save(path string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.loadEntries()
+
+	meta, err := json.Marshal(idx.meta())
+	if err != nil {
+		return err
+	}
+	metaChanged := !bytes.Equal(meta, idx.savedMeta)
+
+	if !idx.rewrite && !metaChanged && len(idx.changed) == 0 {
+		return nil
+	}
+
+	if idx.upgrade != nil {
+		return idx.saveUpgrade(path, meta)
+	}
+
+	if !idx.rewrite && idx.canAppend(path) && idx.journalRecords+len(idx.changed) <= idx.baseRecords/4+1024 {
+		err = idx.appendChanges(path, meta, metaChanged)
+	} else {
+		err = idx.writeFull(path, meta)
+	}
+	if err != nil {
+		return err
+	}
+
+	idx.savedMeta = meta
+	idx.changed = make(map[string]bool)
+	return nil
+}
+
+// saveUpgrade writes an index read in an older format to path in the current
+// one, after backing up the file it was read from. A JSON index kept elsewhere
+// is removed once converted. The caller must hold the lock.
+func (idx *Index) saveUpgrade(path string, meta []byte) error {
+	upgrade := idx.upgrade
+
+	data, err := os.ReadFile(upgrade.path)
+	if err != nil {
+		return err
+	}
+	upgrade.result.BackupPath, err = migrate.Backup(upgrade.path, upgrade.version, data)
+	if err != nil {
+		return fmt.Errorf("backing up %s: %w", upgrade.path, err)
+	}
+
+	if err := idx.writeFull(path, meta); err != nil {
+		return fmt.Errorf("upgrading %s: %w", upgrade.path, err)
+	}
+	if upgrade.path != path {
+		if err := os.Remove(upgrade.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	upgrade.result.To = IndexFormatVersion
+	idx.upgrade = nil
+	idx.savedMeta = meta
+	idx.changed = make(map[string]bool)
+	return nil
+}
+
+// canAppend reports whether the file at path is still the one the index was
+// read from or last wrote, so changes can be appended to it
+func (idx *Index) canAppend(path string) bool {
+	if idx.diskInfo == nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(info, idx.diskInfo) && info.Size() == idx.diskSize
+}
+
+// appendChanges appends the changed entries, and the metadata if it changed,
+// to the end of the index file
+func (idx *Index) appendChanges(path string, meta []byte, metaChanged bool) error {
+	var buf []byte
+	if metaChanged {
+		buf = appendSegment(buf, segmentMeta, meta)
+	}
+	if len(idx.changed) > 0 {
+		paths := make([]string, 0, len(idx.changed))
+		for path := range idx.changed {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		buf = appendSegment(buf, segmentEntries, idx.encodeEntries(paths))
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		idx.rewrite = true
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		idx.rewrite = true
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	idx.diskSize += int64(len(buf))
+	idx.journalRecords += len(idx.changed)
+	return nil
+}
+
+// writeFull writes the whole index to a temporary file and renames it over the
+// index, so readers never see a partially written index
+func (idx *Index) writeFull(path string, meta []byte) error {
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	paths := idx.paths()
+	buf := []byte(indexMagic)
+	buf = binary.AppendUvarint(buf, IndexFormatVersion)
+	buf = appendSegment(buf, segmentMeta, meta)
+	buf = appendSegment(buf, segmentEntries, idx.encodeEntries(paths))
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	idx.diskInfo = info
+	idx.diskSize = int64(len(buf))
+	idx.baseRecords = len(paths)
+	idx.journalRecords = 0
+	idx.rewrite = false
+	idx.FormatVersion = IndexFormatVersion
+	return nil
+}
+
+// paths returns every tracked path in sorted order; the caller must hold the lock
+func (idx *Index) paths() []string {
+	seen := make(map[string]bool, len(idx.Synced)+len(idx.Files)+len(idx.Deleted))
+	paths := make([]string, 0, len(idx.Synced)+len(idx.Files)+len(idx.Deleted))
+	for _, files := range []map[string]*File{idx.Files, idx.Deleted, idx.Synced} {
+		for path := range files {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// encodeEntries encodes the current entries of the given paths. A path that is
+// in none of the maps is recorded as removed.
+func (idx *Index) encodeEntries(paths []string) []byte {
+	e := &entryEncoder{}
+	e.uvarint(uint64(len(paths)))
+
+	for _, path := range paths {
+		e.string(fieldPath, path)
+
+		entries := []*File{idx.Files[path], idx.Deleted[path], idx.Synced[path]}
+		var flags byte
+		for i, file := range entries {
+			if file != nil {
+				flags |= entryFlags[i]
+			}
+		}
+		e.buf = append(e.buf, flags)
+
+		for _, file := range entries {
+			if file != nil {
+				e.file(file)
+			}
+		}
+	}
+
+	return e.buf
+}
+
+// applyEntries decodes an entries segment into the maps. Records in the base
+// segment are known to be new, so earlier entries only need clearing for
+// appended segments.
+func (idx *Index) applyEntries(payload []byte, base bool) error {
+	d := &entryDecoder{buf: payload, version: idx.FormatVersion}
+	count := d.uvarint()
+
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		path := d.string(fieldPath)
+		flags := d.byte()
+
+		if !base {
+			delete(idx.Files, path)
+			delete(idx.Deleted, path)
+			delete(idx.Synced, path)
+		}
+
+		for i, files := range []map[string]*File{idx.Files, idx.Deleted, idx.Synced} {
+			if flags&entryFlags[i] != 0 {
+				file := d.file()
+				file.Path = path
+				files[path] = file
+			}
+		}
+	}
+
+	if d.err == nil && len(d.buf) > 0 {
+		d.err = errCorruptIndex
+	}
+	return d.err
+}
+
+// validateEntries checks that an entries segment decodes, without building its
+// files, so a corrupt index is reported when it is loaded rather than when its
+// files are first needed
+func validateEntries(payload []byte, version int) error {
+	d := &entryDecoder{buf: payload, version: version}
+	count := d.uvarint()
+
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		d.skipString(fieldPath)
+		flags := d.byte()
+		for _, flag := range entryFlags {
+			if flags&flag != 0 {
+				d.skipFile()
+			}
+		}
+	}
+
+	if d.err == nil && len(d.buf) > 0 {
+		d.err = errCorruptIndex
+	}
+	return d.err
+}
+
+// entryEncoder writes index entries, storing strings as the suffix that
+// differs from the same field of the previous record
+type entryEncoder struct {
+	buf  []byte
+	prev [numFields]string
+}
+
+func (e *entryEncoder) uvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *entryEncoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *entryEncoder) string(field int, s string) {
+	prev := e.prev[field]
+	shared := 0
+	for shared < len(prev) && shared < len(s) && prev[shared] == s[shared] {
+		shared++
+	}
+	e.uvarint(uint64(shared))
+	e.uvarint(uint64(len(s) - shared))
+	e.buf = append(e.buf, s[shared:]...)
+	e.prev[field] = s
+}
+
+func (e *entryEncoder) file(file *File) {
+	e.varint(file.Size)
+
+	// SHA-256 hashes are stored as raw bytes
+	if raw, err := hex.DecodeString(file.Hash); err == nil && len(raw) == 32 && hex.EncodeToString(raw) == file.Hash {
+		e.buf = append(e.buf, 1)
+		e.buf = append(e.buf, raw...)
+	} else {
+		e.buf = append(e.buf, 0)
+		e.string(fieldHash, file.Hash)
+	}
+
+	e.varint(file.LastModified.Unix())
+	e.uvarint(uint64(file.LastModified.Nanosecond()))
+	e.string(fieldStatus, string(file.Status))
+	e.string(fieldRemoteURL, file.RemoteURL)
+	e.string(fieldCollection, file.Collection)
+	e.string(fieldError, file.Error)
+
+	if file.Stat == nil {
+		e.buf = append(e.buf, 0)
+		return
+	}
+	e.buf = append(e.buf, 1)
+	e.varint(file.Stat.Size)
+	e.varint(file.Stat.MTime)
+	e.varint(file.Stat.CTime)
+	e.uvarint(file.Stat.Inode)
+}
+
+// entryDecoder reads index entries written by entryEncoder. The first error is
+// kept and later reads return zero values.
+type entryDecoder struct {
+	buf     []byte
+	prev    [numFields]string
+	err     error
+	version int
+
+	// Lengths of the previous values, when skipping rather than decoding
+	prevLen [numFields]uint64
+}
+
+func (d *entryDecoder) fail() {
+	if d.err == nil {
+		d.err = errCorruptIndex
+	}
+	d.buf = nil
+}
+
+func (d *entryDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *entryDecoder) varint() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *entryDecoder) byte() byte {
+	if len(d.buf) < 1 {
+		d.fail()
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *entryDecoder) bytes(n uint64) []byte {
+	if uint64(len(d.buf)) < n {
+		d.fail()
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *entryDecoder) string(field int) string {
+	shared := d.uvarint()
+	suffix := d.bytes(d.uvarint())
+	prev := d.prev[field]
+	if shared > uint64(len(prev)) {
+		d.fail()
+		return ""
+	}
+	s := prev[:shared] + string(suffix)
+	d.prev[field] = s
+	return s
+}
+
+func (d *entryDecoder) file() *File {
+	file := &File{}
+	file.Size = d.varint()
+
+	if d.byte() == 1 {
+		file.Hash = hex.EncodeToString(d.bytes(32))
+	} else {
+		file.Hash = d.string(fieldHash)
+	}
+
+	sec := d.varint()
+	nsec := d.uvarint()
+	file.LastModified = time.Unix(sec, int64(nsec))
+	file.Status = FileStatus(d.string(fieldStatus))
+	file.RemoteURL = d.string(fieldRemoteURL)
+	file.Collection = d.string(fieldCollection)
+	if d.version >= indexVersionFileError {
+		file.Error = d.string(fieldError)
+	}
+
+	if d.byte() == 1 {
+		file.Stat = &StatInfo{
+			Size:  d.varint(),
+			MTime: d.varint(),
+			CTime: d.varint(),
+			Inode: d.uvarint(),
+		}
+	}
+
+	return file
+}
+
+// skipString reads past a string written by entryEncoder.string, checking it
+// could be decoded
+func (d *entryDecoder) skipString(field int) {
+	shared := d.uvarint()
+	length := d.uvarint()
+	d.bytes(length)
+	if shared > d.prevLen[field] {
+		d.fail()
+		return
+	}
+	d.prevLen[field] = shared + length
+}
+
+// skipFile reads past a file the way file decodes it
+func (d *entryDecoder) skipFile() {
+	d.varint()
+
+	if d.byte() == 1 {
+		d.bytes(32)
+	} else {
+		d.skipString(fieldHash)
+	}
+
+	d.varint()
+	d.uvarint()
+	d.skipString(fieldStatus)
+	d.skipString(fieldRemoteURL)
+	d.skipString(fieldCollection)
+	if d.version >= indexVersionFileError {
+		d.skipString(fieldError)
+	}
+
+	if d.byte() == 1 {
+		d.varint()
+		d.varint()
+		d.varint()
+		d.uvarint()
+	}
+}
@@ -0,0 +1,314 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// benchmarkFiles is the number of files in the repositories indexes are
+// benchmarked with
+const benchmarkFiles = 1_000_000
+
+// benchmarkPath returns the path of the i-th file of a benchmark repository,
+// spread over directories of a thousand files
+func benchmarkPath(i int) string {
+	return fmt.Sprintf("data/%04d/file%07d.bin", i/1000, i)
+}
+
+// writeBenchmarkIndex saves an index of synced files to a new repository and
+// returns its root and index path. With createFiles, the files are also
+// created in the working tree, empty and with their metadata recorded so
+// status doesn't need to rehash them.
+func writeBenchmarkIndex(b *testing.B, files int, createFiles bool) (string, string) {
+	b.Helper()
+
+	root, indexPath := newTestRepo(b)
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	sum := sha256.Sum256(nil)
+	hash := hex.EncodeToString(sum[:])
+	modified := time.Now().Add(-time.Hour)
+	for i := 0; i < files; i++ {
+		file := &File{
+			Path:         benchmarkPath(i),
+			Hash:         hash,
+			LastModified: modified,
+			RemoteURL:    "/files/" + benchmarkPath(i),
+			Collection:   "default",
+		}
+
+		if createFiles {
+			path := filepath.Join(root, filepath.FromSlash(file.Path))
+			if i%1000 == 0 {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.Chtimes(path, modified, modified); err != nil {
+				b.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			file.Stat = statForCache(info)
+		}
+
+		idx.RecordSynced(file)
+	}
+
+	if err := idx.Save(indexPath); err != nil {
+		b.Fatal(err)
+	}
+	return root, indexPath
+}
+
+// limits bounds the average time and memory an iteration of a benchmark may
+// take, so a regression fails the benchmark instead of only slowing it down.
+// The limits leave room for slower machines.
+type limits struct {
+	duration time.Duration
+	bytes    uint64
+}
+
+// measure runs a benchmark's iterations and fails it if they exceed the limits
+func (l limits) measure(b *testing.B, iteration func()) {
+	b.Helper()
+	b.ReportAllocs()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		iteration()
+	}
+
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	perOp := b.Elapsed() / time.Duration(b.N)
+	bytesPerOp := (after.TotalAlloc - before.TotalAlloc) / uint64(b.N)
+	if perOp > l.duration {
+		b.Errorf("took %v per iteration, limit %v", perOp, l.duration)
+	}
+	if bytesPerOp > l.bytes {
+		b.Errorf("allocated %d MB per iteration, limit %d MB", bytesPerOp>>20, l.bytes>>20)
+	}
+}
+
+// BenchmarkLoadIndex loads an index the way commands that only need its
+// collections and routes do, without decoding the files
+func BenchmarkLoadIndex(b *testing.B) {
+	_, indexPath := writeBenchmarkIndex(b, benchmarkFiles, false)
+
+	limits{duration: 500 * time.Millisecond, bytes: 128 << 20}.measure(b, func() {
+		idx, err := LoadIndex(indexPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(idx.GetCollections()) != 0 {
+			b.Fatal("benchmark index has collections")
+		}
+	})
+}
+
+// BenchmarkLoadIndexFiles loads an index and decodes every file
+func BenchmarkLoadIndexFiles(b *testing.B) {
+	_, indexPath := writeBenchmarkIndex(b, benchmarkFiles, false)
+
+	limits{duration: 5 * time.Second, bytes: 1 << 30}.measure(b, func() {
+		idx, err := LoadIndex(indexPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if files := idx.GetAllFiles(); len(files) != benchmarkFiles {
+			b.Fatalf("loaded %d files, want %d", len(files), benchmarkFiles)
+		}
+	})
+}
+
+// BenchmarkStatus runs what 'hhx status' does in a repository where nothing
+// changed since the last push
+func BenchmarkStatus(b *testing.B) {
+	if testing.Short() {
+		b.Skip("creates a million files")
+	}
+
+	_, indexPath := writeBenchmarkIndex(b, benchmarkFiles, true)
+
+	limits{duration: 30 * time.Second, bytes: 2 << 30}.measure(b, func() {
+		idx, err := LoadIndex(indexPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		newFiles, modifiedFiles, deletedFiles, err := idx.ScanWorkingDirectory()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(newFiles)+len(modifiedFiles)+len(deletedFiles) > 0 {
+			b.Fatalf("%d new, %d modified and %d deleted files in an unchanged tree",
+				len(newFiles), len(modifiedFiles), len(deletedFiles))
+		}
+		if err := idx.Save(indexPath); err != nil {
+			b.Fatal(err)
+		}
+		_ = idx.GetStagedFiles()
+		_ = idx.GetStagedDeletions()
+		_ = idx.GetFailedFiles()
+	})
+}
+
+func TestLoadIndexReportsCorruptEntries(t *testing.T) {
+	_, indexPath := newTestRepo(t)
+
+	// An entries segment whose checksum is intact but whose record is cut short
+	data := binary.AppendUvarint([]byte(indexMagic), IndexFormatVersion)
+	data = appendSegment(data, segmentMeta, []byte("{}"))
+	data = appendSegment(data, segmentEntries, []byte{1, 5, 'a', '.'})
+	if err := os.WriteFile(indexPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadIndex(indexPath); !errors.Is(err, errCorruptIndex) {
+		t.Fatalf("LoadIndex returned %v, want %v", err, errCorruptIndex)
+	}
+}
+
+// writeIndexWithJournal saves an index with one file and appends a second
+// file, returning the index path and the size of the file before the append
+func writeIndexWithJournal(t *testing.T) (string, int64) {
+	t.Helper()
+
+	_, indexPath := newTestRepo(t)
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.RecordSynced(&File{Path: "a.txt", Hash: "hash-a"})
+	if err := idx.Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx.RecordSynced(&File{Path: "b.txt", Hash: "hash-b"})
+	if err := idx.Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	return indexPath, info.Size()
+}
+
+func TestLoadIndexDropsTruncatedLastSegment(t *testing.T) {
+	indexPath, baseSize := writeIndexWithJournal(t)
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() <= baseSize {
+		t.Fatal("second save didn't append to the index")
+	}
+
+	// A save interrupted while appending
+	if err := os.Truncate(indexPath, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.GetSyncedFile("a.txt"); !ok {
+		t.Error("a.txt lost along with the truncated segment")
+	}
+	if _, ok := idx.GetSyncedFile("b.txt"); ok {
+		t.Error("b.txt loaded from a truncated segment")
+	}
+	if !idx.rewrite {
+		t.Error("index with a truncated segment not marked to be rewritten")
+	}
+}
+
+func TestLoadIndexRejectsDamagedSegments(t *testing.T) {
+	indexPath, baseSize := writeIndexWithJournal(t)
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			// The appended segment is complete but its checksum doesn't match
+			name: "bad checksum in the last segment",
+			data: func() []byte {
+				damaged := append([]byte(nil), data...)
+				damaged[len(damaged)-1] ^= 0xff
+				return damaged
+			}(),
+		},
+		{
+			// Segments follow one that was damaged
+			name: "bad checksum before another segment",
+			data: func() []byte {
+				damaged := append([]byte(nil), data...)
+				damaged[len(damaged)-1] ^= 0xff
+				return append(damaged, data[baseSize:]...)
+			}(),
+		},
+		{
+			// A partly written segment was followed by a later append
+			name: "short segment followed by another",
+			data: append(append([]byte(nil), data[:len(data)-2]...), data[baseSize:]...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(indexPath, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadIndex(indexPath); !errors.Is(err, errCorruptIndex) {
+				t.Fatalf("LoadIndex returned %v, want %v", err, errCorruptIndex)
+			}
+		})
+	}
+}
+
+func TestLoadIndexDecodesFilesOnFirstUse(t *testing.T) {
+	indexPath, _ := writeIndexWithJournal(t)
+
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.pending) == 0 || len(idx.Synced) != 0 {
+		t.Fatalf("LoadIndex decoded files: %d segments pending, %d files", len(idx.pending), len(idx.Synced))
+	}
+
+	if files := idx.GetAllFiles(); len(files) != 2 {
+		t.Fatalf("%d files decoded, want 2", len(files))
+	}
+	if len(idx.pending) != 0 {
+		t.Errorf("%d segments still pending after decoding", len(idx.pending))
+	}
+}
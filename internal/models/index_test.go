@@ -0,0 +1,250 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stagedDeletionPaths lists the paths of the deletions staged in idx
+func stagedDeletionPaths(idx *Index) map[string]bool {
+	paths := make(map[string]bool)
+	for _, file := range idx.GetStagedDeletions() {
+		paths[file.Path] = true
+	}
+	return paths
+}
+
+func TestStageDirectoryStagesMissingFiles(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	if err := os.Mkdir(filepath.Join(idx.RepoRoot, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	recordSyncedFile(t, idx, "data/kept.txt", "kept")
+	recordSyncedFile(t, idx, "data/removed.txt", "removed")
+	recordSyncedFile(t, idx, "outside.txt", "outside")
+	for _, path := range []string{"data/removed.txt", "outside.txt"} {
+		if err := os.Remove(filepath.Join(idx.RepoRoot, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := idx.StageDirectory(filepath.Join(idx.RepoRoot, "data")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the deletion under the staged directory is staged
+	if deletions := stagedDeletionPaths(idx); len(deletions) != 1 || !deletions["data/removed.txt"] {
+		t.Errorf("staged deletions: %v, want data/removed.txt", deletions)
+	}
+	if _, ok := idx.GetSyncedFile("data/removed.txt"); ok {
+		t.Error("data/removed.txt still synced after its deletion was staged")
+	}
+
+	// A scan reports the other deletion as not staged
+	_, _, deleted, err := idx.ScanWorkingDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].Path != "outside.txt" {
+		t.Errorf("unstaged deletions: %v, want outside.txt", deleted)
+	}
+}
+
+func TestUnstageDeletion(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	recordSyncedFile(t, idx, "a.txt", "a")
+	path := filepath.Join(idx.RepoRoot, "a.txt")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := idx.StageDeletion(path); err != nil {
+		t.Fatal(err)
+	}
+	idx.UnstageFile(path)
+
+	if deletions := stagedDeletionPaths(idx); len(deletions) != 0 {
+		t.Errorf("staged deletions after unstaging: %v", deletions)
+	}
+	_, _, deleted, err := idx.ScanWorkingDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].Path != "a.txt" {
+		t.Errorf("unstaged deletions: %v, want a.txt", deleted)
+	}
+
+	// The deletion can be staged again
+	if err := idx.StageDeletion(path); err != nil {
+		t.Fatal(err)
+	}
+	if deletions := stagedDeletionPaths(idx); !deletions["a.txt"] {
+		t.Errorf("staged deletions: %v, want a.txt", deletions)
+	}
+}
+
+func TestConfirmDeletionForgetsFile(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	recordSyncedFile(t, idx, "a.txt", "a")
+	if err := idx.StageDeletion(filepath.Join(idx.RepoRoot, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	idx.ConfirmDeletion("a.txt")
+
+	if len(idx.GetStagedDeletions()) != 0 {
+		t.Error("deletion still staged after the server confirmed it")
+	}
+	if _, ok := idx.GetTrackedFile("a.txt"); ok {
+		t.Error("a.txt still tracked after its deletion was confirmed")
+	}
+}
+
+func TestStageDeletionOfUntrackedFile(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+
+	if err := idx.StageDeletion(filepath.Join(idx.RepoRoot, "never-synced.txt")); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("StageDeletion returned %v, want %v", err, ErrFileNotFound)
+	}
+}
+
+// writeSettledFile writes a file modified long enough ago for its metadata to
+// be trusted, and returns its metadata
+func writeSettledFile(t *testing.T, idx *Index, path, content string) os.FileInfo {
+	t.Helper()
+
+	fullPath := filepath.Join(idx.RepoRoot, path)
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(fullPath, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// scanModified returns the paths a scan reports as modified
+func scanModified(t *testing.T, idx *Index) map[string]bool {
+	t.Helper()
+
+	_, modified, _, err := idx.ScanWorkingDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]bool)
+	for _, file := range modified {
+		paths[file.Path] = true
+	}
+	return paths
+}
+
+func TestScanReusesHashOfUnchangedFile(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	info := writeSettledFile(t, idx, "a.txt", "a")
+
+	// A hash the file doesn't have shows whether it was rehashed
+	idx.RecordSynced(&File{Path: "a.txt", Hash: "recorded-hash", Stat: statForCache(info)})
+
+	if modified := scanModified(t, idx); len(modified) != 0 {
+		t.Errorf("unchanged file rehashed: %v reported modified", modified)
+	}
+
+	idx.DisableStatCache()
+	if modified := scanModified(t, idx); !modified["a.txt"] {
+		t.Error("a.txt not rehashed with the stat cache disabled")
+	}
+}
+
+func TestScanRehashesChangedFile(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	info := writeSettledFile(t, idx, "a.txt", "a")
+	idx.RecordSynced(&File{Path: "a.txt", Hash: hashOfContent("a"), Stat: statForCache(info)})
+
+	// Same size and modification time, different contents
+	writeSettledFile(t, idx, "a.txt", "b")
+	if err := os.Chtimes(filepath.Join(idx.RepoRoot, "a.txt"), info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	if modified := scanModified(t, idx); !modified["a.txt"] {
+		t.Error("a.txt changed in place but not reported modified")
+	}
+}
+
+func TestScanRecordsMetadataOfSettledFiles(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	writeSettledFile(t, idx, "settled.txt", "settled")
+	idx.RecordSynced(&File{Path: "settled.txt", Hash: hashOfContent("settled")})
+	recordSyncedFile(t, idx, "recent.txt", "recent")
+
+	if modified := scanModified(t, idx); len(modified) != 0 {
+		t.Fatalf("unchanged files reported modified: %v", modified)
+	}
+
+	if synced, _ := idx.GetSyncedFile("settled.txt"); synced.Stat == nil {
+		t.Error("metadata of settled.txt not recorded after hashing it")
+	}
+	// A file written within the racy window may change again unnoticed
+	if synced, _ := idx.GetSyncedFile("recent.txt"); synced.Stat != nil {
+		t.Error("metadata of a file modified just now recorded")
+	}
+}
+
+func TestMarkFailedKeepsFilesStaged(t *testing.T) {
+	root, indexPath := newTestRepo(t)
+	idx := NewIndex(root)
+	for _, path := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(root, path), []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := idx.StageFile(filepath.Join(root, path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recordSyncedFile(t, idx, "c.txt", "c")
+	if err := idx.StageDeletion(filepath.Join(root, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	idx.MarkFailed("a.txt", "disk full")
+	idx.MarkFailed("c.txt", "locked")
+	if err := idx.Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failures and their errors survive a reload
+	saved, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := make(map[string]string)
+	for _, file := range saved.GetFailedFiles() {
+		failed[file.Path] = file.Error
+	}
+	if len(failed) != 2 || failed["a.txt"] != "disk full" || failed["c.txt"] != "locked" {
+		t.Errorf("failed files: %v, want a.txt and c.txt with their errors", failed)
+	}
+	if staged := saved.GetStagedFiles(); len(staged) != 2 {
+		t.Errorf("%d files staged, want the failed file still staged with b.txt", len(staged))
+	}
+	if deletions := saved.GetStagedDeletions(); len(deletions) != 1 {
+		t.Errorf("%d deletions staged, want the failed one still staged", len(deletions))
+	}
+
+	// Pushing the file again clears its failure
+	saved.MarkSynced("a.txt", "/files/a.txt", "default")
+	if synced, ok := saved.GetSyncedFile("a.txt"); !ok || synced.Error != "" || synced.Status != StatusSynced {
+		t.Errorf("a.txt after a successful push: %+v", synced)
+	}
+	if len(saved.GetFailedFiles()) != 1 {
+		t.Errorf("%d failed files, want only c.txt", len(saved.GetFailedFiles()))
+	}
+}
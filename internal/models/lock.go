@@ -0,0 +1,166 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockFileName is the name of the lock file held while a process modifies the index
+const LockFileName = "index.lock"
+
+// lockPollInterval is how often a held lock is checked while waiting for it
+const lockPollInterval = 100 * time.Millisecond
+
+// IndexLock is an exclusive lock on a repository's index, held from loading the
+// index until it is saved so concurrent hhx processes can't lose each other's
+// updates.
+//
+// The lock file records the holder's process ID and host name. Where the
+// platform supports it, the holder also keeps an advisory lock on the file
+// open, which the system drops when the holder dies; a lock left behind is
+// only taken over by whoever gets that advisory lock, so two waiters can't both
+// take it over.
+type IndexLock struct {
+	path string
+	file *os.File
+}
+
+// AcquireIndexLock locks the index at indexPath, waiting up to timeout for
+// another process to release it. Locks left behind by processes that are no
+// longer running on this machine are taken over.
+func AcquireIndexLock(indexPath string, timeout time.Duration) (*IndexLock, error) {
+	path := filepath.Join(filepath.Dir(indexPath), LockFileName)
+	deadline := time.Now().Add(timeout)
+
+	hostname, _ := os.Hostname()
+
+	for {
+		lock, err := createLock(path, hostname)
+		if err == nil {
+			return lock, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("error creating lock file: %w", err)
+		}
+
+		pid, owner := readLockOwner(path)
+		if pid > 0 && owner == hostname && !processAlive(pid) {
+			// The holder died without releasing the lock
+			if lock := takeOverLock(path, hostname); lock != nil {
+				return lock, nil
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			if pid > 0 {
+				return nil, fmt.Errorf("%w: %s is held by process %d on %s (remove it if no hhx process is running)", ErrIndexLocked, path, pid, owner)
+			}
+			return nil, fmt.Errorf("%w: %s exists (remove it if no hhx process is running)", ErrIndexLocked, path)
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// createLock creates the lock file if it doesn't exist. The file is written and
+// locked under a temporary name and then linked into place, so a lock file is
+// never seen half written or before its holder locks it.
+func createLock(path, hostname string) (*IndexLock, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), LockFileName+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = fmt.Fprintf(f, "%d\n%s\n", os.Getpid(), hostname)
+	locked := err == nil && tryLockFile(f)
+	if err == nil {
+		err = os.Link(f.Name(), path)
+	}
+	if err != nil || !locked {
+		// Without an advisory lock there's no reason to keep the file open
+		_ = f.Close()
+		f = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &IndexLock{path: path, file: f}, nil
+}
+
+// takeOverLock takes over a lock file whose holder died, returning nil if
+// another process holds it or took it over first
+func takeOverLock(path, hostname string) *IndexLock {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	if !tryLockFile(f) {
+		_ = f.Close()
+		return nil
+	}
+
+	// The lock may have been released and created again since it was read
+	opened, err := f.Stat()
+	current, statErr := os.Stat(path)
+	if err != nil || statErr != nil || !os.SameFile(opened, current) {
+		_ = f.Close()
+		return nil
+	}
+	if pid, owner := readLockOwner(path); pid <= 0 || owner != hostname || processAlive(pid) {
+		_ = f.Close()
+		return nil
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(fmt.Sprintf("%d\n%s\n", os.Getpid(), hostname)), 0)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil
+	}
+
+	return &IndexLock{path: path, file: f}
+}
+
+// Release unlocks the index
+func (l *IndexLock) Release() error {
+	if l == nil {
+		return nil
+	}
+
+	// Remove the file before closing it, so no one takes it over in between
+	err := os.Remove(l.path)
+	if l.file != nil {
+		_ = l.file.Close()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readLockOwner returns the process ID and host name recorded in a lock file
+func readLockOwner(path string) (int, string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, ""
+	}
+
+	lines := strings.SplitN(string(data), "\n", 3)
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return 0, ""
+	}
+
+	owner := ""
+	if len(lines) > 1 {
+		owner = strings.TrimSpace(lines[1])
+	}
+	return pid, owner
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package models
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLockFile takes an exclusive advisory lock on a file without waiting and
+// reports whether it got it. The lock is dropped when the file is closed,
+// including when the process dies.
+func tryLockFile(f *os.File) bool {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) == nil
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package models
+
+import "os"
+
+// tryLockFile can't lock files on this platform, so a lock is never taken over
+// from its holder
+func tryLockFile(f *os.File) bool {
+	return false
+}
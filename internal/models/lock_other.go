@@ -0,0 +1,9 @@
+//go:build !unix
+
+package models
+
+// processAlive assumes the process is running where it can't be checked, so
+// a lock is never taken over from a live process
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRepo creates an empty repository and returns its root and index path
+func newTestRepo(t testing.TB) (string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".hhx"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	indexPath := filepath.Join(root, ".hhx", "index")
+	if err := NewIndex(root).Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+	return root, indexPath
+}
+
+func TestConcurrentStaging(t *testing.T) {
+	root, indexPath := newTestRepo(t)
+
+	const stagers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, stagers)
+	for i := 0; i < stagers; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file%02d.txt", i))
+		if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- stageLocked(indexPath, path)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staged := index.GetStagedFiles(); len(staged) != stagers {
+		t.Fatalf("index has %d staged files, want %d", len(staged), stagers)
+	}
+}
+
+// stageLocked stages a file the way 'hhx stage' does, holding the index lock
+// from loading the index until it is saved
+func stageLocked(indexPath, path string) error {
+	lock, err := AcquireIndexLock(indexPath, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	index, err := LoadIndex(indexPath)
+	if err != nil {
+		return err
+	}
+	if err := index.StageFile(path); err != nil {
+		return err
+	}
+	return index.Save(indexPath)
+}
+
+// writeStaleLock leaves behind the lock of a process that has exited
+func writeStaleLock(t *testing.T, indexPath string) string {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	lockPath := filepath.Join(filepath.Dir(indexPath), LockFileName)
+	if err := os.WriteFile(lockPath, []byte(fmt.Sprintf("%d\n%s\n", cmd.Process.Pid, hostname)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return lockPath
+}
+
+func TestStaleLockTakeOverIsExclusive(t *testing.T) {
+	_, indexPath := newTestRepo(t)
+	lockPath := writeStaleLock(t, indexPath)
+	hostname, _ := os.Hostname()
+
+	// Another waiter is part way through taking the lock over
+	other, err := os.OpenFile(lockPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tryLockFile(other) {
+		_ = other.Close()
+		t.Skip("advisory file locks aren't supported on this platform")
+	}
+
+	// A waiter that read the same dead process ID must not take it over too
+	if lock := takeOverLock(lockPath, hostname); lock != nil {
+		t.Fatal("stale lock taken over twice")
+	}
+
+	_ = other.Close()
+	lock := takeOverLock(lockPath, hostname)
+	if lock == nil {
+		t.Fatal("stale lock not taken over once no one else held it")
+	}
+	if pid, _ := readLockOwner(lockPath); pid != os.Getpid() {
+		t.Fatalf("lock file records process %d, want %d", pid, os.Getpid())
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaleLockTakenOverOnce(t *testing.T) {
+	_, indexPath := newTestRepo(t)
+	lockPath := writeStaleLock(t, indexPath)
+
+	const waiters = 8
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := AcquireIndexLock(indexPath, 30*time.Second)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			n := holders.Add(1)
+			for {
+				max := maxHolders.Load()
+				if n <= max || maxHolders.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			holders.Add(-1)
+
+			errs <- lock.Release()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if max := maxHolders.Load(); max != 1 {
+		t.Fatalf("%d processes held the lock at once", max)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("lock file left behind: %v", err)
+	}
+}
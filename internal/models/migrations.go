@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"hhx/internal/migrate"
+)
+
+// IndexFormatVersion is the version of the index file format written by this
+// build. Bump it whenever the layout of Index or File changes in a way older
+// versions can't read, and teach decodeIndex to read the previous version.
+const IndexFormatVersion = 3
+
+// binaryIndexFormatVersion is the first version of the binary index format
+const binaryIndexFormatVersion = 2
+
+// indexVersionFileError is the version of the binary index format that added
+// the error recorded for files that failed to upload
+const indexVersionFileError = 3
+
+// jsonIndexFormatVersion is the last version of the JSON index format, which
+// version 2 replaced with the binary format
+const jsonIndexFormatVersion = 1
+
+// jsonIndexMigrations upgrades JSON indexes written by older versions of hhx
+// before they are converted to the binary format
+var jsonIndexMigrations = migrate.NewRegistry("index", jsonIndexFormatVersion,
+	migrate.Migration{
+		From:        0,
+		Description: "record the index format version",
+		Apply:       func(doc map[string]json.RawMessage) error { return nil },
+	},
+)
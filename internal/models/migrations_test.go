@@ -0,0 +1,79 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeJSONIndex leaves a version 0 JSON index in a new repository, as older
+// versions of hhx wrote it, and returns the path of the binary index
+func writeJSONIndex(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	hhxDir := filepath.Join(root, ".hhx")
+	if err := os.Mkdir(hhxDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"files":{},"synced":{"a.txt":{"path":"a.txt","size":1,"hash":"hash-a","status":"synced"}},"repo_root":"` +
+		filepath.ToSlash(root) + `"}`
+	if err := os.WriteFile(filepath.Join(hhxDir, legacyIndexName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(hhxDir, "index")
+}
+
+func TestLoadIndexLeavesOlderFormatsOnDisk(t *testing.T) {
+	indexPath := writeJSONIndex(t)
+	jsonPath := filepath.Join(filepath.Dir(indexPath), legacyIndexName)
+
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.GetSyncedFile("a.txt"); !ok {
+		t.Fatal("a.txt missing from the converted index")
+	}
+
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		t.Errorf("loading wrote %s: %v", indexPath, err)
+	}
+	if _, err := os.Stat(jsonPath); err != nil {
+		t.Errorf("loading removed %s: %v", jsonPath, err)
+	}
+}
+
+func TestSaveConvertsJSONIndex(t *testing.T) {
+	indexPath := writeJSONIndex(t)
+	jsonPath := filepath.Join(filepath.Dir(indexPath), legacyIndexName)
+
+	result, err := UpgradeIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.From != 0 || result.To != IndexFormatVersion {
+		t.Errorf("upgraded from version %d to %d, want 0 to %d", result.From, result.To, IndexFormatVersion)
+	}
+	if result.BackupPath != jsonPath+".v0.bak" {
+		t.Errorf("backup at %s, want %s.v0.bak", result.BackupPath, jsonPath)
+	}
+
+	if _, err := os.Stat(result.BackupPath); err != nil {
+		t.Errorf("no backup of the JSON index: %v", err)
+	}
+	if _, err := os.Stat(jsonPath); !os.IsNotExist(err) {
+		t.Errorf("%s left behind after conversion: %v", jsonPath, err)
+	}
+	if version, err := IndexFileVersion(indexPath); err != nil || version != IndexFormatVersion {
+		t.Errorf("index at version %d (%v), want %d", version, err, IndexFormatVersion)
+	}
+
+	idx, err := LoadIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.GetSyncedFile("a.txt"); !ok {
+		t.Fatal("a.txt missing from the converted index")
+	}
+}
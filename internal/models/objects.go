@@ -0,0 +1,373 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ObjectStore caches file contents by their SHA-256 hash, either in a repository's
+// .hhx/objects directory or in a directory shared by several repositories, so
+// synced versions can be read without downloading them
+type ObjectStore struct {
+	dir string
+
+	// Size the store is trimmed to when objects are added, zero for no limit
+	maxSize int64
+
+	// Running total of the stored objects, computed on the first addition
+	mu    sync.Mutex
+	size  int64
+	sized bool
+}
+
+// NewObjectStore creates a store for the repository at repoRoot
+func NewObjectStore(repoRoot string) *ObjectStore {
+	return NewObjectStoreAt(filepath.Join(repoRoot, ".hhx", "objects"))
+}
+
+// NewObjectStoreAt creates a store in the given directory
+func NewObjectStoreAt(dir string) *ObjectStore {
+	return &ObjectStore{dir: dir}
+}
+
+// SetMaxSize caps the total size of the store. Once an addition takes the store
+// over the cap, the least recently used objects are evicted.
+func (s *ObjectStore) SetMaxSize(maxSize int64) {
+	s.maxSize = maxSize
+}
+
+// Dir returns the directory the store keeps its objects in
+func (s *ObjectStore) Dir() string {
+	return s.dir
+}
+
+// MaxSize returns the size cap of the store, zero for no limit
+func (s *ObjectStore) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Path returns where the content with the given hash is stored. Objects are
+// spread over subdirectories named after the first two characters of the hash.
+func (s *ObjectStore) Path(hash string) string {
+	if len(hash) < 3 {
+		return filepath.Join(s.dir, hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash[2:])
+}
+
+// Has reports whether the content with the given hash is stored
+func (s *ObjectStore) Has(hash string) bool {
+	if hash == "" {
+		return false
+	}
+	_, err := os.Stat(s.Path(hash))
+	return err == nil
+}
+
+// Lookup returns the path of the stored content with the given hash, marking
+// it as recently used
+func (s *ObjectStore) Lookup(hash string) (string, bool) {
+	if !s.Has(hash) {
+		return "", false
+	}
+	s.touch(hash)
+	return s.Path(hash), true
+}
+
+// Open opens the stored content with the given hash, marking it as recently used
+func (s *ObjectStore) Open(hash string) (*os.File, error) {
+	f, err := os.Open(s.Path(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	s.touch(hash)
+	return f, nil
+}
+
+// touch updates the modification time of an object, which eviction treats as
+// its last use
+func (s *ObjectStore) touch(hash string) {
+	now := time.Now()
+	_ = os.Chtimes(s.Path(hash), now, now)
+}
+
+// Put copies the file at srcPath into the store, verifying that its content
+// still has the given hash. Objects that are already stored are only marked as
+// recently used.
+func (s *ObjectStore) Put(hash, srcPath string) error {
+	if hash == "" {
+		return fmt.Errorf("no hash for %s", srcPath)
+	}
+	if s.Has(hash) {
+		s.touch(hash)
+		return nil
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	destPath := s.Path(hash)
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".hhx-object-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("%s changed: its hash is %s, not %s", srcPath, got, hash)
+	}
+
+	// Objects are never modified in place
+	if err := os.Chmod(tmpPath, 0444); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return s.added(size)
+}
+
+// Track accounts for an object that was written straight to its Path, evicting
+// old objects if the store is now over its size cap
+func (s *ObjectStore) Track(hash string) error {
+	info, err := os.Stat(s.Path(hash))
+	if err != nil {
+		return err
+	}
+	return s.added(info.Size())
+}
+
+// added updates the running size of the store after an object was added and
+// evicts the least recently used objects if it went over the cap. The store is
+// trimmed to 90% of the cap so that eviction doesn't run on every addition.
+func (s *ObjectStore) added(size int64) error {
+	if s.maxSize <= 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sized {
+		usage, err := s.Usage()
+		if err != nil {
+			return err
+		}
+		s.size = usage.Size
+		s.sized = true
+	} else {
+		s.size += size
+	}
+
+	if s.size <= s.maxSize {
+		return nil
+	}
+
+	result, err := s.Prune(s.maxSize / 10 * 9)
+	if err != nil {
+		return err
+	}
+	s.size = result.RemainingSize
+	return nil
+}
+
+// CacheUsage describes the contents of an object store
+type CacheUsage struct {
+	Objects int
+	Size    int64
+}
+
+// PruneResult describes the objects removed by a prune
+type PruneResult struct {
+	Removed        int
+	Freed          int64
+	Remaining      int
+	RemainingSize  int64
+	TempFilesFreed int64
+}
+
+// storedObject is an object found while scanning the store
+type storedObject struct {
+	path    string
+	size    int64
+	lastUse time.Time
+}
+
+// staleTempAge is how old a temporary file left by an interrupted write must be
+// before prune removes it
+const staleTempAge = time.Hour
+
+// scan lists the objects in the store, along with temporary files left behind
+// by interrupted writes
+func (s *ObjectStore) scan() ([]storedObject, []storedObject, error) {
+	var objects, temps []storedObject
+
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == s.dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		object := storedObject{path: path, size: info.Size(), lastUse: info.ModTime()}
+		if strings.HasPrefix(d.Name(), ".hhx-") {
+			temps = append(temps, object)
+		} else {
+			objects = append(objects, object)
+		}
+		return nil
+	})
+
+	return objects, temps, err
+}
+
+// Usage counts the objects in the store and their total size
+func (s *ObjectStore) Usage() (*CacheUsage, error) {
+	objects, _, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &CacheUsage{Objects: len(objects)}
+	for _, object := range objects {
+		usage.Size += object.size
+	}
+	return usage, nil
+}
+
+// Prune evicts the least recently used objects until the store holds at most
+// maxSize bytes, and removes stale temporary files. A maxSize of zero empties
+// the store.
+func (s *ObjectStore) Prune(maxSize int64) (*PruneResult, error) {
+	objects, temps, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{}
+	for _, temp := range temps {
+		if time.Since(temp.lastUse) > staleTempAge && os.Remove(temp.path) == nil {
+			result.TempFilesFreed += temp.size
+		}
+	}
+
+	var total int64
+	for _, object := range objects {
+		total += object.size
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].lastUse.Before(objects[j].lastUse)
+	})
+
+	for _, object := range objects {
+		if total <= maxSize {
+			break
+		}
+		if err := os.Remove(object.path); err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+		total -= object.size
+		result.Removed++
+
+		// Drop the object's directory once it is empty
+		_ = os.Remove(filepath.Dir(object.path))
+		result.Freed += object.size
+	}
+
+	result.Remaining = len(objects) - result.Removed
+	result.RemainingSize = total
+	return result, nil
+}
+
+// Checkout copies the stored content with the given hash to destPath, verifying
+// the hash on the way. The content is written next to destPath first and moved
+// into place once complete.
+func (s *ObjectStore) Checkout(hash, destPath string) (int64, error) {
+	src, err := s.Open(hash)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".hhx-restore-*")
+	if err != nil {
+		return 0, err
+	}
+	tmpPath := tmp.Name()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		// Drop the corrupt object so it is downloaded again next time
+		_ = os.Remove(tmpPath)
+		_ = os.Remove(s.Path(hash))
+		return 0, fmt.Errorf("cached object %s is corrupt: hash is %s", hash, got)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+
+	return size, nil
+}
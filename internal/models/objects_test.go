@@ -0,0 +1,170 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// putObject writes content to a file and adds it to the store, returning its hash
+func putObject(t *testing.T, store *ObjectStore, content string) string {
+	t.Helper()
+
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash := hashOfContent(content)
+	if err := store.Put(hash, src); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// setLastUse sets when an object was last used
+func setLastUse(t *testing.T, store *ObjectStore, hash string, lastUse time.Time) {
+	t.Helper()
+
+	if err := os.Chtimes(store.Path(hash), lastUse, lastUse); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObjectStorePutAndCheckout(t *testing.T) {
+	store := NewObjectStore(t.TempDir())
+	hash := putObject(t, store, "weights")
+
+	if want := filepath.Join(store.Dir(), hash[:2], hash[2:]); store.Path(hash) != want {
+		t.Errorf("object stored at %s, want %s", store.Path(hash), want)
+	}
+	if !store.Has(hash) {
+		t.Fatal("object not stored")
+	}
+	info, err := os.Stat(store.Path(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("object is writable: %v", info.Mode())
+	}
+
+	dest := filepath.Join(t.TempDir(), "models", "net.bin")
+	size, err := store.Checkout(hash, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "weights" || size != 7 {
+		t.Errorf("checked out %d bytes %q, want 7 bytes %q", size, data, "weights")
+	}
+}
+
+func TestObjectStorePutVerifiesHash(t *testing.T) {
+	store := NewObjectStore(t.TempDir())
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte("changed since hashing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := hashOfContent("original")
+	if err := store.Put(hash, src); err == nil {
+		t.Error("Put stored content under a hash it doesn't have")
+	}
+	if store.Has(hash) {
+		t.Error("object stored despite the hash mismatch")
+	}
+	if usage, err := store.Usage(); err != nil || usage.Objects != 0 {
+		t.Errorf("store holds %+v (%v), want nothing", usage, err)
+	}
+}
+
+func TestCheckoutDropsCorruptObject(t *testing.T) {
+	store := NewObjectStore(t.TempDir())
+	hash := putObject(t, store, "weights")
+	if err := os.Chmod(store.Path(hash), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.Path(hash), []byte("bit rot"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	destDir := t.TempDir()
+	if _, err := store.Checkout(hash, filepath.Join(destDir, "net.bin")); err == nil {
+		t.Fatal("corrupt object checked out")
+	}
+	if store.Has(hash) {
+		t.Error("corrupt object left in the store")
+	}
+	if entries, _ := os.ReadDir(destDir); len(entries) != 0 {
+		t.Errorf("failed checkout left %s behind", entries[0].Name())
+	}
+}
+
+func TestObjectStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewObjectStore(t.TempDir())
+	store.SetMaxSize(100)
+
+	a := putObject(t, store, strings.Repeat("a", 40))
+	b := putObject(t, store, strings.Repeat("b", 40))
+	setLastUse(t, store, a, time.Now().Add(-2*time.Hour))
+	setLastUse(t, store, b, time.Now().Add(-time.Hour))
+
+	// Reading a makes b the least recently used
+	if _, ok := store.Lookup(a); !ok {
+		t.Fatal("a not stored")
+	}
+	c := putObject(t, store, strings.Repeat("c", 40))
+
+	if store.Has(b) {
+		t.Error("least recently used object kept over the size cap")
+	}
+	if !store.Has(a) || !store.Has(c) {
+		t.Error("recently used object evicted")
+	}
+	if usage, err := store.Usage(); err != nil || usage.Size != 80 {
+		t.Errorf("store holds %+v (%v), want 80 bytes", usage, err)
+	}
+}
+
+func TestPruneRemovesStaleTempFiles(t *testing.T) {
+	store := NewObjectStore(t.TempDir())
+	hash := putObject(t, store, "weights")
+
+	// Files left by interrupted writes, one of them possibly still being written
+	dir := filepath.Dir(store.Path(hash))
+	stale := filepath.Join(dir, ".hhx-object-stale")
+	fresh := filepath.Join(dir, ".hhx-object-fresh")
+	for _, path := range []string{stale, fresh} {
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := store.Prune(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 0 || result.Remaining != 1 || result.TempFilesFreed != 7 {
+		t.Errorf("prune result: %+v, want only the stale temporary file removed", result)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file kept: %v", err)
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("temporary file still being written removed: %v", err)
+	}
+
+	// Pruning to nothing empties the store
+	if result, err := store.Prune(0); err != nil || result.Removed != 1 || store.Has(hash) {
+		t.Errorf("prune to zero: %+v (%v), want the object removed", result, err)
+	}
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// CollectionRoute sends files matching a path pattern to a collection
+type CollectionRoute struct {
+	// Glob pattern relative to the repository root, e.g. "checkpoints/**"
+	Pattern string `json:"pattern"`
+
+	// Name of the collection matching files are pushed to
+	Collection string `json:"collection"`
+
+	compile sync.Once
+	re      *regexp.Regexp
+}
+
+// matches reports whether the route applies to a path relative to the repository root
+func (r *CollectionRoute) matches(path string) bool {
+	r.compile.Do(func() {
+		r.re, _ = compileGlob(r.Pattern)
+	})
+
+	return r.re != nil && r.re.MatchString(path)
+}
+
+// AddRoute adds a rule sending files matching pattern to a collection. Routes
+// are checked in the order they were added and the first match wins.
+func (idx *Index) AddRoute(pattern, collection string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if pattern == "" {
+		return fmt.Errorf("route pattern cannot be empty")
+	}
+
+	if _, exists := idx.Collections[collection]; !exists {
+		return ErrCollectionNotFound
+	}
+
+	if _, err := compileGlob(pattern); err != nil {
+		return fmt.Errorf("invalid route pattern %q: %w", pattern, err)
+	}
+
+	for _, route := range idx.Routes {
+		if route.Pattern == pattern {
+			return fmt.Errorf("a route for %q already exists", pattern)
+		}
+	}
+
+	idx.Routes = append(idx.Routes, &CollectionRoute{
+		Pattern:    pattern,
+		Collection: collection,
+	})
+
+	return nil
+}
+
+// RemoveRoute removes the route with the given pattern
+func (idx *Index) RemoveRoute(pattern string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for i, route := range idx.Routes {
+		if route.Pattern == pattern {
+			idx.Routes = append(idx.Routes[:i], idx.Routes[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no route for %q", pattern)
+}
+
+// GetRoutes returns all routes in the order they are checked
+func (idx *Index) GetRoutes() []*CollectionRoute {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	routes := make([]*CollectionRoute, len(idx.Routes))
+	copy(routes, idx.Routes)
+	return routes
+}
+
+// SetStagingCollection tags every file staged afterwards with a collection,
+// overriding routes; an empty name restores the default behaviour
+func (idx *Index) SetStagingCollection(name string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if name != "" {
+		if _, exists := idx.Collections[name]; !exists {
+			return ErrCollectionNotFound
+		}
+	}
+
+	idx.stagingCollection = name
+	return nil
+}
+
+// ResolveCollection returns the collection a file should be pushed to: the
+// collection it was tagged with, or else the first matching route. An empty
+// result means the file goes to the default collection.
+func (idx *Index) ResolveCollection(file *File) string {
+	if file.Collection != "" {
+		return file.Collection
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.routeFor(file.Path)
+}
+
+// routeFor returns the collection of the first route matching path; the
+// caller must hold the lock
+func (idx *Index) routeFor(path string) string {
+	for _, route := range idx.Routes {
+		if route.matches(path) {
+			return route.Collection
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestResolveCollection(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	for _, name := range []string{"checkpoints", "tables", "raw"} {
+		if err := idx.AddCollection(&Collection{Name: name, Type: CollectionTypeBucket, Path: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first matching route wins
+	for _, route := range [][2]string{
+		{"checkpoints/**", "checkpoints"},
+		{"*.parquet", "tables"},
+		{"data/**", "raw"},
+	} {
+		if err := idx.AddRoute(route[0], route[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		file *File
+		want string
+	}{
+		{&File{Path: "checkpoints/run1/model.pt"}, "checkpoints"},
+		{&File{Path: "checkpoints/run1/metrics.parquet"}, "checkpoints"},
+		{&File{Path: "data/train.parquet"}, "tables"},
+		{&File{Path: "data/train.csv"}, "raw"},
+		{&File{Path: "old/checkpoints/model.pt"}, ""},
+		{&File{Path: "data/train.csv", Collection: "tables"}, "tables"},
+	}
+
+	for _, tt := range tests {
+		if got := idx.ResolveCollection(tt.file); got != tt.want {
+			t.Errorf("ResolveCollection(%s tagged %q) = %q, want %q", tt.file.Path, tt.file.Collection, got, tt.want)
+		}
+	}
+}
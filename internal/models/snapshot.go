@@ -0,0 +1,265 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShortIDLength is the number of characters of a snapshot ID shown to users
+const ShortIDLength = 12
+
+// ManifestEntry records a single file as it was synced with the server
+type ManifestEntry struct {
+	Path       string `json:"path"`                 // Relative path from repository root
+	Hash       string `json:"hash"`                 // SHA-256 hash of file content
+	Size       int64  `json:"size"`                 // File size in bytes
+	RemoteURL  string `json:"remote_url,omitempty"` // URL of the file on the server
+	Collection string `json:"collection,omitempty"` // Collection name
+}
+
+// Snapshot is an immutable record of every synced file at a point in time
+type Snapshot struct {
+	// SHA-256 hash of the snapshot's contents, not stored in the snapshot itself
+	ID string `json:"-"`
+
+	// ID of the previous snapshot, empty for the first one
+	Parent string `json:"parent,omitempty"`
+
+	// Who created the snapshot
+	Author string `json:"author"`
+
+	// When the snapshot was created
+	Time time.Time `json:"time"`
+
+	// Description of the snapshot
+	Message string `json:"message"`
+
+	// Files in the snapshot, sorted by path
+	Files []*ManifestEntry `json:"files"`
+}
+
+// ShortID returns the abbreviated ID shown to users
+func (s *Snapshot) ShortID() string {
+	return ShortID(s.ID)
+}
+
+// ShortID abbreviates a snapshot ID
+func ShortID(id string) string {
+	if len(id) > ShortIDLength {
+		return id[:ShortIDLength]
+	}
+	return id
+}
+
+// SameFiles reports whether two snapshots record exactly the same files
+func (s *Snapshot) SameFiles(other *Snapshot) bool {
+	if other == nil || len(s.Files) != len(other.Files) {
+		return false
+	}
+
+	for i, entry := range s.Files {
+		if *entry != *other.Files[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Manifest returns the synced files, sorted by path. Files deleted locally are
+// left out, whether or not their deletion is staged, so snapshots and tags
+// record only files that are part of the working tree.
+func (idx *Index) Manifest() []*ManifestEntry {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	return newManifest(idx.Synced)
+}
+
+// Tracked returns every file known to be on the server, sorted by path. Unlike
+// Manifest, it includes files deleted locally until their deletion is pushed.
+func (idx *Index) Tracked() []*ManifestEntry {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	idx.loadEntries()
+
+	return newManifest(idx.Synced, idx.Deleted)
+}
+
+// newManifest lists the files of the given maps, sorted by path
+func newManifest(fileMaps ...map[string]*File) []*ManifestEntry {
+	manifest := []*ManifestEntry{}
+	for _, files := range fileMaps {
+		for _, file := range files {
+			manifest = append(manifest, &ManifestEntry{
+				Path:       file.Path,
+				Hash:       file.Hash,
+				Size:       file.Size,
+				RemoteURL:  file.RemoteURL,
+				Collection: file.Collection,
+			})
+		}
+	}
+
+	sort.Slice(manifest, func(i, j int) bool {
+		return manifest[i].Path < manifest[j].Path
+	})
+	return manifest
+}
+
+// SnapshotStore reads and writes snapshots in a repository's .hhx/snapshots directory
+type SnapshotStore struct {
+	dir string
+}
+
+// NewSnapshotStore creates a store for the repository at repoRoot
+func NewSnapshotStore(repoRoot string) *SnapshotStore {
+	return &SnapshotStore{
+		dir: filepath.Join(repoRoot, ".hhx", "snapshots"),
+	}
+}
+
+// Head returns the ID of the latest snapshot, or an empty string if there is none
+func (s *SnapshotStore) Head() (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, "HEAD"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save writes a snapshot, sets its ID and makes it the new head. Snapshot files
+// are never overwritten once written.
+func (s *SnapshotStore) Save(snapshot *Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:])
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("snapshot %s already exists", ShortID(id))
+		}
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(s.path(id))
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(s.path(id))
+		return err
+	}
+
+	// Move the head with a rename so it always names a complete snapshot
+	tmp := filepath.Join(s.dir, "HEAD.tmp")
+	if err := os.WriteFile(tmp, []byte(id+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, "HEAD")); err != nil {
+		return err
+	}
+
+	snapshot.ID = id
+	return nil
+}
+
+// Load reads a snapshot by its full ID or an unambiguous prefix of it
+func (s *SnapshotStore) Load(id string) (*Snapshot, error) {
+	fullID, err := s.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.path(fullID))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", ShortID(fullID), err)
+	}
+	snapshot.ID = fullID
+
+	return &snapshot, nil
+}
+
+// Resolve expands a snapshot ID prefix to a full ID
+func (s *SnapshotStore) Resolve(prefix string) (string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return "", ErrSnapshotNotFound
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrSnapshotNotFound
+		}
+		return "", err
+	}
+
+	match := ""
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		if match != "" {
+			return "", ErrAmbiguousSnapshot
+		}
+		match = id
+	}
+
+	if match == "" {
+		return "", ErrSnapshotNotFound
+	}
+	return match, nil
+}
+
+// Log returns the snapshot history starting at the head, newest first
+func (s *SnapshotStore) Log() ([]*Snapshot, error) {
+	id, err := s.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Snapshot
+	for id != "" {
+		snapshot, err := s.Load(id)
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, snapshot)
+		id = snapshot.Parent
+	}
+
+	return snapshots, nil
+}
+
+// path returns the file a snapshot is stored in
+func (s *SnapshotStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordSyncedFile writes a file to the working tree and records it as synced
+// with its contents
+func recordSyncedFile(t *testing.T, idx *Index, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(idx.RepoRoot, path), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx.RecordSynced(&File{
+		Path:      path,
+		Hash:      hashOfContent(content),
+		Size:      int64(len(content)),
+		RemoteURL: "/files/" + path,
+	})
+}
+
+// hashOfContent returns the SHA-256 hash of content as the index records it
+func hashOfContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestPaths lists the paths of a manifest
+func manifestPaths(manifest []*ManifestEntry) string {
+	var paths []string
+	for _, entry := range manifest {
+		paths = append(paths, entry.Path)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestManifestLeavesOutDeletedFiles(t *testing.T) {
+	idx := NewIndex(t.TempDir())
+	recordSyncedFile(t, idx, "kept.txt", "kept")
+	recordSyncedFile(t, idx, "staged.txt", "staged")
+	recordSyncedFile(t, idx, "missing.txt", "missing")
+
+	// One deletion is staged, the other only found by a scan
+	if err := idx.StageDeletion(filepath.Join(idx.RepoRoot, "staged.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(idx.RepoRoot, "missing.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, deleted, err := idx.ScanWorkingDirectory(); err != nil || len(deleted) != 1 {
+		t.Fatalf("scan found %d unstaged deletions (%v), want 1", len(deleted), err)
+	}
+
+	if got := manifestPaths(idx.Manifest()); got != "kept.txt" {
+		t.Errorf("manifest lists %q, want only kept.txt", got)
+	}
+	if got := manifestPaths(idx.Tracked()); got != "kept.txt missing.txt staged.txt" {
+		t.Errorf("tracked files: %q, want all three", got)
+	}
+}
+
+// saveSnapshot saves a snapshot of the given files on top of the store's head
+func saveSnapshot(t *testing.T, store *SnapshotStore, message string, files ...*ManifestEntry) *Snapshot {
+	t.Helper()
+
+	head, err := store.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &Snapshot{
+		Parent:  head,
+		Author:  "tester",
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Message: message,
+		Files:   files,
+	}
+	if err := store.Save(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func TestSnapshotStoreLog(t *testing.T) {
+	store := NewSnapshotStore(t.TempDir())
+	if head, err := store.Head(); err != nil || head != "" {
+		t.Fatalf("head of an empty store: %q (%v)", head, err)
+	}
+
+	first := saveSnapshot(t, store, "first", &ManifestEntry{Path: "a.txt", Hash: "hash-a"})
+	second := saveSnapshot(t, store, "second", &ManifestEntry{Path: "a.txt", Hash: "hash-a2"})
+
+	if head, err := store.Head(); err != nil || head != second.ID {
+		t.Errorf("head is %q (%v), want %s", head, err, second.ID)
+	}
+
+	log, err := store.Log()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 || log[0].ID != second.ID || log[1].ID != first.ID {
+		t.Fatalf("log has %d snapshots, want second then first", len(log))
+	}
+	if log[0].Parent != first.ID || log[1].Parent != "" {
+		t.Errorf("parents: %q and %q, want %s and none", log[0].Parent, log[1].Parent, first.ID)
+	}
+	if log[1].Message != "first" || log[1].Files[0].Hash != "hash-a" {
+		t.Errorf("first snapshot loaded as %+v", log[1])
+	}
+}
+
+func TestSnapshotStoreResolve(t *testing.T) {
+	store := NewSnapshotStore(t.TempDir())
+	snapshot := saveSnapshot(t, store, "first")
+
+	for _, prefix := range []string{snapshot.ID, snapshot.ShortID(), strings.ToUpper(snapshot.ShortID())} {
+		if id, err := store.Resolve(prefix); err != nil || id != snapshot.ID {
+			t.Errorf("Resolve(%q) = %q (%v), want %s", prefix, id, err, snapshot.ID)
+		}
+	}
+	for _, prefix := range []string{"", "not-an-id"} {
+		if _, err := store.Resolve(prefix); !errors.Is(err, ErrSnapshotNotFound) {
+			t.Errorf("Resolve(%q) returned %v, want %v", prefix, err, ErrSnapshotNotFound)
+		}
+	}
+
+	// Save snapshots until two IDs share their first character
+	seen := map[byte]bool{snapshot.ID[0]: true}
+	for i := 0; ; i++ {
+		id := saveSnapshot(t, store, fmt.Sprint(i)).ID
+		if seen[id[0]] {
+			if _, err := store.Resolve(id[:1]); !errors.Is(err, ErrAmbiguousSnapshot) {
+				t.Errorf("Resolve(%q) returned %v, want %v", id[:1], err, ErrAmbiguousSnapshot)
+			}
+			break
+		}
+		seen[id[0]] = true
+	}
+}
+
+func TestSnapshotsAreNeverOverwritten(t *testing.T) {
+	dir := t.TempDir()
+	store := NewSnapshotStore(dir)
+	snapshot := saveSnapshot(t, store, "first", &ManifestEntry{Path: "a.txt", Hash: "hash-a"})
+
+	// The same snapshot saved again would have the same ID
+	again := &Snapshot{Author: snapshot.Author, Time: snapshot.Time, Message: snapshot.Message, Files: snapshot.Files}
+	if err := store.Save(again); err == nil {
+		t.Error("saving an existing snapshot again succeeded")
+	}
+
+	info, err := os.Stat(store.path(snapshot.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("snapshot file is writable: %v", info.Mode())
+	}
+}
+
+func TestSnapshotSameFiles(t *testing.T) {
+	files := func(hash string) []*ManifestEntry {
+		return []*ManifestEntry{{Path: "a.txt", Hash: "hash-a"}, {Path: "b.txt", Hash: hash}}
+	}
+	snapshot := &Snapshot{Message: "one", Files: files("hash-b")}
+
+	tests := []struct {
+		name  string
+		other *Snapshot
+		want  bool
+	}{
+		{name: "same files, other message", other: &Snapshot{Message: "two", Files: files("hash-b")}, want: true},
+		{name: "changed file", other: &Snapshot{Files: files("hash-b2")}, want: false},
+		{name: "fewer files", other: &Snapshot{Files: files("hash-b")[:1]}, want: false},
+		{name: "no snapshot", other: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := snapshot.SameFiles(tt.other); got != tt.want {
+			t.Errorf("%s: SameFiles() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
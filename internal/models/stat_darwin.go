@@ -0,0 +1,14 @@
+package models
+
+import (
+	"os"
+	"syscall"
+)
+
+// fillPlatformStat adds the inode number and change time of a file
+func fillPlatformStat(stat *StatInfo, info os.FileInfo) {
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		stat.Inode = sys.Ino
+		stat.CTime = sys.Ctimespec.Nano()
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package models
+
+import "os"
+
+// fillPlatformStat is a no-op where inode numbers and change times aren't
+// available; size and mtime alone decide whether a hash can be reused
+func fillPlatformStat(stat *StatInfo, info os.FileInfo) {}
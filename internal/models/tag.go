@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tagNamePattern restricts tag names to characters that are safe in file names and URLs
+var tagNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// reservedTagNames can't be used as tag names because they are subcommands of hhx tag
+var reservedTagNames = []string{"list"}
+
+// Tag is a named, immutable set of synced files, e.g. a dataset version
+type Tag struct {
+	// Name of the tag, e.g. "v1.3"
+	Name string `json:"name"`
+
+	// Description of the tag
+	Message string `json:"message,omitempty"`
+
+	// Who created the tag
+	Author string `json:"author"`
+
+	// When the tag was created
+	Time time.Time `json:"time"`
+
+	// Latest snapshot when the tag was created, if any
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// Files in the tag, sorted by path
+	Files []*ManifestEntry `json:"files"`
+}
+
+// ValidateTagName checks that a tag name can be stored locally and on the server
+func ValidateTagName(name string) error {
+	if !tagNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("%w: %q (use letters, digits, '.', '_' and '-')", ErrInvalidTagName, name)
+	}
+
+	for _, reserved := range reservedTagNames {
+		if name == reserved {
+			return fmt.Errorf("%w: %q is reserved", ErrInvalidTagName, name)
+		}
+	}
+
+	return nil
+}
+
+// TagStore reads and writes tags in a repository's .hhx/tags directory
+type TagStore struct {
+	dir string
+}
+
+// NewTagStore creates a store for the repository at repoRoot
+func NewTagStore(repoRoot string) *TagStore {
+	return &TagStore{
+		dir: filepath.Join(repoRoot, ".hhx", "tags"),
+	}
+}
+
+// Save writes a tag. Tags are never overwritten once written.
+func (s *TagStore) Save(tag *Tag) error {
+	if err := ValidateTagName(tag.Name); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(tag, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path(tag.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		if os.IsExist(err) {
+			return ErrTagExists
+		}
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(s.path(tag.Name))
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(s.path(tag.Name))
+		return err
+	}
+
+	return nil
+}
+
+// Exists reports whether a tag with the given name is stored locally
+func (s *TagStore) Exists(name string) bool {
+	if ValidateTagName(name) != nil {
+		return false
+	}
+	_, err := os.Stat(s.path(name))
+	return err == nil
+}
+
+// Load reads a tag by name
+func (s *TagStore) Load(name string) (*Tag, error) {
+	if err := ValidateTagName(name); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	var tag Tag
+	if err := json.Unmarshal(data, &tag); err != nil {
+		return nil, fmt.Errorf("invalid tag %s: %w", name, err)
+	}
+
+	return &tag, nil
+}
+
+// List returns every local tag, oldest first
+func (s *TagStore) List() ([]*Tag, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var tags []*Tag
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+
+		tag, err := s.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Time.Before(tags[j].Time)
+	})
+	return tags, nil
+}
+
+// path returns the file a tag is stored in
+func (s *TagStore) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// UploadSession records the progress of a chunked upload, so an interrupted
+// push can resume from the last part the server acknowledged
+type UploadSession struct {
+	UploadID   string         `json:"upload_id"`
+	ProjectID  string         `json:"project_id"`
+	Collection string         `json:"collection"`
+	Path       string         `json:"path"`
+	Hash       string         `json:"hash"`
+	Size       int64          `json:"size"`
+	ModTime    time.Time      `json:"mod_time"`
+	PartSize   int64          `json:"part_size"`
+	Parts      []UploadedPart `json:"parts"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// UploadedPart is a part of a chunked upload the server has acknowledged
+type UploadedPart struct {
+	Number int    `json:"number"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// PartCount returns the number of parts the file is split into
+func (s *UploadSession) PartCount() int {
+	if s.PartSize <= 0 || s.Size == 0 {
+		return 1
+	}
+	return int((s.Size + s.PartSize - 1) / s.PartSize)
+}
+
+// Acknowledged reports whether the server has acknowledged a part
+func (s *UploadSession) Acknowledged(number int) bool {
+	for _, part := range s.Parts {
+		if part.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// UploadedBytes returns the total size of the acknowledged parts
+func (s *UploadSession) UploadedBytes() int64 {
+	var total int64
+	for _, part := range s.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+// UploadStore keeps the state of unfinished chunked uploads in a repository's
+// .hhx/uploads directory
+type UploadStore struct {
+	dir string
+}
+
+// NewUploadStore creates a store for the repository at repoRoot
+func NewUploadStore(repoRoot string) *UploadStore {
+	return &UploadStore{dir: filepath.Join(repoRoot, ".hhx", "uploads")}
+}
+
+// path returns where the session for a file in a collection is kept
+func (s *UploadStore) path(projectID, collection, path string) string {
+	sum := sha256.Sum256([]byte(projectID + "\x00" + collection + "\x00" + path))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns the unfinished upload of a file to a collection, or nil if there
+// is none
+func (s *UploadStore) Load(projectID, collection, path string) (*UploadSession, error) {
+	data, err := os.ReadFile(s.path(projectID, collection, path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var session UploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		// A damaged session can't be resumed; start the upload over
+		return nil, nil
+	}
+	return &session, nil
+}
+
+// Save records a session, replacing the file with a rename so a crash never
+// leaves a partly written session behind
+func (s *UploadStore) Save(session *UploadSession) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := s.path(session.ProjectID, session.Collection, session.Path)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Remove forgets a session once its upload is complete or abandoned
+func (s *UploadStore) Remove(session *UploadSession) error {
+	err := os.Remove(s.path(session.ProjectID, session.Collection, session.Path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -51,10 +51,7 @@ func (m Model) Init() tea.Cmd {
 
 // Update handles UI updates
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var (
-		cmd  tea.Cmd
-		cmds []tea.Cmd
-	)
+	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
@@ -196,7 +193,7 @@ func renderFiles(files []*models.File, width int) string {
 	// Categorize files
 	for _, file := range files {
 		switch file.Status {
-		case models.StatusStaged:
+		case models.StatusStaged, models.StatusFailed:
 			stagedFiles = append(stagedFiles, file)
 		case models.StatusModified:
 			modifiedFiles = append(modifiedFiles, file)
@@ -31,6 +31,8 @@ func (i FileItem) Description() string {
 	switch i.File.Status {
 	case models.StatusStaged:
 		status = "Staged"
+	case models.StatusFailed:
+		status = "Failed to upload"
 	case models.StatusModified:
 		status = "Modified"
 	case models.StatusUntracked:
@@ -76,6 +78,7 @@ func (m *FileListModel) SetFiles(files []*models.File) {
 			// Order: Staged, Modified, Untracked, Synced
 			statusOrder := map[models.FileStatus]int{
 				models.StatusStaged:    0,
+				models.StatusFailed:    0,
 				models.StatusModified:  1,
 				models.StatusUntracked: 2,
 				models.StatusSynced:    3,
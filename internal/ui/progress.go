@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"hhx/internal/util"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// plainInterval is how often progress is printed when stdout isn't a terminal
+const plainInterval = 10 * time.Second
+
+// maxActiveFiles is how many partly transferred files the live view lists
+const maxActiveFiles = 5
+
+// ProgressView shows the progress of a transfer while it runs: a live view when
+// stdout is a terminal, and otherwise a line of text every few seconds, as
+// suits CI logs. A nil view prints messages directly.
+type ProgressView struct {
+	transfer *Transfer
+	title    string
+
+	// Live view on a terminal
+	program *tea.Program
+	exited  chan struct{}
+
+	// Periodic lines otherwise
+	stop    chan struct{}
+	stopped chan struct{}
+
+	stopOnce sync.Once
+}
+
+// StartProgress starts showing a transfer, with a title such as "Pushing"
+func StartProgress(transfer *Transfer, title string) *ProgressView {
+	v := &ProgressView{transfer: transfer, title: title}
+
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		v.stop = make(chan struct{})
+		v.stopped = make(chan struct{})
+		go v.printPeriodically()
+		return v
+	}
+
+	// Without input or a signal handler the view never takes over the
+	// terminal, so Ctrl-C still reaches the command
+	v.program = tea.NewProgram(newProgressModel(transfer, title),
+		tea.WithInput(nil), tea.WithoutSignalHandler())
+	v.exited = make(chan struct{})
+	go func() {
+		defer close(v.exited)
+		_, _ = v.program.Run()
+	}()
+	return v
+}
+
+// Println prints a message above the progress view
+func (v *ProgressView) Println(a ...interface{}) {
+	if v == nil || v.program == nil {
+		fmt.Println(a...)
+		return
+	}
+	v.program.Println(a...)
+}
+
+// Printf prints a formatted message above the progress view
+func (v *ProgressView) Printf(format string, a ...interface{}) {
+	v.Println(strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"))
+}
+
+// AddFile adds a file to the transfer shown by the view
+func (v *ProgressView) AddFile(path, label string, size int64) {
+	if v != nil {
+		v.transfer.AddFile(path, label, size)
+	}
+}
+
+// Finish marks a file of the transfer as done
+func (v *ProgressView) Finish(path string) {
+	if v != nil {
+		v.transfer.Finish(path)
+	}
+}
+
+// Stop removes the live view, or stops printing progress lines. Calling it
+// again has no effect.
+func (v *ProgressView) Stop() {
+	if v == nil {
+		return
+	}
+
+	v.stopOnce.Do(func() {
+		if v.program != nil {
+			v.program.Send(progressDoneMsg{})
+			<-v.exited
+			return
+		}
+
+		close(v.stop)
+		<-v.stopped
+	})
+}
+
+// printPeriodically prints a progress line every plainInterval until stopped
+func (v *ProgressView) printPeriodically() {
+	defer close(v.stopped)
+
+	ticker := time.NewTicker(plainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-v.stop:
+			return
+		case <-ticker.C:
+			fmt.Printf("%s: %s\n", v.title, summarizeTransfer(v.transfer.Stats()))
+		}
+	}
+}
+
+// summarizeTransfer describes the overall progress of a transfer in one line
+func summarizeTransfer(stats TransferStats) string {
+	line := fmt.Sprintf("%s of %s (%.0f%%), %d of %d files",
+		util.FormatSize(stats.Done), util.FormatSize(stats.Total), stats.Percent()*100,
+		stats.Finished, stats.Files)
+	if stats.Rate > 0 {
+		line += fmt.Sprintf(", %s/s", util.FormatSize(int64(stats.Rate)))
+	}
+	if stats.ETA > 0 {
+		line += fmt.Sprintf(", ETA %s", formatETA(stats.ETA))
+	}
+	return line
+}
+
+// formatETA rounds a remaining time to a precision that suits its length
+func formatETA(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(time.Minute).String()
+}
+
+// Messages
+type progressTickMsg time.Time
+type progressDoneMsg struct{}
+
+// progressModel is the live view of a transfer
+type progressModel struct {
+	transfer *Transfer
+	title    string
+	stats    TransferStats
+	spinner  spinner.Model
+	overall  progress.Model
+	file     progress.Model
+	width    int
+	done     bool
+}
+
+// newProgressModel creates the live view of a transfer
+func newProgressModel(transfer *Transfer, title string) progressModel {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+
+	return progressModel{
+		transfer: transfer,
+		title:    title,
+		stats:    transfer.Stats(),
+		spinner:  s,
+		overall:  progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+		file:     progress.New(progress.WithSolidFill("39"), progress.WithWidth(20)),
+	}
+}
+
+// progressTick asks for the next refresh of the view
+func progressTick() tea.Cmd {
+	return tea.Tick(200*time.Millisecond, func(t time.Time) tea.Msg {
+		return progressTickMsg(t)
+	})
+}
+
+// Init starts refreshing the view
+func (m progressModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, progressTick())
+}
+
+// Update refreshes the view from the transfer
+func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case progressTickMsg:
+		m.stats = m.transfer.Stats()
+		return m, progressTick()
+
+	case progressDoneMsg:
+		m.done = true
+		return m, tea.Quit
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.overall.Width = min(max(msg.Width-20, 10), 60)
+		return m, nil
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// View renders the overall progress and the files being transferred
+func (m progressModel) View() string {
+	// The command prints its own summary once the view is gone
+	if m.done {
+		return ""
+	}
+
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\n", m.spinner.View(), m.title, muted.Render(fmt.Sprintf("(%s elapsed)", m.stats.Elapsed.Round(time.Second))))
+	fmt.Fprintf(&b, "  %s\n", m.overall.ViewAs(m.stats.Percent()))
+	fmt.Fprintf(&b, "  %s\n", muted.Render(summarizeTransfer(m.stats)))
+
+	active := m.stats.Active
+	if len(active) > maxActiveFiles {
+		active = active[:maxActiveFiles]
+	}
+	for _, file := range active {
+		fmt.Fprintf(&b, "  %s %s %s\n",
+			m.file.ViewAs(file.Percent()),
+			m.fileLabel(file.Label),
+			muted.Render(fmt.Sprintf("%s of %s", util.FormatSize(file.Done), util.FormatSize(file.Size))))
+	}
+	if more := len(m.stats.Active) - len(active); more > 0 {
+		fmt.Fprintf(&b, "  %s\n", muted.Render(fmt.Sprintf("and %d more", more)))
+	}
+
+	return b.String()
+}
+
+// fileLabel shortens a file's label to fit next to its progress bar
+func (m progressModel) fileLabel(label string) string {
+	limit := 40
+	if m.width > 0 {
+		limit = max(m.width-50, 10)
+	}
+	if len(label) <= limit {
+		return label
+	}
+
+	// Keep the end of the path, which tells files apart
+	return "..." + label[len(label)-(limit-3):]
+}
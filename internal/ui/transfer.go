@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Transfer tracks the bytes moved for each file of a push or pull. It can be
+// given to an api.Client as its progress reporter and is safe for concurrent use.
+type Transfer struct {
+	mu      sync.Mutex
+	files   map[string]*transferFile
+	total   int64
+	done    int64
+	moved   int64
+	count   int
+	started time.Time
+
+	// Throughput, smoothed over the samples taken by Stats
+	sampledAt    time.Time
+	sampledMoved int64
+	rate         float64
+}
+
+// transferFile is the progress of a single file
+type transferFile struct {
+	label    string
+	size     int64
+	done     int64
+	finished bool
+	order    int
+}
+
+// TransferStats is a snapshot of a transfer
+type TransferStats struct {
+	// Bytes of all files, and bytes transferred or skipped so far
+	Total int64
+	Done  int64
+
+	// Number of files, and number that are finished
+	Files    int
+	Finished int
+
+	// Bytes per second, and the estimated time left; zero while unknown
+	Rate float64
+	ETA  time.Duration
+
+	Elapsed time.Duration
+
+	// Files partly transferred, in the order they were added
+	Active []FileStats
+}
+
+// FileStats is a snapshot of the progress of one file
+type FileStats struct {
+	Label string
+	Size  int64
+	Done  int64
+}
+
+// Percent returns the share of the transfer that is done, from 0 to 1
+func (s TransferStats) Percent() float64 {
+	if s.Total <= 0 {
+		if s.Files > 0 {
+			return float64(s.Finished) / float64(s.Files)
+		}
+		return 0
+	}
+	return float64(s.Done) / float64(s.Total)
+}
+
+// Percent returns the share of the file that is done, from 0 to 1
+func (s FileStats) Percent() float64 {
+	if s.Size <= 0 {
+		return 0
+	}
+	return float64(s.Done) / float64(s.Size)
+}
+
+// NewTransfer creates an empty transfer
+func NewTransfer() *Transfer {
+	now := time.Now()
+	return &Transfer{
+		files:     make(map[string]*transferFile),
+		started:   now,
+		sampledAt: now,
+	}
+}
+
+// AddFile adds a file of the given size, identified by path and shown as label
+func (t *Transfer) AddFile(path, label string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.files[path]; ok {
+		return
+	}
+	t.files[path] = &transferFile{label: label, size: size, order: len(t.files)}
+	t.total += size
+}
+
+// Transferred records n more bytes of a file as sent or received
+func (t *Transfer) Transferred(path string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.moved += n
+	file, ok := t.files[path]
+	if !ok || file.finished {
+		return
+	}
+
+	// Bytes sent again after a failure don't count twice towards the total
+	add := n
+	if file.done+add > file.size {
+		add = file.size - file.done
+	}
+	file.done += add
+	t.done += add
+
+	if file.done >= file.size {
+		file.finished = true
+		t.count++
+	}
+}
+
+// Retried records that n bytes of a file recorded as transferred are being
+// sent again, so they no longer count as done
+func (t *Transfer) Retried(path string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, ok := t.files[path]
+	if !ok {
+		return
+	}
+
+	n = min(n, file.done)
+	file.done -= n
+	t.done -= n
+	if file.finished && file.done < file.size {
+		file.finished = false
+		t.count--
+	}
+}
+
+// Skipped records a file whose contents didn't need to be transferred
+func (t *Transfer) Skipped(path string) {
+	t.Finish(path)
+}
+
+// Finish marks a file as done, whether or not all of it was transferred
+func (t *Transfer) Finish(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, ok := t.files[path]
+	if !ok || file.finished {
+		return
+	}
+	t.done += file.size - file.done
+	file.done = file.size
+	file.finished = true
+	t.count++
+}
+
+// Stats returns a snapshot of the transfer. The throughput is updated from the
+// bytes moved since the previous call, so it should be called periodically.
+func (t *Transfer) Stats() TransferStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if elapsed := now.Sub(t.sampledAt).Seconds(); elapsed >= 0.5 {
+		current := float64(t.moved-t.sampledMoved) / elapsed
+		if t.rate == 0 {
+			t.rate = current
+		} else {
+			t.rate = 0.7*t.rate + 0.3*current
+		}
+		t.sampledAt = now
+		t.sampledMoved = t.moved
+	}
+
+	stats := TransferStats{
+		Total:    t.total,
+		Done:     t.done,
+		Files:    len(t.files),
+		Finished: t.count,
+		Rate:     t.rate,
+		Elapsed:  now.Sub(t.started),
+	}
+	if t.rate > 0 && t.total > t.done {
+		stats.ETA = time.Duration(float64(t.total-t.done) / t.rate * float64(time.Second))
+	}
+
+	var active []*transferFile
+	for _, file := range t.files {
+		if file.done > 0 && !file.finished {
+			active = append(active, file)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].order < active[j].order
+	})
+	for _, file := range active {
+		stats.Active = append(stats.Active, FileStats{Label: file.label, Size: file.size, Done: file.done})
+	}
+
+	return stats
+}
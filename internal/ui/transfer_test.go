@@ -0,0 +1,163 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransferCountsEachByteOnce(t *testing.T) {
+	transfer := NewTransfer()
+	transfer.AddFile("a", "a.bin", 100)
+	transfer.AddFile("b", "b.bin", 50)
+	transfer.AddFile("a", "a.bin", 100)
+
+	transfer.Transferred("a", 60)
+	transfer.Transferred("unknown", 10)
+	stats := transfer.Stats()
+	if stats.Total != 150 || stats.Done != 60 || stats.Files != 2 || stats.Finished != 0 {
+		t.Errorf("stats: %+v, want 60 of 150 bytes and 2 files", stats)
+	}
+
+	// More bytes than the file has, as when a part is sent again
+	transfer.Transferred("a", 60)
+	stats = transfer.Stats()
+	if stats.Done != 100 || stats.Finished != 1 {
+		t.Errorf("stats: %+v, want 100 bytes done and a.bin finished", stats)
+	}
+}
+
+func TestTransferRetried(t *testing.T) {
+	transfer := NewTransfer()
+	transfer.AddFile("a", "a.bin", 100)
+	transfer.Transferred("a", 100)
+
+	// The whole request is sent again
+	transfer.Retried("a", 100)
+	stats := transfer.Stats()
+	if stats.Done != 0 || stats.Finished != 0 {
+		t.Errorf("stats: %+v, want nothing done after the retry", stats)
+	}
+
+	// Never less than nothing
+	transfer.Retried("a", 10)
+	transfer.Transferred("a", 100)
+	stats = transfer.Stats()
+	if stats.Done != 100 || stats.Finished != 1 {
+		t.Errorf("stats: %+v, want a.bin done once resent", stats)
+	}
+}
+
+func TestTransferFinish(t *testing.T) {
+	transfer := NewTransfer()
+	transfer.AddFile("a", "a.bin", 100)
+	transfer.AddFile("b", "b.bin", 50)
+	transfer.Transferred("a", 30)
+
+	// Files the server already had, or that failed, are done all the same
+	transfer.Finish("a")
+	transfer.Skipped("b")
+	transfer.Finish("b")
+
+	stats := transfer.Stats()
+	if stats.Done != 150 || stats.Finished != 2 || stats.Percent() != 1 {
+		t.Errorf("stats: %+v, want everything done", stats)
+	}
+	if len(stats.Active) != 0 {
+		t.Errorf("finished files listed as active: %+v", stats.Active)
+	}
+}
+
+func TestTransferStats(t *testing.T) {
+	transfer := NewTransfer()
+	for _, name := range []string{"c", "a", "b"} {
+		transfer.AddFile(name, name+".bin", 100)
+	}
+	transfer.Transferred("b", 50)
+	transfer.Transferred("c", 25)
+
+	// Pretend the bytes took a second to move
+	transfer.sampledAt = transfer.sampledAt.Add(-time.Second)
+	stats := transfer.Stats()
+
+	if len(stats.Active) != 2 || stats.Active[0].Label != "c.bin" || stats.Active[1].Label != "b.bin" {
+		t.Fatalf("active files: %+v, want c.bin then b.bin, in the order they were added", stats.Active)
+	}
+	if stats.Active[1].Percent() != 0.5 {
+		t.Errorf("b.bin %.2f done, want 0.5", stats.Active[1].Percent())
+	}
+	if stats.Rate < 70 || stats.Rate > 75 {
+		t.Errorf("rate %.1f bytes/s, want about 75", stats.Rate)
+	}
+	if stats.ETA < 2*time.Second || stats.ETA > 4*time.Second {
+		t.Errorf("ETA %v for 225 bytes at %.1f bytes/s", stats.ETA, stats.Rate)
+	}
+}
+
+func TestSummarizeTransfer(t *testing.T) {
+	tests := []struct {
+		stats TransferStats
+		want  string
+	}{
+		{
+			stats: TransferStats{Total: 2048, Done: 1024, Files: 4, Finished: 1},
+			want:  "1.00 KB of 2.00 KB (50%), 1 of 4 files",
+		},
+		{
+			stats: TransferStats{Total: 2048, Done: 1024, Files: 4, Finished: 1, Rate: 512, ETA: 2 * time.Second},
+			want:  "1.00 KB of 2.00 KB (50%), 1 of 4 files, 512 B/s, ETA 2s",
+		},
+		{
+			// Empty files count by number
+			stats: TransferStats{Files: 4, Finished: 2},
+			want:  "0 B of 0 B (50%), 2 of 4 files",
+		},
+	}
+	for _, tt := range tests {
+		if got := summarizeTransfer(tt.stats); got != tt.want {
+			t.Errorf("summarizeTransfer(%+v) = %q, want %q", tt.stats, got, tt.want)
+		}
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		eta  time.Duration
+		want string
+	}{
+		{1400 * time.Millisecond, "1s"},
+		{59 * time.Second, "59s"},
+		{90*time.Minute + 20*time.Second, "1h30m0s"},
+	}
+	for _, tt := range tests {
+		if got := formatETA(tt.eta); got != tt.want {
+			t.Errorf("formatETA(%v) = %q, want %q", tt.eta, got, tt.want)
+		}
+	}
+}
+
+func TestNilProgressViewIsSafe(t *testing.T) {
+	var view *ProgressView
+	view.AddFile("a", "a.bin", 1)
+	view.Finish("a")
+	view.Stop()
+}
+
+func TestProgressViewStopsWithoutTerminal(t *testing.T) {
+	// Tests don't run on a terminal, so the view prints plain lines
+	view := StartProgress(NewTransfer(), "Pushing")
+	if view.program != nil {
+		t.Skip("stdout is a terminal")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		view.Stop()
+		view.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop didn't return")
+	}
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -23,6 +24,41 @@ func FormatSize(size int64) string {
 	return fmt.Sprintf("%.2f %s", floatSize, units[unitIndex])
 }
 
+// ParseSize parses a size such as "512MB" or "10 GB" into bytes. Units are
+// powers of 1024, as printed by FormatSize; a plain number is in bytes.
+func ParseSize(s string) (int64, error) {
+	units := map[string]float64{
+		"":   1,
+		"B":  1,
+		"K":  1 << 10,
+		"KB": 1 << 10,
+		"M":  1 << 20,
+		"MB": 1 << 20,
+		"G":  1 << 30,
+		"GB": 1 << 30,
+		"T":  1 << 40,
+		"TB": 1 << 40,
+	}
+
+	s = strings.TrimSpace(s)
+	end := len(s)
+	for end > 0 && (s[end-1] < '0' || s[end-1] > '9') {
+		end--
+	}
+
+	multiplier, ok := units[strings.ToUpper(strings.TrimSpace(s[end:]))]
+	if !ok || end == 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	value, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return int64(value * multiplier), nil
+}
+
 // IsUUID checks if a string is a valid UUID
 func IsUUID(str string) bool {
 	// Simple UUID check - this is not a comprehensive validation